@@ -0,0 +1,86 @@
+-- ===============================
+-- MAINTENANCE WINDOWS
+-- ===============================
+
+-- Scheduled maintenance covering monitors by id, group or tag. One-off windows
+-- run from starts_at to ends_at. Recurring windows repeat according to an
+-- RRULE or cron expression evaluated in the window's timezone, each occurrence
+-- lasting duration_seconds, with starts_at/ends_at bounding the series.
+CREATE TABLE IF NOT EXISTS public.maintenance_windows (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT,
+
+    -- Targets: a monitor is covered if it matches any of these
+    monitor_ids UUID[] NOT NULL DEFAULT '{}',
+    group_ids UUID[] NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Timing
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ,
+    duration_seconds INTEGER CHECK (duration_seconds > 0),
+    recurrence TEXT, -- RRULE (e.g. FREQ=WEEKLY;BYDAY=SU;BYHOUR=2) or cron expression
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+
+    -- skip: checks are not dispatched; flag: checks run but results are marked
+    action TEXT NOT NULL DEFAULT 'skip' CHECK (action IN ('skip', 'flag')),
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT maintenance_windows_has_target CHECK (
+        cardinality(monitor_ids) > 0 OR cardinality(group_ids) > 0 OR cardinality(tags) > 0
+    ),
+    CONSTRAINT maintenance_windows_one_off_has_end CHECK (
+        recurrence IS NOT NULL OR ends_at IS NOT NULL
+    ),
+    CONSTRAINT maintenance_windows_recurring_has_duration CHECK (
+        recurrence IS NULL OR duration_seconds IS NOT NULL
+    )
+);
+
+DROP TRIGGER IF EXISTS set_maintenance_windows_timestamp ON public.maintenance_windows;
+CREATE TRIGGER set_maintenance_windows_timestamp
+    BEFORE UPDATE ON public.maintenance_windows
+    FOR EACH ROW
+    EXECUTE FUNCTION basejump.trigger_set_timestamps();
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_user_id ON maintenance_windows(user_id);
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_ends_at ON maintenance_windows(ends_at);
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_monitor_ids ON maintenance_windows USING gin(monitor_ids);
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_tags ON maintenance_windows USING gin(tags);
+
+-- ===============================
+-- RLS POLICIES
+-- ===============================
+
+ALTER TABLE maintenance_windows ENABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS maintenance_windows_select_policy ON maintenance_windows;
+CREATE POLICY maintenance_windows_select_policy ON maintenance_windows
+    FOR SELECT
+    USING (user_id = auth.uid());
+
+DROP POLICY IF EXISTS maintenance_windows_insert_policy ON maintenance_windows;
+CREATE POLICY maintenance_windows_insert_policy ON maintenance_windows
+    FOR INSERT
+    WITH CHECK (user_id = auth.uid());
+
+DROP POLICY IF EXISTS maintenance_windows_update_policy ON maintenance_windows;
+CREATE POLICY maintenance_windows_update_policy ON maintenance_windows
+    FOR UPDATE
+    USING (user_id = auth.uid())
+    WITH CHECK (user_id = auth.uid());
+
+DROP POLICY IF EXISTS maintenance_windows_delete_policy ON maintenance_windows;
+CREATE POLICY maintenance_windows_delete_policy ON maintenance_windows
+    FOR DELETE
+    USING (user_id = auth.uid());
+
+-- ===============================
+-- GRANTS
+-- ===============================
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON maintenance_windows TO authenticated;
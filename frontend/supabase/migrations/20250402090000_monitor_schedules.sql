@@ -0,0 +1,10 @@
+-- ===============================
+-- MONITOR CRON SCHEDULES
+-- ===============================
+
+-- Monitors may run on a cron expression instead of a fixed interval, e.g.
+-- "*/5 8-17 * * MON-FRI" to check only during business hours. The expression
+-- is evaluated in schedule_timezone (an IANA zone name, UTC when unset). When
+-- schedule is NULL or empty the monitor runs every interval as before.
+ALTER TABLE public.monitors ADD COLUMN IF NOT EXISTS schedule TEXT;
+ALTER TABLE public.monitors ADD COLUMN IF NOT EXISTS schedule_timezone TEXT;
@@ -0,0 +1,10 @@
+-- ===============================
+-- MONITOR PROBE SELECTORS
+-- ===============================
+
+-- Label selector restricting which probe workers may run a monitor's checks,
+-- e.g. {"provider": "aws", "ip_family": "ipv6"}. Every key must match the
+-- worker's label of the same name; region, provider and ip_family match the
+-- attributes workers register with. NULL or {} allows any worker in the
+-- check's location.
+ALTER TABLE public.monitors ADD COLUMN IF NOT EXISTS probe_selector JSONB;
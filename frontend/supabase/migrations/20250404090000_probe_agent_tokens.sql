@@ -0,0 +1,38 @@
+-- ===============================
+-- PRIVATE PROBE AGENT TOKENS
+-- ===============================
+
+-- Tokens that customer-hosted probe agents register with. An agent holding a
+-- token only receives checks for monitors owned by the token's user. Only a
+-- SHA-256 hash of the token is stored; token_prefix keeps the first few
+-- characters so that users can tell their tokens apart.
+CREATE TABLE IF NOT EXISTS public.probe_agent_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+DROP TRIGGER IF EXISTS set_probe_agent_tokens_timestamp ON public.probe_agent_tokens;
+CREATE TRIGGER set_probe_agent_tokens_timestamp
+    BEFORE UPDATE ON public.probe_agent_tokens
+    FOR EACH ROW
+    EXECUTE FUNCTION basejump.trigger_set_timestamps();
+
+CREATE INDEX IF NOT EXISTS idx_probe_agent_tokens_user_id ON probe_agent_tokens(user_id);
+
+-- ===============================
+-- RLS POLICIES
+-- ===============================
+
+-- Tokens are only issued, listed and revoked through the API gateway, which
+-- never returns the hash. Clients get no direct access, so a leaked session
+-- cannot read hashes or re-enable a revoked token.
+ALTER TABLE probe_agent_tokens ENABLE ROW LEVEL SECURITY;
+
+REVOKE ALL ON probe_agent_tokens FROM anon, authenticated;
@@ -0,0 +1,19 @@
+-- ===============================
+-- MONITOR HTTP CONFIGURATION
+-- ===============================
+
+-- Request settings for HTTP monitors that monitor_http_extension has no
+-- column for. Cookies are sent by name, e.g. {"session": "..."}, and
+-- assertions are further checks on the JSON body and headers, e.g.
+-- [{"source": "json", "path": "status", "operator": "equals", "value": "ok"}].
+ALTER TABLE public.monitor_http_extension
+    ADD COLUMN IF NOT EXISTS user_agent TEXT,
+    ADD COLUMN IF NOT EXISTS cookies JSONB,
+    ADD COLUMN IF NOT EXISTS assertions JSONB,
+    ADD COLUMN IF NOT EXISTS max_response_time_ms INTEGER CHECK (max_response_time_ms > 0);
+
+-- Probes send PATCH requests too
+ALTER TABLE public.monitor_http_extension
+    DROP CONSTRAINT IF EXISTS monitor_http_extension_method_check,
+    ADD CONSTRAINT monitor_http_extension_method_check
+        CHECK (method IN ('GET', 'POST', 'PUT', 'PATCH', 'DELETE', 'HEAD', 'OPTIONS'));
@@ -0,0 +1,13 @@
+-- ===============================
+-- MONITOR DNS CONFIGURATION
+-- ===============================
+
+-- Resolver settings for DNS monitors that monitor_dns_extension has no column
+-- for. dnssec validates the answers' chain of trust from trust_anchor, a DS
+-- record such as 'example.com. IN DS 12345 13 2 <digest>', or from the root
+-- zone's key when it is NULL. A propagation check asks resolvers, e.g.
+-- {1.1.1.1,8.8.8.8}, as well as the zone's authoritative nameservers.
+ALTER TABLE public.monitor_dns_extension
+    ADD COLUMN IF NOT EXISTS resolvers TEXT[],
+    ADD COLUMN IF NOT EXISTS dnssec BOOLEAN DEFAULT false,
+    ADD COLUMN IF NOT EXISTS trust_anchor TEXT;
@@ -0,0 +1,10 @@
+-- ===============================
+-- MONITOR RESULT DEDUPLICATION
+-- ===============================
+
+-- Ingestion stores each result at the time its check ran, so that a result
+-- redelivered after a failure is recognised and not counted twice. Rows stored
+-- before this migration were stamped with their ingestion time and are unique
+-- already.
+CREATE UNIQUE INDEX IF NOT EXISTS monitor_results_monitor_location_time_key
+    ON public.monitor_results (monitor_id, location, time);
@@ -265,3 +265,46 @@ func (q *Queries) IncrementUsageStats(ctx context.Context, arg IncrementUsageSta
 	)
 	return err
 }
+
+const listDailyCheckUsage = `-- name: ListDailyCheckUsage :many
+WITH user_daily_checks AS (
+    SELECT m.user_id, COUNT(*) as check_count
+    FROM monitor_results mr
+    JOIN monitors m ON mr.monitor_id = m.id
+    WHERE mr.time >= CURRENT_DATE
+    GROUP BY m.user_id
+)
+SELECT
+    u.user_id,
+    ul.max_checks_per_day,
+    COALESCE(udc.check_count, 0) as current_check_count
+FROM (SELECT DISTINCT user_id FROM monitors) u
+LEFT JOIN user_limits ul ON ul.user_id = u.user_id
+LEFT JOIN user_daily_checks udc ON udc.user_id = u.user_id
+`
+
+type ListDailyCheckUsageRow struct {
+	UserID            uuid.UUID
+	MaxChecksPerDay   pgtype.Int4
+	CurrentCheckCount int64
+}
+
+func (q *Queries) ListDailyCheckUsage(ctx context.Context) ([]ListDailyCheckUsageRow, error) {
+	rows, err := q.db.Query(ctx, listDailyCheckUsage)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDailyCheckUsageRow
+	for rows.Next() {
+		var i ListDailyCheckUsageRow
+		if err := rows.Scan(&i.UserID, &i.MaxChecksPerDay, &i.CurrentCheckCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
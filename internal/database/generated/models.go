@@ -22,6 +22,7 @@ const (
 	AlertConditionSslExpiry    AlertCondition = "ssl_expiry"
 	AlertConditionKeyword      AlertCondition = "keyword"
 	AlertConditionPattern      AlertCondition = "pattern"
+	AlertConditionDnssecExpiry AlertCondition = "dnssec_expiry"
 )
 
 func (e *AlertCondition) Scan(src interface{}) error {
@@ -350,6 +351,24 @@ type AlertHistory struct {
 	NotificationSent bool
 }
 
+type MaintenanceWindow struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	Name            string
+	Description     pgtype.Text
+	MonitorIds      []uuid.UUID
+	GroupIds        []uuid.UUID
+	Tags            []string
+	StartsAt        pgtype.Timestamptz
+	EndsAt          pgtype.Timestamptz
+	DurationSeconds pgtype.Int4
+	Recurrence      pgtype.Text
+	Timezone        string
+	Action          string
+	CreatedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+}
+
 type Monitor struct {
 	ID                  uuid.UUID
 	UserID              uuid.UUID
@@ -368,6 +387,9 @@ type Monitor struct {
 	ExpectedResponse    pgtype.Text
 	CreatedAt           pgtype.Timestamptz
 	UpdatedAt           pgtype.Timestamptz
+	Schedule            pgtype.Text
+	ScheduleTimezone    pgtype.Text
+	ProbeSelector       []byte
 }
 
 type MonitorResult struct {
@@ -415,6 +437,18 @@ type NotificationPreference struct {
 	UpdatedAt       pgtype.Timestamptz
 }
 
+type ProbeAgentToken struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Name        string
+	TokenHash   string
+	TokenPrefix string
+	LastUsedAt  pgtype.Timestamptz
+	RevokedAt   pgtype.Timestamptz
+	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
+}
+
 type UserLimit struct {
 	UserID                    uuid.UUID
 	MaxMonitors               int32
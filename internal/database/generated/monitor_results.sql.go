@@ -12,9 +12,10 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createMonitorResult = `-- name: CreateMonitorResult :one
+const createMonitorResult = `-- name: CreateMonitorResult :exec
 INSERT INTO monitor_results (
     monitor_id,
+    time,
     location,
     success,
     latency,
@@ -28,12 +29,14 @@ INSERT INTO monitor_results (
     first_byte_time,
     total_time
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
-) RETURNING id, monitor_id, time, location, success, latency, status_code, response_size, error_message, certificate_expiry, dns_resolution_time, tls_handshake_time, connect_time, first_byte_time, total_time
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+ON CONFLICT (monitor_id, location, time) DO NOTHING
 `
 
 type CreateMonitorResultParams struct {
 	MonitorID         uuid.UUID
+	Time              pgtype.Timestamptz
 	Location          string
 	Success           bool
 	Latency           pgtype.Int4
@@ -48,9 +51,10 @@ type CreateMonitorResultParams struct {
 	TotalTime         pgtype.Int4
 }
 
-func (q *Queries) CreateMonitorResult(ctx context.Context, arg CreateMonitorResultParams) (MonitorResult, error) {
-	row := q.db.QueryRow(ctx, createMonitorResult,
+func (q *Queries) CreateMonitorResult(ctx context.Context, arg CreateMonitorResultParams) error {
+	_, err := q.db.Exec(ctx, createMonitorResult,
 		arg.MonitorID,
+		arg.Time,
 		arg.Location,
 		arg.Success,
 		arg.Latency,
@@ -64,25 +68,7 @@ func (q *Queries) CreateMonitorResult(ctx context.Context, arg CreateMonitorResu
 		arg.FirstByteTime,
 		arg.TotalTime,
 	)
-	var i MonitorResult
-	err := row.Scan(
-		&i.ID,
-		&i.MonitorID,
-		&i.Time,
-		&i.Location,
-		&i.Success,
-		&i.Latency,
-		&i.StatusCode,
-		&i.ResponseSize,
-		&i.ErrorMessage,
-		&i.CertificateExpiry,
-		&i.DnsResolutionTime,
-		&i.TlsHandshakeTime,
-		&i.ConnectTime,
-		&i.FirstByteTime,
-		&i.TotalTime,
-	)
-	return i, err
+	return err
 }
 
 const deleteOldMonitorResults = `-- name: DeleteOldMonitorResults :exec
@@ -27,10 +27,13 @@ INSERT INTO monitors (
     verify_ssl,
     port,
     dns_record_type,
-    expected_response
+    expected_response,
+    schedule,
+    schedule_timezone,
+    probe_selector
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
-) RETURNING id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
+) RETURNING id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector
 `
 
 type CreateMonitorParams struct {
@@ -48,6 +51,9 @@ type CreateMonitorParams struct {
 	Port                pgtype.Int4
 	DnsRecordType       pgtype.Text
 	ExpectedResponse    pgtype.Text
+	Schedule            pgtype.Text
+	ScheduleTimezone    pgtype.Text
+	ProbeSelector       []byte
 }
 
 func (q *Queries) CreateMonitor(ctx context.Context, arg CreateMonitorParams) (Monitor, error) {
@@ -66,6 +72,9 @@ func (q *Queries) CreateMonitor(ctx context.Context, arg CreateMonitorParams) (M
 		arg.Port,
 		arg.DnsRecordType,
 		arg.ExpectedResponse,
+		arg.Schedule,
+		arg.ScheduleTimezone,
+		arg.ProbeSelector,
 	)
 	var i Monitor
 	err := row.Scan(
@@ -86,6 +95,9 @@ func (q *Queries) CreateMonitor(ctx context.Context, arg CreateMonitorParams) (M
 		&i.ExpectedResponse,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Schedule,
+		&i.ScheduleTimezone,
+		&i.ProbeSelector,
 	)
 	return i, err
 }
@@ -106,7 +118,7 @@ func (q *Queries) DeleteMonitor(ctx context.Context, arg DeleteMonitorParams) er
 }
 
 const getMonitor = `-- name: GetMonitor :one
-SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at FROM monitors
+SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector FROM monitors
 WHERE id = $1 AND user_id = $2
 `
 
@@ -136,6 +148,9 @@ func (q *Queries) GetMonitor(ctx context.Context, arg GetMonitorParams) (Monitor
 		&i.ExpectedResponse,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Schedule,
+		&i.ScheduleTimezone,
+		&i.ProbeSelector,
 	)
 	return i, err
 }
@@ -167,7 +182,7 @@ WITH stats AS (
     GROUP BY monitor_id
 )
 SELECT
-    m.id, m.user_id, m.name, m.type, m.target, m.interval, m.timeout, m.status, m.locations, m.expected_status_codes, m.follow_redirects, m.verify_ssl, m.port, m.dns_record_type, m.expected_response, m.created_at, m.updated_at,
+    m.id, m.user_id, m.name, m.type, m.target, m.interval, m.timeout, m.status, m.locations, m.expected_status_codes, m.follow_redirects, m.verify_ssl, m.port, m.dns_record_type, m.expected_response, m.created_at, m.updated_at, m.schedule, m.schedule_timezone, m.probe_selector,
     COALESCE(s.total_checks, 0) as checks_24h,
     COALESCE(s.successful_checks, 0) as successful_checks_24h,
     COALESCE(s.avg_latency, 0) as avg_latency_24h,
@@ -205,6 +220,9 @@ type GetMonitorWithStatsRow struct {
 	ExpectedResponse    pgtype.Text
 	CreatedAt           pgtype.Timestamptz
 	UpdatedAt           pgtype.Timestamptz
+	Schedule            pgtype.Text
+	ScheduleTimezone    pgtype.Text
+	ProbeSelector       []byte
 	Checks24h           int64
 	SuccessfulChecks24h int64
 	AvgLatency24h       float64
@@ -234,6 +252,9 @@ func (q *Queries) GetMonitorWithStats(ctx context.Context, arg GetMonitorWithSta
 		&i.ExpectedResponse,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Schedule,
+		&i.ScheduleTimezone,
+		&i.ProbeSelector,
 		&i.Checks24h,
 		&i.SuccessfulChecks24h,
 		&i.AvgLatency24h,
@@ -245,7 +266,7 @@ func (q *Queries) GetMonitorWithStats(ctx context.Context, arg GetMonitorWithSta
 }
 
 const getMonitorsByLocation = `-- name: GetMonitorsByLocation :many
-SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at FROM monitors
+SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector FROM monitors
 WHERE status = 'active'
 AND $1 = ANY(locations)
 ORDER BY created_at DESC
@@ -278,6 +299,9 @@ func (q *Queries) GetMonitorsByLocation(ctx context.Context, locations []string)
 			&i.ExpectedResponse,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Schedule,
+			&i.ScheduleTimezone,
+			&i.ProbeSelector,
 		); err != nil {
 			return nil, err
 		}
@@ -295,7 +319,7 @@ WITH last_check AS (
     FROM monitor_results
     GROUP BY monitor_id
 )
-SELECT m.id, m.user_id, m.name, m.type, m.target, m.interval, m.timeout, m.status, m.locations, m.expected_status_codes, m.follow_redirects, m.verify_ssl, m.port, m.dns_record_type, m.expected_response, m.created_at, m.updated_at
+SELECT m.id, m.user_id, m.name, m.type, m.target, m.interval, m.timeout, m.status, m.locations, m.expected_status_codes, m.follow_redirects, m.verify_ssl, m.port, m.dns_record_type, m.expected_response, m.created_at, m.updated_at, m.schedule, m.schedule_timezone, m.probe_selector
 FROM monitors m
 LEFT JOIN last_check lc ON m.id = lc.monitor_id
 WHERE m.status = 'active'
@@ -333,6 +357,9 @@ func (q *Queries) GetMonitorsNeedingCheck(ctx context.Context) ([]Monitor, error
 			&i.ExpectedResponse,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Schedule,
+			&i.ScheduleTimezone,
+			&i.ProbeSelector,
 		); err != nil {
 			return nil, err
 		}
@@ -345,7 +372,7 @@ func (q *Queries) GetMonitorsNeedingCheck(ctx context.Context) ([]Monitor, error
 }
 
 const listActiveMonitors = `-- name: ListActiveMonitors :many
-SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at FROM monitors
+SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector FROM monitors
 WHERE status = 'active'
 ORDER BY created_at DESC
 `
@@ -377,6 +404,9 @@ func (q *Queries) ListActiveMonitors(ctx context.Context) ([]Monitor, error) {
 			&i.ExpectedResponse,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Schedule,
+			&i.ScheduleTimezone,
+			&i.ProbeSelector,
 		); err != nil {
 			return nil, err
 		}
@@ -389,7 +419,7 @@ func (q *Queries) ListActiveMonitors(ctx context.Context) ([]Monitor, error) {
 }
 
 const listMonitors = `-- name: ListMonitors :many
-SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at FROM monitors
+SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector FROM monitors
 WHERE user_id = $1
 ORDER BY created_at DESC
 `
@@ -421,6 +451,346 @@ func (q *Queries) ListMonitors(ctx context.Context, userID uuid.UUID) ([]Monitor
 			&i.ExpectedResponse,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Schedule,
+			&i.ScheduleTimezone,
+			&i.ProbeSelector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPingExtensions = `-- name: ListPingExtensions :many
+SELECT e.monitor_id, e.packet_count, e.packet_size, e.max_latency_ms, e.max_packet_loss_percent
+FROM monitor_ping_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.status = 'active'
+`
+
+type ListPingExtensionsRow struct {
+	MonitorID            uuid.UUID
+	PacketCount          pgtype.Int4
+	PacketSize           pgtype.Int4
+	MaxLatencyMs         pgtype.Int4
+	MaxPacketLossPercent pgtype.Int4
+}
+
+func (q *Queries) ListPingExtensions(ctx context.Context) ([]ListPingExtensionsRow, error) {
+	rows, err := q.db.Query(ctx, listPingExtensions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPingExtensionsRow
+	for rows.Next() {
+		var i ListPingExtensionsRow
+		if err := rows.Scan(
+			&i.MonitorID,
+			&i.PacketCount,
+			&i.PacketSize,
+			&i.MaxLatencyMs,
+			&i.MaxPacketLossPercent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHttpExtensions = `-- name: ListHttpExtensions :many
+SELECT e.monitor_id, e.method, e.headers, e.body, e.expected_status_code, e.verify_ssl, e.follow_redirects, e.max_redirects, e.content_match, e.content_match_mode, e.basic_auth_user, e.basic_auth_password, e.user_agent, e.cookies, e.assertions, e.max_response_time_ms
+FROM monitor_http_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.status = 'active'
+`
+
+type ListHttpExtensionsRow struct {
+	MonitorID          uuid.UUID
+	Method             pgtype.Text
+	Headers            []byte
+	Body               pgtype.Text
+	ExpectedStatusCode pgtype.Int4
+	VerifySsl          pgtype.Bool
+	FollowRedirects    pgtype.Bool
+	MaxRedirects       pgtype.Int4
+	ContentMatch       pgtype.Text
+	ContentMatchMode   pgtype.Text
+	BasicAuthUser      pgtype.Text
+	BasicAuthPassword  pgtype.Text
+	UserAgent          pgtype.Text
+	Cookies            []byte
+	Assertions         []byte
+	MaxResponseTimeMs  pgtype.Int4
+}
+
+func (q *Queries) ListHttpExtensions(ctx context.Context) ([]ListHttpExtensionsRow, error) {
+	rows, err := q.db.Query(ctx, listHttpExtensions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListHttpExtensionsRow
+	for rows.Next() {
+		var i ListHttpExtensionsRow
+		if err := rows.Scan(
+			&i.MonitorID,
+			&i.Method,
+			&i.Headers,
+			&i.Body,
+			&i.ExpectedStatusCode,
+			&i.VerifySsl,
+			&i.FollowRedirects,
+			&i.MaxRedirects,
+			&i.ContentMatch,
+			&i.ContentMatchMode,
+			&i.BasicAuthUser,
+			&i.BasicAuthPassword,
+			&i.UserAgent,
+			&i.Cookies,
+			&i.Assertions,
+			&i.MaxResponseTimeMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserHttpExtensions = `-- name: ListUserHttpExtensions :many
+SELECT e.monitor_id, e.method, e.headers, e.body, e.expected_status_code, e.verify_ssl, e.follow_redirects, e.max_redirects, e.content_match, e.content_match_mode, e.basic_auth_user, e.basic_auth_password, e.user_agent, e.cookies, e.assertions, e.max_response_time_ms
+FROM monitor_http_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.user_id = $1
+`
+
+type ListUserHttpExtensionsRow struct {
+	MonitorID          uuid.UUID
+	Method             pgtype.Text
+	Headers            []byte
+	Body               pgtype.Text
+	ExpectedStatusCode pgtype.Int4
+	VerifySsl          pgtype.Bool
+	FollowRedirects    pgtype.Bool
+	MaxRedirects       pgtype.Int4
+	ContentMatch       pgtype.Text
+	ContentMatchMode   pgtype.Text
+	BasicAuthUser      pgtype.Text
+	BasicAuthPassword  pgtype.Text
+	UserAgent          pgtype.Text
+	Cookies            []byte
+	Assertions         []byte
+	MaxResponseTimeMs  pgtype.Int4
+}
+
+func (q *Queries) ListUserHttpExtensions(ctx context.Context, userID uuid.UUID) ([]ListUserHttpExtensionsRow, error) {
+	rows, err := q.db.Query(ctx, listUserHttpExtensions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserHttpExtensionsRow
+	for rows.Next() {
+		var i ListUserHttpExtensionsRow
+		if err := rows.Scan(
+			&i.MonitorID,
+			&i.Method,
+			&i.Headers,
+			&i.Body,
+			&i.ExpectedStatusCode,
+			&i.VerifySsl,
+			&i.FollowRedirects,
+			&i.MaxRedirects,
+			&i.ContentMatch,
+			&i.ContentMatchMode,
+			&i.BasicAuthUser,
+			&i.BasicAuthPassword,
+			&i.UserAgent,
+			&i.Cookies,
+			&i.Assertions,
+			&i.MaxResponseTimeMs,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getHttpExtension = `-- name: GetHttpExtension :one
+SELECT monitor_id, method, headers, body, expected_status_code, verify_ssl, follow_redirects, max_redirects, content_match, content_match_mode, basic_auth_user, basic_auth_password, user_agent, cookies, assertions, max_response_time_ms
+FROM monitor_http_extension
+WHERE monitor_id = $1
+`
+
+type GetHttpExtensionRow struct {
+	MonitorID          uuid.UUID
+	Method             pgtype.Text
+	Headers            []byte
+	Body               pgtype.Text
+	ExpectedStatusCode pgtype.Int4
+	VerifySsl          pgtype.Bool
+	FollowRedirects    pgtype.Bool
+	MaxRedirects       pgtype.Int4
+	ContentMatch       pgtype.Text
+	ContentMatchMode   pgtype.Text
+	BasicAuthUser      pgtype.Text
+	BasicAuthPassword  pgtype.Text
+	UserAgent          pgtype.Text
+	Cookies            []byte
+	Assertions         []byte
+	MaxResponseTimeMs  pgtype.Int4
+}
+
+func (q *Queries) GetHttpExtension(ctx context.Context, monitorID uuid.UUID) (GetHttpExtensionRow, error) {
+	row := q.db.QueryRow(ctx, getHttpExtension, monitorID)
+	var i GetHttpExtensionRow
+	err := row.Scan(
+		&i.MonitorID,
+		&i.Method,
+		&i.Headers,
+		&i.Body,
+		&i.ExpectedStatusCode,
+		&i.VerifySsl,
+		&i.FollowRedirects,
+		&i.MaxRedirects,
+		&i.ContentMatch,
+		&i.ContentMatchMode,
+		&i.BasicAuthUser,
+		&i.BasicAuthPassword,
+		&i.UserAgent,
+		&i.Cookies,
+		&i.Assertions,
+		&i.MaxResponseTimeMs,
+	)
+	return i, err
+}
+
+const upsertHttpExtension = `-- name: UpsertHttpExtension :exec
+INSERT INTO monitor_http_extension (
+    monitor_id,
+    url,
+    method,
+    headers,
+    body,
+    max_redirects,
+    content_match,
+    content_match_mode,
+    basic_auth_user,
+    basic_auth_password,
+    user_agent,
+    cookies,
+    assertions,
+    max_response_time_ms
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+ON CONFLICT (monitor_id) DO UPDATE SET
+    url = EXCLUDED.url,
+    method = EXCLUDED.method,
+    headers = EXCLUDED.headers,
+    body = EXCLUDED.body,
+    max_redirects = EXCLUDED.max_redirects,
+    content_match = EXCLUDED.content_match,
+    content_match_mode = EXCLUDED.content_match_mode,
+    basic_auth_user = EXCLUDED.basic_auth_user,
+    basic_auth_password = EXCLUDED.basic_auth_password,
+    user_agent = EXCLUDED.user_agent,
+    cookies = EXCLUDED.cookies,
+    assertions = EXCLUDED.assertions,
+    max_response_time_ms = EXCLUDED.max_response_time_ms
+`
+
+type UpsertHttpExtensionParams struct {
+	MonitorID         uuid.UUID
+	Url               string
+	Method            pgtype.Text
+	Headers           []byte
+	Body              pgtype.Text
+	MaxRedirects      pgtype.Int4
+	ContentMatch      pgtype.Text
+	ContentMatchMode  pgtype.Text
+	BasicAuthUser     pgtype.Text
+	BasicAuthPassword pgtype.Text
+	UserAgent         pgtype.Text
+	Cookies           []byte
+	Assertions        []byte
+	MaxResponseTimeMs pgtype.Int4
+}
+
+func (q *Queries) UpsertHttpExtension(ctx context.Context, arg UpsertHttpExtensionParams) error {
+	_, err := q.db.Exec(ctx, upsertHttpExtension,
+		arg.MonitorID,
+		arg.Url,
+		arg.Method,
+		arg.Headers,
+		arg.Body,
+		arg.MaxRedirects,
+		arg.ContentMatch,
+		arg.ContentMatchMode,
+		arg.BasicAuthUser,
+		arg.BasicAuthPassword,
+		arg.UserAgent,
+		arg.Cookies,
+		arg.Assertions,
+		arg.MaxResponseTimeMs,
+	)
+	return err
+}
+
+const listDnsExtensions = `-- name: ListDnsExtensions :many
+SELECT e.monitor_id, e.record_type, e.nameserver, e.expected_ip, e.expected_value, e.check_propagation, e.resolvers, e.dnssec, e.trust_anchor
+FROM monitor_dns_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.status = 'active'
+`
+
+type ListDnsExtensionsRow struct {
+	MonitorID        uuid.UUID
+	RecordType       string
+	Nameserver       pgtype.Text
+	ExpectedIp       []string
+	ExpectedValue    pgtype.Text
+	CheckPropagation pgtype.Bool
+	Resolvers        []string
+	Dnssec           pgtype.Bool
+	TrustAnchor      pgtype.Text
+}
+
+func (q *Queries) ListDnsExtensions(ctx context.Context) ([]ListDnsExtensionsRow, error) {
+	rows, err := q.db.Query(ctx, listDnsExtensions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDnsExtensionsRow
+	for rows.Next() {
+		var i ListDnsExtensionsRow
+		if err := rows.Scan(
+			&i.MonitorID,
+			&i.RecordType,
+			&i.Nameserver,
+			&i.ExpectedIp,
+			&i.ExpectedValue,
+			&i.CheckPropagation,
+			&i.Resolvers,
+			&i.Dnssec,
+			&i.TrustAnchor,
 		); err != nil {
 			return nil, err
 		}
@@ -432,8 +802,148 @@ func (q *Queries) ListMonitors(ctx context.Context, userID uuid.UUID) ([]Monitor
 	return items, nil
 }
 
+const listUserDnsExtensions = `-- name: ListUserDnsExtensions :many
+SELECT e.monitor_id, e.record_type, e.nameserver, e.expected_ip, e.expected_value, e.check_propagation, e.resolvers, e.dnssec, e.trust_anchor
+FROM monitor_dns_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.user_id = $1
+`
+
+type ListUserDnsExtensionsRow struct {
+	MonitorID        uuid.UUID
+	RecordType       string
+	Nameserver       pgtype.Text
+	ExpectedIp       []string
+	ExpectedValue    pgtype.Text
+	CheckPropagation pgtype.Bool
+	Resolvers        []string
+	Dnssec           pgtype.Bool
+	TrustAnchor      pgtype.Text
+}
+
+func (q *Queries) ListUserDnsExtensions(ctx context.Context, userID uuid.UUID) ([]ListUserDnsExtensionsRow, error) {
+	rows, err := q.db.Query(ctx, listUserDnsExtensions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserDnsExtensionsRow
+	for rows.Next() {
+		var i ListUserDnsExtensionsRow
+		if err := rows.Scan(
+			&i.MonitorID,
+			&i.RecordType,
+			&i.Nameserver,
+			&i.ExpectedIp,
+			&i.ExpectedValue,
+			&i.CheckPropagation,
+			&i.Resolvers,
+			&i.Dnssec,
+			&i.TrustAnchor,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDnsExtension = `-- name: GetDnsExtension :one
+SELECT monitor_id, record_type, nameserver, expected_ip, expected_value, check_propagation, resolvers, dnssec, trust_anchor
+FROM monitor_dns_extension
+WHERE monitor_id = $1
+`
+
+type GetDnsExtensionRow struct {
+	MonitorID        uuid.UUID
+	RecordType       string
+	Nameserver       pgtype.Text
+	ExpectedIp       []string
+	ExpectedValue    pgtype.Text
+	CheckPropagation pgtype.Bool
+	Resolvers        []string
+	Dnssec           pgtype.Bool
+	TrustAnchor      pgtype.Text
+}
+
+func (q *Queries) GetDnsExtension(ctx context.Context, monitorID uuid.UUID) (GetDnsExtensionRow, error) {
+	row := q.db.QueryRow(ctx, getDnsExtension, monitorID)
+	var i GetDnsExtensionRow
+	err := row.Scan(
+		&i.MonitorID,
+		&i.RecordType,
+		&i.Nameserver,
+		&i.ExpectedIp,
+		&i.ExpectedValue,
+		&i.CheckPropagation,
+		&i.Resolvers,
+		&i.Dnssec,
+		&i.TrustAnchor,
+	)
+	return i, err
+}
+
+const upsertDnsExtension = `-- name: UpsertDnsExtension :exec
+INSERT INTO monitor_dns_extension (
+    monitor_id,
+    hostname,
+    record_type,
+    nameserver,
+    expected_ip,
+    expected_value,
+    check_propagation,
+    resolvers,
+    dnssec,
+    trust_anchor
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (monitor_id) DO UPDATE SET
+    hostname = EXCLUDED.hostname,
+    record_type = EXCLUDED.record_type,
+    nameserver = EXCLUDED.nameserver,
+    expected_ip = EXCLUDED.expected_ip,
+    expected_value = EXCLUDED.expected_value,
+    check_propagation = EXCLUDED.check_propagation,
+    resolvers = EXCLUDED.resolvers,
+    dnssec = EXCLUDED.dnssec,
+    trust_anchor = EXCLUDED.trust_anchor
+`
+
+type UpsertDnsExtensionParams struct {
+	MonitorID        uuid.UUID
+	Hostname         string
+	RecordType       string
+	Nameserver       pgtype.Text
+	ExpectedIp       []string
+	ExpectedValue    pgtype.Text
+	CheckPropagation pgtype.Bool
+	Resolvers        []string
+	Dnssec           pgtype.Bool
+	TrustAnchor      pgtype.Text
+}
+
+func (q *Queries) UpsertDnsExtension(ctx context.Context, arg UpsertDnsExtensionParams) error {
+	_, err := q.db.Exec(ctx, upsertDnsExtension,
+		arg.MonitorID,
+		arg.Hostname,
+		arg.RecordType,
+		arg.Nameserver,
+		arg.ExpectedIp,
+		arg.ExpectedValue,
+		arg.CheckPropagation,
+		arg.Resolvers,
+		arg.Dnssec,
+		arg.TrustAnchor,
+	)
+	return err
+}
+
 const listMonitorsByType = `-- name: ListMonitorsByType :many
-SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at FROM monitors
+SELECT id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector FROM monitors
 WHERE user_id = $1 AND type = $2
 ORDER BY created_at DESC
 `
@@ -470,6 +980,9 @@ func (q *Queries) ListMonitorsByType(ctx context.Context, arg ListMonitorsByType
 			&i.ExpectedResponse,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Schedule,
+			&i.ScheduleTimezone,
+			&i.ProbeSelector,
 		); err != nil {
 			return nil, err
 		}
@@ -496,9 +1009,12 @@ SET
     verify_ssl = COALESCE($12, verify_ssl),
     port = COALESCE($13, port),
     dns_record_type = COALESCE($14, dns_record_type),
-    expected_response = COALESCE($15, expected_response)
+    expected_response = COALESCE($15, expected_response),
+    schedule = COALESCE($16, schedule),
+    schedule_timezone = COALESCE($17, schedule_timezone),
+    probe_selector = COALESCE($18, probe_selector)
 WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at
+RETURNING id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector
 `
 
 type UpdateMonitorParams struct {
@@ -517,6 +1033,9 @@ type UpdateMonitorParams struct {
 	Port                pgtype.Int4
 	DnsRecordType       pgtype.Text
 	ExpectedResponse    pgtype.Text
+	Schedule            pgtype.Text
+	ScheduleTimezone    pgtype.Text
+	ProbeSelector       []byte
 }
 
 func (q *Queries) UpdateMonitor(ctx context.Context, arg UpdateMonitorParams) (Monitor, error) {
@@ -536,6 +1055,9 @@ func (q *Queries) UpdateMonitor(ctx context.Context, arg UpdateMonitorParams) (M
 		arg.Port,
 		arg.DnsRecordType,
 		arg.ExpectedResponse,
+		arg.Schedule,
+		arg.ScheduleTimezone,
+		arg.ProbeSelector,
 	)
 	var i Monitor
 	err := row.Scan(
@@ -556,6 +1078,9 @@ func (q *Queries) UpdateMonitor(ctx context.Context, arg UpdateMonitorParams) (M
 		&i.ExpectedResponse,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Schedule,
+		&i.ScheduleTimezone,
+		&i.ProbeSelector,
 	)
 	return i, err
 }
@@ -564,7 +1089,7 @@ const updateMonitorStatus = `-- name: UpdateMonitorStatus :one
 UPDATE monitors
 SET status = $3
 WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at
+RETURNING id, user_id, name, type, target, interval, timeout, status, locations, expected_status_codes, follow_redirects, verify_ssl, port, dns_record_type, expected_response, created_at, updated_at, schedule, schedule_timezone, probe_selector
 `
 
 type UpdateMonitorStatusParams struct {
@@ -594,6 +1119,9 @@ func (q *Queries) UpdateMonitorStatus(ctx context.Context, arg UpdateMonitorStat
 		&i.ExpectedResponse,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Schedule,
+		&i.ScheduleTimezone,
+		&i.ProbeSelector,
 	)
 	return i, err
 }
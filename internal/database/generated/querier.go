@@ -15,19 +15,26 @@ type Querier interface {
 	AcknowledgeAlert(ctx context.Context, arg AcknowledgeAlertParams) (AlertHistory, error)
 	CreateAlertConfig(ctx context.Context, arg CreateAlertConfigParams) (AlertConfig, error)
 	CreateAlertHistory(ctx context.Context, arg CreateAlertHistoryParams) (AlertHistory, error)
+	CreateMaintenanceWindow(ctx context.Context, arg CreateMaintenanceWindowParams) (MaintenanceWindow, error)
 	CreateMonitor(ctx context.Context, arg CreateMonitorParams) (Monitor, error)
-	CreateMonitorResult(ctx context.Context, arg CreateMonitorResultParams) (MonitorResult, error)
+	CreateMonitorResult(ctx context.Context, arg CreateMonitorResultParams) error
+	CreateProbeAgentToken(ctx context.Context, arg CreateProbeAgentTokenParams) (ProbeAgentToken, error)
 	DeleteAlertConfig(ctx context.Context, arg DeleteAlertConfigParams) error
+	DeleteMaintenanceWindow(ctx context.Context, arg DeleteMaintenanceWindowParams) error
 	DeleteMonitor(ctx context.Context, arg DeleteMonitorParams) error
 	DeleteNotificationPreferences(ctx context.Context, arg DeleteNotificationPreferencesParams) error
 	DeleteOldMonitorResults(ctx context.Context, dollar_1 pgtype.Text) error
 	GenerateApiKey(ctx context.Context, userID uuid.UUID) (pgtype.Text, error)
 	GetActiveAlerts(ctx context.Context, userID uuid.UUID) ([]AlertHistory, error)
+	GetActiveProbeAgentToken(ctx context.Context, tokenHash string) (ProbeAgentToken, error)
 	GetAlertConfig(ctx context.Context, arg GetAlertConfigParams) (GetAlertConfigRow, error)
 	GetAlertHistory(ctx context.Context, arg GetAlertHistoryParams) (GetAlertHistoryRow, error)
 	GetAlertStats(ctx context.Context, userID uuid.UUID) (GetAlertStatsRow, error)
+	GetDnsExtension(ctx context.Context, monitorID uuid.UUID) (GetDnsExtensionRow, error)
 	GetFailedChecks(ctx context.Context, arg GetFailedChecksParams) ([]MonitorResult, error)
+	GetHttpExtension(ctx context.Context, monitorID uuid.UUID) (GetHttpExtensionRow, error)
 	GetLatestMonitorResult(ctx context.Context, monitorID uuid.UUID) (MonitorResult, error)
+	GetMaintenanceWindow(ctx context.Context, arg GetMaintenanceWindowParams) (MaintenanceWindow, error)
 	GetMonitor(ctx context.Context, arg GetMonitorParams) (Monitor, error)
 	GetMonitorCount(ctx context.Context, userID uuid.UUID) (int64, error)
 	GetMonitorHourlyStats(ctx context.Context, arg GetMonitorHourlyStatsParams) ([]GetMonitorHourlyStatsRow, error)
@@ -53,19 +60,34 @@ type Querier interface {
 	ListAlertConfigsByMonitor(ctx context.Context, monitorID uuid.UUID) ([]AlertConfig, error)
 	ListAlertHistory(ctx context.Context, arg ListAlertHistoryParams) ([]AlertHistory, error)
 	ListAlertHistoryByMonitor(ctx context.Context, arg ListAlertHistoryByMonitorParams) ([]AlertHistory, error)
+	ListCurrentMaintenanceWindows(ctx context.Context) ([]MaintenanceWindow, error)
+	ListDailyCheckUsage(ctx context.Context) ([]ListDailyCheckUsageRow, error)
+	ListDnsExtensions(ctx context.Context) ([]ListDnsExtensionsRow, error)
+	ListHttpExtensions(ctx context.Context) ([]ListHttpExtensionsRow, error)
+	ListMaintenanceWindows(ctx context.Context, userID uuid.UUID) ([]MaintenanceWindow, error)
+	ListMonitorMaintenanceStates(ctx context.Context) ([]ListMonitorMaintenanceStatesRow, error)
 	ListMonitors(ctx context.Context, userID uuid.UUID) ([]Monitor, error)
 	ListMonitorsByType(ctx context.Context, arg ListMonitorsByTypeParams) ([]Monitor, error)
 	ListNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]NotificationPreference, error)
+	ListPingExtensions(ctx context.Context) ([]ListPingExtensionsRow, error)
+	ListProbeAgentTokens(ctx context.Context, userID uuid.UUID) ([]ProbeAgentToken, error)
+	ListUserDnsExtensions(ctx context.Context, userID uuid.UUID) ([]ListUserDnsExtensionsRow, error)
+	ListUserHttpExtensions(ctx context.Context, userID uuid.UUID) ([]ListUserHttpExtensionsRow, error)
 	MarkNotificationSent(ctx context.Context, id uuid.UUID) error
 	RefreshHourlyStats(ctx context.Context) error
 	ResolveAlert(ctx context.Context, arg ResolveAlertParams) (AlertHistory, error)
 	RevokeApiKey(ctx context.Context, userID uuid.UUID) error
+	RevokeProbeAgentToken(ctx context.Context, arg RevokeProbeAgentTokenParams) (ProbeAgentToken, error)
 	UpdateAlertConfig(ctx context.Context, arg UpdateAlertConfigParams) (AlertConfig, error)
 	UpdateApiKeyLastUsed(ctx context.Context, userID uuid.UUID) error
+	UpdateMaintenanceWindow(ctx context.Context, arg UpdateMaintenanceWindowParams) (MaintenanceWindow, error)
 	UpdateMonitor(ctx context.Context, arg UpdateMonitorParams) (Monitor, error)
 	UpdateMonitorStatus(ctx context.Context, arg UpdateMonitorStatusParams) (Monitor, error)
+	UpdateProbeAgentTokenLastUsed(ctx context.Context, id uuid.UUID) error
 	UpdateUserLimits(ctx context.Context, arg UpdateUserLimitsParams) (UserLimit, error)
 	UpdateUserSettings(ctx context.Context, arg UpdateUserSettingsParams) (UserSetting, error)
+	UpsertDnsExtension(ctx context.Context, arg UpsertDnsExtensionParams) error
+	UpsertHttpExtension(ctx context.Context, arg UpsertHttpExtensionParams) error
 	UpsertNotificationPreferences(ctx context.Context, arg UpsertNotificationPreferencesParams) (NotificationPreference, error)
 	UpsertUserUsageStats(ctx context.Context, arg UpsertUserUsageStatsParams) (UserUsageStat, error)
 }
@@ -106,3 +106,19 @@ SELECT
 FROM user_limits ul
 LEFT JOIN current_usage cu ON true
 WHERE ul.user_id = $1;
+
+-- name: ListDailyCheckUsage :many
+WITH user_daily_checks AS (
+    SELECT m.user_id, COUNT(*) as check_count
+    FROM monitor_results mr
+    JOIN monitors m ON mr.monitor_id = m.id
+    WHERE mr.time >= CURRENT_DATE
+    GROUP BY m.user_id
+)
+SELECT
+    u.user_id,
+    ul.max_checks_per_day,
+    COALESCE(udc.check_count, 0) as current_check_count
+FROM (SELECT DISTINCT user_id FROM monitors) u
+LEFT JOIN user_limits ul ON ul.user_id = u.user_id
+LEFT JOIN user_daily_checks udc ON udc.user_id = u.user_id;
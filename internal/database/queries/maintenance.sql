@@ -0,0 +1,58 @@
+-- name: CreateMaintenanceWindow :one
+INSERT INTO maintenance_windows (
+    user_id,
+    name,
+    description,
+    monitor_ids,
+    group_ids,
+    tags,
+    starts_at,
+    ends_at,
+    duration_seconds,
+    recurrence,
+    timezone,
+    action
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+) RETURNING *;
+
+-- name: GetMaintenanceWindow :one
+SELECT * FROM maintenance_windows
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListMaintenanceWindows :many
+SELECT * FROM maintenance_windows
+WHERE user_id = $1
+ORDER BY starts_at DESC;
+
+-- name: UpdateMaintenanceWindow :one
+UPDATE maintenance_windows
+SET
+    name = $3,
+    description = $4,
+    monitor_ids = $5,
+    group_ids = $6,
+    tags = $7,
+    starts_at = $8,
+    ends_at = $9,
+    duration_seconds = $10,
+    recurrence = $11,
+    timezone = $12,
+    action = $13,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteMaintenanceWindow :exec
+DELETE FROM maintenance_windows
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListCurrentMaintenanceWindows :many
+SELECT * FROM maintenance_windows
+WHERE ends_at IS NULL OR ends_at > NOW()
+ORDER BY starts_at ASC;
+
+-- name: ListMonitorMaintenanceStates :many
+SELECT id, user_id, group_id, tags, maintenance_mode, maintenance_until
+FROM monitors
+WHERE status = 'active';
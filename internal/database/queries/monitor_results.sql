@@ -1,6 +1,7 @@
--- name: CreateMonitorResult :one
+-- name: CreateMonitorResult :exec
 INSERT INTO monitor_results (
     monitor_id,
+    time,
     location,
     success,
     latency,
@@ -14,8 +15,9 @@ INSERT INTO monitor_results (
     first_byte_time,
     total_time
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
-) RETURNING *;
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+ON CONFLICT (monitor_id, location, time) DO NOTHING;
 
 -- name: GetMonitorResults :many
 SELECT * FROM monitor_results
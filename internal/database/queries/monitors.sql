@@ -13,9 +13,12 @@ INSERT INTO monitors (
     verify_ssl,
     port,
     dns_record_type,
-    expected_response
+    expected_response,
+    schedule,
+    schedule_timezone,
+    probe_selector
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
 ) RETURNING *;
 
 -- name: GetMonitor :one
@@ -32,6 +35,106 @@ SELECT * FROM monitors
 WHERE status = 'active'
 ORDER BY created_at DESC;
 
+-- name: ListPingExtensions :many
+SELECT e.monitor_id, e.packet_count, e.packet_size, e.max_latency_ms, e.max_packet_loss_percent
+FROM monitor_ping_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.status = 'active';
+
+-- name: ListHttpExtensions :many
+SELECT e.monitor_id, e.method, e.headers, e.body, e.expected_status_code, e.verify_ssl, e.follow_redirects, e.max_redirects, e.content_match, e.content_match_mode, e.basic_auth_user, e.basic_auth_password, e.user_agent, e.cookies, e.assertions, e.max_response_time_ms
+FROM monitor_http_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.status = 'active';
+
+-- name: ListUserHttpExtensions :many
+SELECT e.monitor_id, e.method, e.headers, e.body, e.expected_status_code, e.verify_ssl, e.follow_redirects, e.max_redirects, e.content_match, e.content_match_mode, e.basic_auth_user, e.basic_auth_password, e.user_agent, e.cookies, e.assertions, e.max_response_time_ms
+FROM monitor_http_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.user_id = $1;
+
+-- name: GetHttpExtension :one
+SELECT monitor_id, method, headers, body, expected_status_code, verify_ssl, follow_redirects, max_redirects, content_match, content_match_mode, basic_auth_user, basic_auth_password, user_agent, cookies, assertions, max_response_time_ms
+FROM monitor_http_extension
+WHERE monitor_id = $1;
+
+-- name: UpsertHttpExtension :exec
+INSERT INTO monitor_http_extension (
+    monitor_id,
+    url,
+    method,
+    headers,
+    body,
+    max_redirects,
+    content_match,
+    content_match_mode,
+    basic_auth_user,
+    basic_auth_password,
+    user_agent,
+    cookies,
+    assertions,
+    max_response_time_ms
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+ON CONFLICT (monitor_id) DO UPDATE SET
+    url = EXCLUDED.url,
+    method = EXCLUDED.method,
+    headers = EXCLUDED.headers,
+    body = EXCLUDED.body,
+    max_redirects = EXCLUDED.max_redirects,
+    content_match = EXCLUDED.content_match,
+    content_match_mode = EXCLUDED.content_match_mode,
+    basic_auth_user = EXCLUDED.basic_auth_user,
+    basic_auth_password = EXCLUDED.basic_auth_password,
+    user_agent = EXCLUDED.user_agent,
+    cookies = EXCLUDED.cookies,
+    assertions = EXCLUDED.assertions,
+    max_response_time_ms = EXCLUDED.max_response_time_ms;
+
+-- name: ListDnsExtensions :many
+SELECT e.monitor_id, e.record_type, e.nameserver, e.expected_ip, e.expected_value, e.check_propagation, e.resolvers, e.dnssec, e.trust_anchor
+FROM monitor_dns_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.status = 'active';
+
+-- name: ListUserDnsExtensions :many
+SELECT e.monitor_id, e.record_type, e.nameserver, e.expected_ip, e.expected_value, e.check_propagation, e.resolvers, e.dnssec, e.trust_anchor
+FROM monitor_dns_extension e
+JOIN monitors m ON m.id = e.monitor_id
+WHERE m.user_id = $1;
+
+-- name: GetDnsExtension :one
+SELECT monitor_id, record_type, nameserver, expected_ip, expected_value, check_propagation, resolvers, dnssec, trust_anchor
+FROM monitor_dns_extension
+WHERE monitor_id = $1;
+
+-- name: UpsertDnsExtension :exec
+INSERT INTO monitor_dns_extension (
+    monitor_id,
+    hostname,
+    record_type,
+    nameserver,
+    expected_ip,
+    expected_value,
+    check_propagation,
+    resolvers,
+    dnssec,
+    trust_anchor
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (monitor_id) DO UPDATE SET
+    hostname = EXCLUDED.hostname,
+    record_type = EXCLUDED.record_type,
+    nameserver = EXCLUDED.nameserver,
+    expected_ip = EXCLUDED.expected_ip,
+    expected_value = EXCLUDED.expected_value,
+    check_propagation = EXCLUDED.check_propagation,
+    resolvers = EXCLUDED.resolvers,
+    dnssec = EXCLUDED.dnssec,
+    trust_anchor = EXCLUDED.trust_anchor;
+
 -- name: ListMonitorsByType :many
 SELECT * FROM monitors
 WHERE user_id = $1 AND type = $2
@@ -52,7 +155,10 @@ SET
     verify_ssl = COALESCE($12, verify_ssl),
     port = COALESCE($13, port),
     dns_record_type = COALESCE($14, dns_record_type),
-    expected_response = COALESCE($15, expected_response)
+    expected_response = COALESCE($15, expected_response),
+    schedule = COALESCE($16, schedule),
+    schedule_timezone = COALESCE($17, schedule_timezone),
+    probe_selector = COALESCE($18, probe_selector)
 WHERE id = $1 AND user_id = $2
 RETURNING *;
 
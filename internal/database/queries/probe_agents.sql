@@ -0,0 +1,29 @@
+-- name: CreateProbeAgentToken :one
+INSERT INTO probe_agent_tokens (
+    user_id,
+    name,
+    token_hash,
+    token_prefix
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetActiveProbeAgentToken :one
+SELECT * FROM probe_agent_tokens
+WHERE token_hash = $1 AND revoked_at IS NULL;
+
+-- name: ListProbeAgentTokens :many
+SELECT * FROM probe_agent_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: RevokeProbeAgentToken :one
+UPDATE probe_agent_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: UpdateProbeAgentTokenLastUsed :exec
+UPDATE probe_agent_tokens
+SET last_used_at = NOW()
+WHERE id = $1;
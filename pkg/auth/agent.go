@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// agentTokenPrefix marks tokens issued to private probe agents
+const agentTokenPrefix = "pa_"
+
+// GenerateAgentToken creates a new private probe agent token. Only its hash
+// is stored, so the token itself can be shown to the user once.
+func GenerateAgentToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate agent token: %w", err)
+	}
+	return agentTokenPrefix + hex.EncodeToString(bytes), nil
+}
+
+// HashAgentToken returns the hash agent tokens are stored and looked up by
+func HashAgentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AgentTokenPrefix returns the start of a token, enough for users to tell
+// their tokens apart without revealing them
+func AgentTokenPrefix(token string) string {
+	if len(token) <= len(agentTokenPrefix)+8 {
+		return token
+	}
+	return token[:len(agentTokenPrefix)+8]
+}
+
+// RegistrationProof is what a shared probe worker sends to show it holds the
+// operator's registration secret. It binds the secret to the worker's ID and
+// signing key, so a captured registration cannot be replayed with another key.
+func RegistrationProof(secret, workerID, publicKey string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(workerID + "\n" + publicKey))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRegistrationProof reports whether proof was made with secret for the
+// given worker ID and signing key
+func VerifyRegistrationProof(secret, workerID, publicKey, proof string) bool {
+	if secret == "" || proof == "" {
+		return false
+	}
+	expected := RegistrationProof(secret, workerID, publicKey)
+	return hmac.Equal([]byte(expected), []byte(proof))
+}
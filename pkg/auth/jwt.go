@@ -86,3 +86,31 @@ func JWTMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		}
 	}
 }
+
+// AdminMiddleware restricts a route to platform operators and must run after
+// JWTMiddleware. Supabase service-role tokens are admitted, as are users whose
+// app_metadata carries the "admin" role.
+func AdminMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_roles") == "service_role" {
+			c.Next()
+			return
+		}
+
+		if claims, ok := c.Get("JWT_CLAIMS"); ok {
+			if mapClaims, ok := claims.(jwt.MapClaims); ok {
+				if appMetadata, ok := mapClaims["app_metadata"].(map[string]interface{}); ok {
+					if role, _ := appMetadata["role"].(string); role == "admin" {
+						c.Next()
+						return
+					}
+				}
+			}
+		}
+
+		logger.Warn("non-admin user denied access",
+			zap.String("user_id", c.GetString("user_id")),
+			zap.String("path", c.FullPath()))
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+	}
+}
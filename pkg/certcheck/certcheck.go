@@ -0,0 +1,94 @@
+// Package certcheck describes the certificate a server presents during a TLS
+// handshake. Probe workers add the description to the details of HTTPS and
+// TLS-over-TCP checks, and ingestion and the alert manager read the expiry
+// back from them.
+package certcheck
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys of the check result details that describe the certificate
+const (
+	// DetailExpiry is when the leaf certificate expires, in RFC 3339
+	DetailExpiry        = "certificate_expiry"
+	DetailDaysRemaining = "certificate_days_remaining"
+	DetailSubject       = "certificate_subject"
+	DetailIssuer        = "certificate_issuer"
+	// DetailSANs lists the DNS names and addresses the certificate is valid
+	// for, separated by commas
+	DetailSANs          = "certificate_sans"
+	DetailHostnameMatch = "certificate_hostname_match"
+	DetailHostnameError = "certificate_hostname_error"
+	DetailChainValid    = "certificate_chain_valid"
+	DetailChainError    = "certificate_chain_error"
+	DetailChainLength   = "certificate_chain_length"
+	// DetailChainExpiry is when the first certificate the server sent, leaf
+	// or intermediate, expires
+	DetailChainExpiry = "certificate_chain_expiry"
+	DetailTLSVersion  = "tls_version"
+	DetailTLSCipher   = "tls_cipher"
+)
+
+// Inspect describes the certificates a server presented for host. The chain
+// is checked against the system roots whether or not the handshake verified
+// it, so that checks which skip verification still report a broken chain.
+func Inspect(state tls.ConnectionState, host string, now time.Time) map[string]string {
+	details := map[string]string{
+		DetailTLSVersion: tls.VersionName(state.Version),
+		DetailTLSCipher:  tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) == 0 {
+		return details
+	}
+
+	leaf := state.PeerCertificates[0]
+	details[DetailExpiry] = leaf.NotAfter.UTC().Format(time.RFC3339)
+	details[DetailDaysRemaining] = strconv.Itoa(int(leaf.NotAfter.Sub(now).Hours() / 24))
+	details[DetailSubject] = leaf.Subject.String()
+	details[DetailIssuer] = leaf.Issuer.String()
+	details[DetailChainLength] = strconv.Itoa(len(state.PeerCertificates))
+
+	sans := append([]string{}, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	details[DetailSANs] = strings.Join(sans, ",")
+
+	chainExpiry := leaf.NotAfter
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+		if cert.NotAfter.Before(chainExpiry) {
+			chainExpiry = cert.NotAfter
+		}
+	}
+	details[DetailChainExpiry] = chainExpiry.UTC().Format(time.RFC3339)
+
+	details[DetailHostnameMatch] = "true"
+	if err := leaf.VerifyHostname(host); err != nil {
+		details[DetailHostnameMatch] = "false"
+		details[DetailHostnameError] = err.Error()
+	}
+
+	details[DetailChainValid] = "true"
+	if _, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: now}); err != nil {
+		details[DetailChainValid] = "false"
+		details[DetailChainError] = err.Error()
+	}
+
+	return details
+}
+
+// Expiry reads when the leaf certificate expires from check result details
+func Expiry(details map[string]string) (time.Time, bool) {
+	expiry, err := time.Parse(time.RFC3339, details[DetailExpiry])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiry, true
+}
@@ -0,0 +1,358 @@
+package certcheck
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issued is a certificate and the key it was issued for
+type issued struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// testRoot signs the certificates served in these tests. TestMain makes it
+// the only system root, so that chains it signs verify.
+var testRoot *issued
+
+func TestMain(m *testing.M) {
+	os.Exit(runWithTestRoot(m))
+}
+
+func runWithTestRoot(m *testing.M) int {
+	var err error
+	testRoot, err = issue(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// The system roots are loaded once, on first use, from SSL_CERT_FILE
+	dir, err := os.MkdirTemp("", "certcheck")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	rootFile := filepath.Join(dir, "root.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testRoot.cert.Raw})
+	if err := os.WriteFile(rootFile, data, 0o600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	os.Setenv("SSL_CERT_FILE", rootFile)
+	os.Setenv("SSL_CERT_DIR", dir)
+
+	return m.Run()
+}
+
+// issue creates a certificate from template, signed by parent or, without
+// one, by itself
+func issue(template *x509.Certificate, parent *issued) (*issued, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serial
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &issued{cert: cert, key: key}, nil
+}
+
+// issueIntermediate creates an intermediate CA under the test root
+func issueIntermediate(t *testing.T, notAfter time.Time) *issued {
+	t.Helper()
+	intermediate, err := issue(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test Intermediate CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, testRoot)
+	if err != nil {
+		t.Fatalf("failed to issue intermediate: %v", err)
+	}
+	return intermediate
+}
+
+// issueLeaf creates a server certificate for example.test and the loopback
+// address
+func issueLeaf(t *testing.T, parent *issued, notAfter time.Time) *issued {
+	t.Helper()
+	leaf, err := issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "example.test", Organization: []string{"Example"}},
+		DNSNames:    []string{"example.test", "www.example.test"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:   time.Now().Add(-time.Hour),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, parent)
+	if err != nil {
+		t.Fatalf("failed to issue leaf: %v", err)
+	}
+	return leaf
+}
+
+// handshake serves chain, leaf first, from a TLS server and returns the state
+// of a connection to it that skips verification, as checks that ignore
+// certificate errors do
+func handshake(t *testing.T, leaf *issued, chain ...*x509.Certificate) tls.ConnectionState {
+	t.Helper()
+
+	certificate := tls.Certificate{PrivateKey: leaf.key, Leaf: leaf.cert}
+	for _, cert := range append([]*x509.Certificate{leaf.cert}, chain...) {
+		certificate.Certificate = append(certificate.Certificate, cert.Raw)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState()
+}
+
+func skipWithoutRootOverride(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("system roots cannot be replaced on this platform")
+	}
+}
+
+func TestInspectChain(t *testing.T) {
+	skipWithoutRootOverride(t)
+
+	now := time.Now()
+	leafExpiry := now.Add(90 * 24 * time.Hour).Truncate(time.Second)
+	intermediate := issueIntermediate(t, now.Add(365*24*time.Hour))
+	leaf := issueLeaf(t, intermediate, leafExpiry)
+
+	details := Inspect(handshake(t, leaf, intermediate.cert), "example.test", now)
+
+	want := map[string]string{
+		DetailExpiry:        leafExpiry.UTC().Format(time.RFC3339),
+		DetailDaysRemaining: "89",
+		DetailSubject:       "CN=example.test,O=Example",
+		DetailIssuer:        "CN=Test Intermediate CA",
+		DetailSANs:          "example.test,www.example.test,127.0.0.1",
+		DetailHostnameMatch: "true",
+		DetailChainValid:    "true",
+		DetailChainLength:   "2",
+		DetailChainExpiry:   leafExpiry.UTC().Format(time.RFC3339),
+		DetailTLSVersion:    "TLS 1.3",
+	}
+	for key, value := range want {
+		if details[key] != value {
+			t.Errorf("%s = %q, want %q", key, details[key], value)
+		}
+	}
+	for _, key := range []string{DetailHostnameError, DetailChainError} {
+		if _, ok := details[key]; ok {
+			t.Errorf("%s = %q, want none", key, details[key])
+		}
+	}
+	if details[DetailTLSCipher] == "" {
+		t.Error("no cipher reported")
+	}
+
+	expiry, ok := Expiry(details)
+	if !ok || !expiry.Equal(leafExpiry) {
+		t.Errorf("Expiry() = %s, %v, want %s", expiry, ok, leafExpiry)
+	}
+}
+
+func TestInspectChainProblems(t *testing.T) {
+	skipWithoutRootOverride(t)
+
+	now := time.Now()
+	intermediate := issueIntermediate(t, now.Add(365*24*time.Hour))
+	leaf := issueLeaf(t, intermediate, now.Add(90*24*time.Hour))
+
+	t.Run("hostname mismatch", func(t *testing.T) {
+		details := Inspect(handshake(t, leaf, intermediate.cert), "other.test", now)
+		if details[DetailHostnameMatch] != "false" || !strings.Contains(details[DetailHostnameError], "other.test") {
+			t.Errorf("hostname match = %q, error %q", details[DetailHostnameMatch], details[DetailHostnameError])
+		}
+		// The chain itself is still fine
+		if details[DetailChainValid] != "true" {
+			t.Errorf("chain valid = %q, error %q", details[DetailChainValid], details[DetailChainError])
+		}
+	})
+
+	t.Run("address in SANs", func(t *testing.T) {
+		details := Inspect(handshake(t, leaf, intermediate.cert), "127.0.0.1", now)
+		if details[DetailHostnameMatch] != "true" {
+			t.Errorf("hostname match = %q, error %q", details[DetailHostnameMatch], details[DetailHostnameError])
+		}
+	})
+
+	t.Run("missing intermediate", func(t *testing.T) {
+		details := Inspect(handshake(t, leaf), "example.test", now)
+		if details[DetailChainLength] != "1" {
+			t.Errorf("chain length = %q, want 1", details[DetailChainLength])
+		}
+		if details[DetailChainValid] != "false" || !strings.Contains(details[DetailChainError], "unknown authority") {
+			t.Errorf("chain valid = %q, error %q", details[DetailChainValid], details[DetailChainError])
+		}
+	})
+
+	t.Run("self-signed", func(t *testing.T) {
+		self, err := issue(&x509.Certificate{
+			Subject:   pkix.Name{CommonName: "example.test"},
+			DNSNames:  []string{"example.test"},
+			NotBefore: now.Add(-time.Hour),
+			NotAfter:  now.Add(24 * time.Hour),
+		}, nil)
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		details := Inspect(handshake(t, self), "example.test", now)
+		if details[DetailChainValid] != "false" {
+			t.Errorf("chain valid = %q, want false", details[DetailChainValid])
+		}
+		if details[DetailSubject] != details[DetailIssuer] {
+			t.Errorf("subject %q and issuer %q differ", details[DetailSubject], details[DetailIssuer])
+		}
+	})
+
+	t.Run("intermediate expires first", func(t *testing.T) {
+		intermediateExpiry := now.Add(30 * 24 * time.Hour).Truncate(time.Second)
+		shortIntermediate := issueIntermediate(t, intermediateExpiry)
+		longLeaf := issueLeaf(t, shortIntermediate, now.Add(90*24*time.Hour))
+
+		details := Inspect(handshake(t, longLeaf, shortIntermediate.cert), "example.test", now)
+		if got := details[DetailChainExpiry]; got != intermediateExpiry.UTC().Format(time.RFC3339) {
+			t.Errorf("chain expiry = %q, want the intermediate's %s", got, intermediateExpiry.UTC().Format(time.RFC3339))
+		}
+		if details[DetailDaysRemaining] != "89" {
+			t.Errorf("days remaining = %q, want the leaf's 89", details[DetailDaysRemaining])
+		}
+		if details[DetailChainValid] != "true" {
+			t.Errorf("chain valid = %q, error %q", details[DetailChainValid], details[DetailChainError])
+		}
+
+		// Once the intermediate has expired the chain no longer verifies
+		later := Inspect(handshake(t, longLeaf, shortIntermediate.cert), "example.test", intermediateExpiry.Add(time.Hour))
+		if later[DetailChainValid] != "false" || !strings.Contains(later[DetailChainError], "expired") {
+			t.Errorf("chain valid = %q, error %q", later[DetailChainValid], later[DetailChainError])
+		}
+	})
+}
+
+func TestInspectDaysRemaining(t *testing.T) {
+	intermediate := issueIntermediate(t, time.Now().Add(365*24*time.Hour))
+	leaf := issueLeaf(t, intermediate, time.Now().Add(60*24*time.Hour))
+	expiry := leaf.cert.NotAfter
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf.cert, intermediate.cert}}
+
+	// Whole days are counted, rounding towards zero
+	tests := []struct {
+		untilExpiry time.Duration
+		want        string
+	}{
+		{30 * 24 * time.Hour, "30"},
+		{30*24*time.Hour - time.Minute, "29"},
+		{24 * time.Hour, "1"},
+		{23 * time.Hour, "0"},
+		{0, "0"},
+		{-23 * time.Hour, "0"},
+		{-24 * time.Hour, "-1"},
+		{-10 * 24 * time.Hour, "-10"},
+	}
+
+	for _, tt := range tests {
+		details := Inspect(state, "example.test", expiry.Add(-tt.untilExpiry))
+		if got := details[DetailDaysRemaining]; got != tt.want {
+			t.Errorf("%s before expiry: days remaining = %s, want %s", tt.untilExpiry, got, tt.want)
+		}
+	}
+
+	expired := Inspect(state, "example.test", expiry.Add(time.Hour))
+	if expired[DetailChainValid] != "false" || !strings.Contains(expired[DetailChainError], "expired") {
+		t.Errorf("expired leaf: chain valid = %q, error %q", expired[DetailChainValid], expired[DetailChainError])
+	}
+}
+
+func TestInspectWithoutCertificates(t *testing.T) {
+	details := Inspect(tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, "example.test", time.Now())
+	want := map[string]string{
+		DetailTLSVersion: "TLS 1.2",
+		DetailTLSCipher:  "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	}
+	if len(details) != len(want) {
+		t.Errorf("Inspect() = %v, want only %v", details, want)
+	}
+	for key, value := range want {
+		if details[key] != value {
+			t.Errorf("%s = %q, want %q", key, details[key], value)
+		}
+	}
+	if _, ok := Expiry(details); ok {
+		t.Error("Expiry() found an expiry without a certificate")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"2025-06-01T12:00:00Z", time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), true},
+		{"2025-06-01T14:00:00+02:00", time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"2025-06-01", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Expiry(map[string]string{DetailExpiry: tt.value})
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("Expiry(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"* * * FOO *",
+		"@every 5m",
+	}
+
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, time.January, 15, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 15, 10, 18, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC)},
+		{"17 * * * *", time.Date(2025, 1, 15, 11, 17, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"0,30 10-11 * * *", time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2025, 1, 15, 10, 25, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * FEB *", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * MON-FRI", time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * sat", time.Date(2025, 1, 18, 9, 0, 0, 0, time.UTC)},
+		// Sunday written as 7
+		{"0 9 * * 7", time.Date(2025, 1, 19, 9, 0, 0, 0, time.UTC)},
+		// Day of month or day of week when both are restricted
+		{"0 0 20 * 5", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Never fires
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: Next(%s) = %s, want %s", tt.expr, from, got, tt.want)
+		}
+	}
+}
+
+func TestNextInTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "local time of day",
+			expr: "0 9 * * *",
+			from: time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2025, 1, 15, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "same local time after clocks go forward",
+			expr: "0 9 * * *",
+			from: time.Date(2025, 3, 8, 10, 0, 0, 0, newYork),
+			want: time.Date(2025, 3, 9, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "skipped hour moves to the next day",
+			expr: "30 2 * * *",
+			from: time.Date(2025, 3, 9, 0, 0, 0, 0, newYork),
+			want: time.Date(2025, 3, 10, 2, 30, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseInTimezone(tt.expr, "America/New_York")
+			if err != nil {
+				t.Fatalf("ParseInTimezone() error = %v", err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseInTimezone("* * * * *", "Mars/Olympus_Mons"); err == nil {
+		t.Error("ParseInTimezone() with unknown zone succeeded, want error")
+	}
+}
+
+func TestNextN(t *testing.T) {
+	schedule, err := Parse("*/15 * * * *")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	from := time.Date(2025, 1, 15, 10, 50, 0, 0, time.UTC)
+	want := []time.Time{
+		time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 15, 11, 15, 0, 0, time.UTC),
+		time.Date(2025, 1, 15, 11, 30, 0, 0, time.UTC),
+	}
+	got := schedule.NextN(from, 3)
+	if len(got) != len(want) {
+		t.Fatalf("NextN() returned %d times, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("NextN()[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	never, err := Parse("0 0 31 4 *")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := never.NextN(from, 3); len(got) != 0 {
+		t.Errorf("NextN() for a schedule that never fires = %v, want none", got)
+	}
+}
@@ -0,0 +1,268 @@
+// Package dnscheck queries a nameserver for one type of record and compares
+// the answers with the values a monitor expects. Queries go straight to the
+// nameserver rather than through the system resolver, so a check can name any
+// server, including one started in-process.
+package dnscheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultRecordType is queried when the monitor does not say
+const DefaultRecordType = "A"
+
+// RecordTypes lists the record types a check may query
+var RecordTypes = []string{"A", "AAAA", "CNAME", "MX", "TXT", "NS", "SOA", "SRV"}
+
+// Keys of the check result details of a DNS check. Answers are reported as
+// answer_1, answer_2 and so on.
+const (
+	DetailRecordType  = "record_type"
+	DetailNameserver  = "nameserver"
+	DetailRcode       = "rcode"
+	DetailAnswerCount = "answer_count"
+	// DetailResolutionTime shares its key with the DNS phase of HTTP checks,
+	// so that both are stored as dns_resolution_time
+	DetailResolutionTime = "dns_time_ms"
+)
+
+// Config is the DNS configuration of a check. The zero value asks the
+// worker's own nameserver for A records and accepts any answer.
+type Config struct {
+	RecordType string `json:"record_type,omitempty"`
+	// Nameserver is a host or host:port; port 53 is assumed
+	Nameserver string `json:"nameserver,omitempty"`
+	// ExpectedValues must all be among the answers. MX and SRV answers also
+	// match on their target name alone.
+	ExpectedValues []string `json:"expected_values,omitempty"`
+	// CheckPropagation also asks every authoritative nameserver of the zone,
+	// and each of Resolvers, and fails if their answers differ
+	CheckPropagation bool     `json:"check_propagation,omitempty"`
+	Resolvers        []string `json:"resolvers,omitempty"`
+	// DNSSEC validates the records' chain of trust from TrustAnchor, a DS
+	// record, or from RootTrustAnchor
+	DNSSEC      bool   `json:"dnssec,omitempty"`
+	TrustAnchor string `json:"trust_anchor,omitempty"`
+}
+
+// Type returns the record type to query
+func (c *Config) Type() string {
+	if c == nil || c.RecordType == "" {
+		return DefaultRecordType
+	}
+	return strings.ToUpper(c.RecordType)
+}
+
+// ValidateRecordType checks that a record type can be queried
+func ValidateRecordType(recordType string) error {
+	if !slices.Contains(RecordTypes, strings.ToUpper(recordType)) {
+		return fmt.Errorf("record type must be one of %s", strings.Join(RecordTypes, ", "))
+	}
+	return nil
+}
+
+// NameserverAddress returns the host:port to send queries for nameserver to
+func NameserverAddress(nameserver string) (string, error) {
+	if _, _, err := net.SplitHostPort(nameserver); err == nil {
+		return nameserver, nil
+	}
+	// Bare hosts, including IPv6 addresses without brackets
+	host := strings.Trim(nameserver, "[]")
+	if host == "" || strings.ContainsAny(host, " /") {
+		return "", fmt.Errorf("invalid nameserver %q", nameserver)
+	}
+	return net.JoinHostPort(host, "53"), nil
+}
+
+// Result is a nameserver's answer to a check
+type Result struct {
+	Nameserver string
+	Rcode      string
+	// Answers holds the records of the queried type, rendered by Format
+	Answers []string
+	RTT     time.Duration
+}
+
+// Details returns the check result details describing the answer
+func (r *Result) Details(recordType string) map[string]string {
+	details := map[string]string{
+		DetailRecordType:     recordType,
+		DetailNameserver:     r.Nameserver,
+		DetailRcode:          r.Rcode,
+		DetailAnswerCount:    strconv.Itoa(len(r.Answers)),
+		DetailResolutionTime: strconv.FormatInt(r.RTT.Milliseconds(), 10),
+	}
+	for i, answer := range r.Answers {
+		details[fmt.Sprintf("answer_%d", i+1)] = answer
+	}
+	return details
+}
+
+// Resolver sends the queries of DNS checks
+type Resolver struct {
+	// Nameserver answers checks that do not name their own
+	Nameserver string
+}
+
+// NewResolver returns a resolver whose default nameserver is the first one
+// in /etc/resolv.conf
+func NewResolver() *Resolver {
+	nameserver := "127.0.0.1:53"
+	if config, err := dns.ClientConfigFromFile("/etc/resolv.conf"); err == nil && len(config.Servers) > 0 {
+		nameserver = net.JoinHostPort(config.Servers[0], config.Port)
+	}
+	return &Resolver{Nameserver: nameserver}
+}
+
+// Query asks the check's nameserver for the records of its type at name.
+// Answers truncated over UDP are asked for again over TCP.
+func (r *Resolver) Query(ctx context.Context, name string, config *Config) (*Result, error) {
+	nameserver, err := r.nameserver(config)
+	if err != nil {
+		return nil, err
+	}
+	qtype, err := recordType(config)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, rtt, err := exchange(ctx, question(name, qtype, true), nameserver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Nameserver: nameserver,
+		Rcode:      dns.RcodeToString[resp.Rcode],
+		Answers:    answers(resp, qtype),
+		RTT:        rtt,
+	}, nil
+}
+
+// nameserver returns the address of the check's nameserver
+func (r *Resolver) nameserver(config *Config) (string, error) {
+	if config == nil || config.Nameserver == "" {
+		return r.Nameserver, nil
+	}
+	return NameserverAddress(config.Nameserver)
+}
+
+// recordType returns the query type of the check's record type
+func recordType(config *Config) (uint16, error) {
+	qtype, ok := dns.StringToType[config.Type()]
+	if !ok {
+		return 0, fmt.Errorf("unsupported record type %s", config.Type())
+	}
+	return qtype, nil
+}
+
+// question builds a query for the records of type qtype at name. recurse
+// asks the server to resolve it rather than answer from its own zones.
+func question(name string, qtype uint16, recurse bool) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = recurse
+	return msg
+}
+
+// exchange sends a query to nameserver, asking again over TCP if the answer
+// was truncated
+func exchange(ctx context.Context, msg *dns.Msg, nameserver string) (*dns.Msg, time.Duration, error) {
+	client := &dns.Client{}
+	resp, rtt, err := client.ExchangeContext(ctx, msg, nameserver)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		var retry time.Duration
+		resp, retry, err = client.ExchangeContext(ctx, msg, nameserver)
+		rtt += retry
+	}
+	if err != nil {
+		return nil, rtt, fmt.Errorf("query to %s failed: %w", nameserver, err)
+	}
+	return resp, rtt, nil
+}
+
+// answers renders the records of type qtype in a response
+func answers(resp *dns.Msg, qtype uint16) []string {
+	var rendered []string
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype == qtype {
+			rendered = append(rendered, Format(rr))
+		}
+	}
+	return rendered
+}
+
+// Check judges a result: the nameserver must have answered without error,
+// with at least one record and with every expected value
+func (c *Config) Check(name string, result *Result) error {
+	if result.Rcode != dns.RcodeToString[dns.RcodeSuccess] {
+		return fmt.Errorf("%s answered %s for %s", result.Nameserver, result.Rcode, name)
+	}
+	if len(result.Answers) == 0 {
+		return fmt.Errorf("no %s records for %s", c.Type(), name)
+	}
+	if c == nil {
+		return nil
+	}
+	for _, expected := range c.ExpectedValues {
+		if !slices.ContainsFunc(result.Answers, func(answer string) bool { return matches(answer, expected) }) {
+			return fmt.Errorf("expected %s record %q not found, got %s", c.Type(), expected, strings.Join(result.Answers, ", "))
+		}
+	}
+	return nil
+}
+
+// Format renders a record's data the way expected values are written: IP
+// addresses as usual, names without the trailing dot and MX, SRV and SOA
+// fields separated by spaces
+func Format(rr dns.RR) string {
+	switch v := rr.(type) {
+	case *dns.A:
+		return v.A.String()
+	case *dns.AAAA:
+		return v.AAAA.String()
+	case *dns.CNAME:
+		return hostname(v.Target)
+	case *dns.NS:
+		return hostname(v.Ns)
+	case *dns.MX:
+		return fmt.Sprintf("%d %s", v.Preference, hostname(v.Mx))
+	case *dns.TXT:
+		return strings.Join(v.Txt, "")
+	case *dns.SRV:
+		return fmt.Sprintf("%d %d %d %s", v.Priority, v.Weight, v.Port, hostname(v.Target))
+	case *dns.SOA:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", hostname(v.Ns), hostname(v.Mbox),
+			v.Serial, v.Refresh, v.Retry, v.Expire, v.Minttl)
+	default:
+		return strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+}
+
+func hostname(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// matches compares an answer with an expected value, ignoring case and a
+// trailing dot, comparing addresses by value and allowing MX and SRV answers
+// to be matched by their target
+func matches(answer, expected string) bool {
+	expected = strings.TrimSuffix(strings.TrimSpace(expected), ".")
+	if strings.EqualFold(answer, expected) {
+		return true
+	}
+	if a, e := net.ParseIP(answer), net.ParseIP(expected); a != nil && e != nil {
+		return a.Equal(e)
+	}
+	fields := strings.Fields(answer)
+	return len(fields) > 1 && strings.EqualFold(fields[len(fields)-1], expected)
+}
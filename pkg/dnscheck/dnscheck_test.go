@@ -0,0 +1,276 @@
+package dnscheck
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testZone is served by startServer. truncated.example.test. is only
+// answered over TCP; over UDP the reply is truncated.
+var testZone = []string{
+	"example.test. 300 IN A 192.0.2.10",
+	"example.test. 300 IN A 192.0.2.11",
+	"example.test. 300 IN AAAA 2001:db8::1",
+	"www.example.test. 300 IN CNAME example.test.",
+	"example.test. 300 IN MX 10 mail.example.test.",
+	`example.test. 300 IN TXT "v=spf1 " "-all"`,
+	"example.test. 300 IN NS ns1.example.test.",
+	"example.test. 300 IN SOA ns1.example.test. hostmaster.example.test. 2024010101 7200 3600 1209600 300",
+	"_sip._tcp.example.test. 300 IN SRV 10 60 5060 sip.example.test.",
+	"truncated.example.test. 300 IN A 192.0.2.99",
+}
+
+// startServer serves testZone over UDP and TCP on the same local port and
+// returns its address
+func startServer(t *testing.T) string {
+	t.Helper()
+
+	var records []dns.RR
+	for _, line := range testZone {
+		rr, err := dns.NewRR(line)
+		if err != nil {
+			t.Fatalf("invalid test record %q: %v", line, err)
+		}
+		records = append(records, rr)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+
+		if q.Name == "truncated.example.test." && w.RemoteAddr().Network() == "udp" {
+			resp.Truncated = true
+			_ = w.WriteMsg(resp)
+			return
+		}
+
+		known := false
+		for _, rr := range records {
+			if !strings.EqualFold(rr.Header().Name, q.Name) {
+				continue
+			}
+			known = true
+			if rr.Header().Rrtype == q.Qtype {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+		if !known {
+			resp.Rcode = dns.RcodeNameError
+		}
+		_ = w.WriteMsg(resp)
+	})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen on udp: %v", err)
+	}
+	listener, err := net.Listen("tcp", conn.LocalAddr().String())
+	if err != nil {
+		conn.Close()
+		t.Fatalf("failed to listen on tcp: %v", err)
+	}
+
+	for _, server := range []*dns.Server{
+		{PacketConn: conn, Handler: handler},
+		{Listener: listener, Handler: handler},
+	} {
+		started := make(chan struct{})
+		server.NotifyStartedFunc = func() { close(started) }
+		go func() { _ = server.ActivateAndServe() }()
+		<-started
+		t.Cleanup(func() { _ = server.Shutdown() })
+	}
+
+	return conn.LocalAddr().String()
+}
+
+func TestQuery(t *testing.T) {
+	resolver := &Resolver{Nameserver: startServer(t)}
+
+	tests := []struct {
+		name       string
+		host       string
+		recordType string
+		expected   []string
+		answers    []string
+		rcode      string
+		wantErr    string
+	}{
+		{
+			name:    "A by default",
+			host:    "example.test",
+			answers: []string{"192.0.2.10", "192.0.2.11"},
+			rcode:   "NOERROR",
+		},
+		{
+			name:       "A with expected values",
+			host:       "example.test",
+			recordType: "A",
+			expected:   []string{"192.0.2.11", "192.0.2.10"},
+			answers:    []string{"192.0.2.10", "192.0.2.11"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "AAAA compared by value",
+			host:       "example.test",
+			recordType: "AAAA",
+			expected:   []string{"2001:0db8::0001"},
+			answers:    []string{"2001:db8::1"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "CNAME with trailing dot",
+			host:       "www.example.test",
+			recordType: "CNAME",
+			expected:   []string{"Example.Test."},
+			answers:    []string{"example.test"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "MX by target alone",
+			host:       "example.test",
+			recordType: "mx",
+			expected:   []string{"mail.example.test"},
+			answers:    []string{"10 mail.example.test"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "TXT strings joined",
+			host:       "example.test",
+			recordType: "TXT",
+			expected:   []string{"v=spf1 -all"},
+			answers:    []string{"v=spf1 -all"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "NS",
+			host:       "example.test",
+			recordType: "NS",
+			expected:   []string{"ns1.example.test"},
+			answers:    []string{"ns1.example.test"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "SOA",
+			host:       "example.test",
+			recordType: "SOA",
+			answers:    []string{"ns1.example.test hostmaster.example.test 2024010101 7200 3600 1209600 300"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "SRV",
+			host:       "_sip._tcp.example.test",
+			recordType: "SRV",
+			expected:   []string{"10 60 5060 sip.example.test"},
+			answers:    []string{"10 60 5060 sip.example.test"},
+			rcode:      "NOERROR",
+		},
+		{
+			name:       "expected value missing",
+			host:       "example.test",
+			recordType: "A",
+			expected:   []string{"192.0.2.10", "192.0.2.12"},
+			answers:    []string{"192.0.2.10", "192.0.2.11"},
+			rcode:      "NOERROR",
+			wantErr:    `expected A record "192.0.2.12" not found`,
+		},
+		{
+			name:       "no records of the type",
+			host:       "www.example.test",
+			recordType: "A",
+			rcode:      "NOERROR",
+			wantErr:    "no A records for www.example.test",
+		},
+		{
+			name:    "NXDOMAIN",
+			host:    "missing.example.test",
+			rcode:   "NXDOMAIN",
+			wantErr: "answered NXDOMAIN",
+		},
+		{
+			name:     "truncated answer retried over TCP",
+			host:     "truncated.example.test",
+			expected: []string{"192.0.2.99"},
+			answers:  []string{"192.0.2.99"},
+			rcode:    "NOERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			config := &Config{RecordType: tt.recordType, ExpectedValues: tt.expected}
+			result, err := resolver.Query(ctx, tt.host, config)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if result.Rcode != tt.rcode {
+				t.Errorf("Rcode = %s, want %s", result.Rcode, tt.rcode)
+			}
+			if !slices.Equal(result.Answers, tt.answers) {
+				t.Errorf("Answers = %q, want %q", result.Answers, tt.answers)
+			}
+
+			err = config.Check(tt.host, result)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Check() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryNameserverOverride(t *testing.T) {
+	addr := startServer(t)
+	// The default nameserver is not listening; the check's own is used
+	resolver := &Resolver{Nameserver: "127.0.0.1:1"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := resolver.Query(ctx, "example.test", &Config{Nameserver: addr})
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if result.Nameserver != addr {
+		t.Errorf("Nameserver = %s, want %s", result.Nameserver, addr)
+	}
+}
+
+func TestNameserverAddress(t *testing.T) {
+	tests := []struct {
+		nameserver string
+		want       string
+		wantErr    bool
+	}{
+		{nameserver: "1.1.1.1", want: "1.1.1.1:53"},
+		{nameserver: "1.1.1.1:5353", want: "1.1.1.1:5353"},
+		{nameserver: "ns1.example.com", want: "ns1.example.com:53"},
+		{nameserver: "2001:db8::53", want: "[2001:db8::53]:53"},
+		{nameserver: "[2001:db8::53]", want: "[2001:db8::53]:53"},
+		{nameserver: "[2001:db8::53]:5353", want: "[2001:db8::53]:5353"},
+		{nameserver: "", wantErr: true},
+		{nameserver: "bad host", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NameserverAddress(tt.nameserver)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NameserverAddress(%q) error = %v, wantErr %v", tt.nameserver, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NameserverAddress(%q) = %q, want %q", tt.nameserver, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RootTrustAnchor is the DS record of the root zone's key signing key,
+// KSK-2017, which validation starts from when a check names no anchor
+const RootTrustAnchor = ". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"
+
+// DNSSEC validation outcomes
+const (
+	// DNSSECSecure means every link from the trust anchor to the records
+	// verified
+	DNSSECSecure = "secure"
+	// DNSSECInsecure means a delegation on the way has no DS records
+	DNSSECInsecure = "insecure"
+	// DNSSECBogus means a signature or DS record did not verify
+	DNSSECBogus = "bogus"
+	// DNSSECIndeterminate means the records needed could not be fetched
+	DNSSECIndeterminate = "indeterminate"
+)
+
+// Keys of the check result details of a DNSSEC check. Each validated link is
+// reported as dnssec_chain_1, dnssec_chain_2 and so on.
+const (
+	DetailDNSSECStatus = "dnssec_status"
+	DetailDNSSECError  = "dnssec_error"
+	// DetailSignatureExpiry is when the first of the signatures validated
+	// expires, in RFC 3339
+	DetailSignatureExpiry        = "dnssec_signature_expiry"
+	DetailSignatureDaysRemaining = "dnssec_signature_days_remaining"
+	// DetailSignatureExpiring names the record set that signature covers
+	DetailSignatureExpiring = "dnssec_signature_expiring"
+)
+
+// ParseTrustAnchor reads a trust anchor written as a DS record
+func ParseTrustAnchor(anchor string) (*dns.DS, error) {
+	rr, err := dns.NewRR(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust anchor: %w", err)
+	}
+	ds, ok := rr.(*dns.DS)
+	if !ok {
+		return nil, errors.New("trust anchor must be a DS record")
+	}
+	return ds, nil
+}
+
+// DNSSECResult is the outcome of validating a check's records
+type DNSSECResult struct {
+	Status string
+	// Chain describes each link validated, from the trust anchor down
+	Chain []string
+	// Expiry is when the first of the validated signatures expires, and
+	// Expiring the record set it covers
+	Expiry   time.Time
+	Expiring string
+	// Err says why the records are not secure
+	Err error
+}
+
+// Details returns the check result details describing the validation
+func (r *DNSSECResult) Details(now time.Time) map[string]string {
+	details := map[string]string{DetailDNSSECStatus: r.Status}
+	if r.Err != nil {
+		details[DetailDNSSECError] = r.Err.Error()
+	}
+	for i, link := range r.Chain {
+		details[fmt.Sprintf("dnssec_chain_%d", i+1)] = link
+	}
+	if !r.Expiry.IsZero() {
+		details[DetailSignatureExpiry] = r.Expiry.UTC().Format(time.RFC3339)
+		details[DetailSignatureDaysRemaining] = strconv.Itoa(int(r.Expiry.Sub(now).Hours() / 24))
+		details[DetailSignatureExpiring] = r.Expiring
+	}
+	return details
+}
+
+// SignatureExpiry reads when the first DNSSEC signature expires from check
+// result details
+func SignatureExpiry(details map[string]string) (time.Time, bool) {
+	expiry, err := time.Parse(time.RFC3339, details[DetailSignatureExpiry])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiry, true
+}
+
+// validator walks the chain of trust for one check
+type validator struct {
+	ctx        context.Context
+	nameserver string
+	now        time.Time
+	result     *DNSSECResult
+}
+
+// ValidateDNSSEC validates the check's records at name from the trust
+// anchor down: each zone's DNSKEY set must be signed by a key its parent's
+// DS records vouch for, and the records by one of the zone's keys. The
+// nameserver is asked with checking disabled, so that records a validating
+// resolver would refuse can still be examined.
+func (r *Resolver) ValidateDNSSEC(ctx context.Context, name string, config *Config, now time.Time) *DNSSECResult {
+	result := &DNSSECResult{Status: DNSSECIndeterminate}
+	fail := func(status string, err error) *DNSSECResult {
+		result.Status = status
+		result.Err = err
+		return result
+	}
+
+	nameserver, err := r.nameserver(config)
+	if err != nil {
+		return fail(DNSSECIndeterminate, err)
+	}
+	qtype, err := recordType(config)
+	if err != nil {
+		return fail(DNSSECIndeterminate, err)
+	}
+	anchorText := RootTrustAnchor
+	if config != nil && config.TrustAnchor != "" {
+		anchorText = config.TrustAnchor
+	}
+	anchor, err := ParseTrustAnchor(anchorText)
+	if err != nil {
+		return fail(DNSSECIndeterminate, err)
+	}
+
+	v := &validator{ctx: ctx, nameserver: nameserver, now: now, result: result}
+	zones, err := v.zoneCuts(anchor.Hdr.Name, name)
+	if err != nil {
+		return fail(DNSSECIndeterminate, err)
+	}
+
+	trusted := []*dns.DS{anchor}
+	var keys []*dns.DNSKEY
+	for i, zone := range zones {
+		if i > 0 {
+			records, sigs, err := v.fetch(zone, dns.TypeDS)
+			if err != nil {
+				return fail(DNSSECIndeterminate, err)
+			}
+			if len(records) == 0 {
+				return fail(DNSSECInsecure, fmt.Errorf("%s has no DS records, so its delegation is unsigned", zone))
+			}
+			if err := v.verify(zone+" DS", records, sigs, keys); err != nil {
+				return fail(DNSSECBogus, err)
+			}
+			trusted = trusted[:0]
+			for _, rr := range records {
+				trusted = append(trusted, rr.(*dns.DS))
+			}
+		}
+
+		records, sigs, err := v.fetch(zone, dns.TypeDNSKEY)
+		if err != nil {
+			return fail(DNSSECIndeterminate, err)
+		}
+		zoneKeys := make([]*dns.DNSKEY, 0, len(records))
+		for _, rr := range records {
+			zoneKeys = append(zoneKeys, rr.(*dns.DNSKEY))
+		}
+		entryKeys := matchDS(zoneKeys, trusted)
+		if len(entryKeys) == 0 {
+			return fail(DNSSECBogus, fmt.Errorf("no DNSKEY of %s matches its DS records", zone))
+		}
+		if err := v.verify(zone+" DNSKEY", records, sigs, entryKeys); err != nil {
+			return fail(DNSSECBogus, err)
+		}
+		keys = zoneKeys
+	}
+
+	records, sigs, err := v.fetch(name, qtype)
+	if err != nil {
+		return fail(DNSSECIndeterminate, err)
+	}
+	if len(records) == 0 {
+		return fail(DNSSECIndeterminate, fmt.Errorf("no %s records for %s to validate", dns.TypeToString[qtype], name))
+	}
+	if err := v.verify(dns.Fqdn(name)+" "+dns.TypeToString[qtype], records, sigs, keys); err != nil {
+		return fail(DNSSECBogus, err)
+	}
+
+	result.Status = DNSSECSecure
+	return result
+}
+
+// zoneCuts lists the zones from the anchor's down to the one name is in
+func (v *validator) zoneCuts(anchor, name string) ([]string, error) {
+	zone, err := findZone(v.ctx, name, v.nameserver)
+	if err != nil {
+		return nil, err
+	}
+	if !dns.IsSubDomain(anchor, zone) {
+		return nil, fmt.Errorf("%s is not under the trust anchor %s", zone, anchor)
+	}
+
+	zones := []string{anchor}
+	labels := dns.SplitDomainName(zone)
+	for n := dns.CountLabel(anchor) + 1; n < len(labels); n++ {
+		candidate := dns.Fqdn(strings.Join(labels[len(labels)-n:], "."))
+		resp, _, err := exchange(v.ctx, question(candidate, dns.TypeSOA, true), v.nameserver)
+		if err != nil {
+			return nil, err
+		}
+		for _, rr := range resp.Answer {
+			if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(soa.Hdr.Name, candidate) {
+				zones = append(zones, candidate)
+				break
+			}
+		}
+	}
+	if !strings.EqualFold(zone, anchor) {
+		zones = append(zones, zone)
+	}
+	return zones, nil
+}
+
+// fetch returns the records of type qtype at name and their signatures
+func (v *validator) fetch(name string, qtype uint16) ([]dns.RR, []*dns.RRSIG, error) {
+	msg := question(name, qtype, true)
+	msg.SetEdns0(4096, true)
+	msg.CheckingDisabled = true
+
+	resp, _, err := exchange(v.ctx, msg, v.nameserver)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return nil, nil, fmt.Errorf("%s answered %s for %s %s", v.nameserver,
+			dns.RcodeToString[resp.Rcode], name, dns.TypeToString[qtype])
+	}
+
+	owner := dns.Fqdn(name)
+	var records []dns.RR
+	var sigs []*dns.RRSIG
+	for _, rr := range resp.Answer {
+		if !strings.EqualFold(rr.Header().Name, owner) {
+			continue
+		}
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == qtype {
+			sigs = append(sigs, sig)
+		} else if rr.Header().Rrtype == qtype {
+			records = append(records, rr)
+		}
+	}
+	return records, sigs, nil
+}
+
+// verify checks that one of sigs over records was made by one of keys and is
+// current, and notes when it expires
+func (v *validator) verify(what string, records []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY) error {
+	if len(sigs) == 0 {
+		return fmt.Errorf("%s is not signed", what)
+	}
+
+	var failure error
+	for _, sig := range sigs {
+		for _, key := range keys {
+			if key.KeyTag() != sig.KeyTag || key.Algorithm != sig.Algorithm {
+				continue
+			}
+			if err := sig.Verify(key, records); err != nil {
+				failure = fmt.Errorf("signature on %s by key %d does not verify: %w", what, sig.KeyTag, err)
+				continue
+			}
+
+			expires := time.Unix(int64(sig.Expiration), 0)
+			if !sig.ValidityPeriod(v.now) {
+				if v.now.After(expires) {
+					failure = fmt.Errorf("signature on %s by key %d expired at %s", what, sig.KeyTag, expires.UTC().Format(time.RFC3339))
+				} else {
+					failure = fmt.Errorf("signature on %s by key %d is not valid until %s", what, sig.KeyTag,
+						time.Unix(int64(sig.Inception), 0).UTC().Format(time.RFC3339))
+				}
+				continue
+			}
+
+			v.result.Chain = append(v.result.Chain, fmt.Sprintf("%s signed by key %d until %s",
+				what, sig.KeyTag, expires.UTC().Format(time.RFC3339)))
+			if v.result.Expiry.IsZero() || expires.Before(v.result.Expiry) {
+				v.result.Expiry = expires
+				v.result.Expiring = what
+			}
+			return nil
+		}
+	}
+
+	if failure == nil {
+		failure = fmt.Errorf("%s is not signed by a trusted key", what)
+	}
+	return failure
+}
+
+// matchDS returns the keys that one of the DS records vouches for
+func matchDS(keys []*dns.DNSKEY, trusted []*dns.DS) []*dns.DNSKEY {
+	var matched []*dns.DNSKEY
+	for _, key := range keys {
+		for _, ds := range trusted {
+			if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+				continue
+			}
+			if digest := key.ToDS(ds.DigestType); digest != nil && strings.EqualFold(digest.Digest, ds.Digest) {
+				matched = append(matched, key)
+				break
+			}
+		}
+	}
+	return matched
+}
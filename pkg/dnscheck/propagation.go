@@ -0,0 +1,272 @@
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// Keys of the check result details of a propagation check. Each server's
+// answer is reported as propagation_1, propagation_2 and so on.
+const (
+	DetailPropagationZone        = "propagation_zone"
+	DetailPropagationServers     = "propagation_servers"
+	DetailPropagationDisagreeing = "propagation_disagreeing"
+	DetailSOASerials             = "soa_serials"
+	DetailSOASerialMismatch      = "soa_serial_mismatch"
+)
+
+// ServerAnswer is what one server answered in a propagation check
+type ServerAnswer struct {
+	// Name is the nameserver's host name, or the resolver as configured
+	Name    string
+	Address string
+	// Authoritative is set for the zone's own nameservers, which are asked
+	// without recursion
+	Authoritative bool
+	Answers       []string
+	// Serial is the zone's SOA serial on an authoritative server
+	Serial uint32
+	Err    error
+}
+
+// key identifies an answer regardless of the order of its records
+func (a ServerAnswer) key() string {
+	sorted := slices.Clone(a.Answers)
+	slices.Sort(sorted)
+	return strings.Join(sorted, ", ")
+}
+
+func (a ServerAnswer) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s): ", a.Name, a.Address)
+	switch {
+	case a.Err != nil:
+		b.WriteString("error: " + a.Err.Error())
+	case len(a.Answers) == 0:
+		b.WriteString("no records")
+	default:
+		b.WriteString(a.key())
+	}
+	if a.Serial != 0 {
+		fmt.Fprintf(&b, "; serial %d", a.Serial)
+	}
+	return b.String()
+}
+
+// PropagationResult compares the answers of every server in a propagation
+// check
+type PropagationResult struct {
+	Zone    string
+	Servers []ServerAnswer
+	// Consensus is the answer most servers gave
+	Consensus string
+	// Disagreeing names the servers that gave another answer or none
+	Disagreeing []string
+	// Serials lists the distinct SOA serials of the authoritative servers
+	Serials []uint32
+}
+
+// Propagation asks the zone's authoritative nameservers and the configured
+// resolvers for the check's records at name, all at once, and compares what
+// they say. A server that fails to answer counts as disagreeing.
+func (r *Resolver) Propagation(ctx context.Context, name string, config *Config) (*PropagationResult, error) {
+	nameserver, err := r.nameserver(config)
+	if err != nil {
+		return nil, err
+	}
+	qtype, err := recordType(config)
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := findZone(ctx, name, nameserver)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := authoritativeServers(ctx, zone, nameserver)
+	if err != nil {
+		return nil, err
+	}
+	for _, resolver := range config.Resolvers {
+		address, err := NameserverAddress(resolver)
+		servers = append(servers, ServerAnswer{Name: resolver, Address: address, Err: err})
+	}
+
+	var wg sync.WaitGroup
+	for i := range servers {
+		if servers[i].Err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(server *ServerAnswer) {
+			defer wg.Done()
+			askServer(ctx, server, name, qtype, zone)
+		}(&servers[i])
+	}
+	wg.Wait()
+
+	return compare(zone, servers), nil
+}
+
+// askServer fills in a server's answer and, for authoritative servers, its
+// SOA serial
+func askServer(ctx context.Context, server *ServerAnswer, name string, qtype uint16, zone string) {
+	resp, _, err := exchange(ctx, question(name, qtype, !server.Authoritative), server.Address)
+	if err != nil {
+		server.Err = err
+		return
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		server.Err = errors.New(dns.RcodeToString[resp.Rcode])
+		return
+	}
+	server.Answers = answers(resp, qtype)
+
+	if !server.Authoritative {
+		return
+	}
+	resp, _, err = exchange(ctx, question(zone, dns.TypeSOA, false), server.Address)
+	if err != nil {
+		server.Err = fmt.Errorf("SOA %w", err)
+		return
+	}
+	for _, rr := range resp.Answer {
+		if soa, ok := rr.(*dns.SOA); ok {
+			server.Serial = soa.Serial
+		}
+	}
+}
+
+// compare finds the answer most servers agree on and the servers that differ
+func compare(zone string, servers []ServerAnswer) *PropagationResult {
+	result := &PropagationResult{Zone: zone, Servers: servers}
+
+	counts := make(map[string]int)
+	best := 0
+	for _, server := range servers {
+		if server.Err != nil {
+			continue
+		}
+		key := server.key()
+		counts[key]++
+		// Ties go to the answer seen first, authoritative servers coming
+		// first
+		if counts[key] > best {
+			best = counts[key]
+			result.Consensus = key
+		}
+	}
+
+	for _, server := range servers {
+		if server.Err != nil || server.key() != result.Consensus {
+			result.Disagreeing = append(result.Disagreeing, server.Name)
+		}
+		if server.Authoritative && server.Serial != 0 && !slices.Contains(result.Serials, server.Serial) {
+			result.Serials = append(result.Serials, server.Serial)
+		}
+	}
+	slices.Sort(result.Serials)
+	return result
+}
+
+// Details returns the check result details describing every server's answer
+func (p *PropagationResult) Details() map[string]string {
+	details := map[string]string{
+		DetailPropagationZone:    p.Zone,
+		DetailPropagationServers: strconv.Itoa(len(p.Servers)),
+	}
+	for i, server := range p.Servers {
+		details[fmt.Sprintf("propagation_%d", i+1)] = server.String()
+	}
+	if len(p.Disagreeing) > 0 {
+		details[DetailPropagationDisagreeing] = strings.Join(p.Disagreeing, ",")
+	}
+
+	serials := make([]string, len(p.Serials))
+	for i, serial := range p.Serials {
+		serials[i] = strconv.FormatUint(uint64(serial), 10)
+	}
+	details[DetailSOASerials] = strings.Join(serials, ",")
+	details[DetailSOASerialMismatch] = strconv.FormatBool(len(p.Serials) > 1)
+	return details
+}
+
+// Check fails if any server disagreed or the authoritative servers serve
+// different versions of the zone
+func (p *PropagationResult) Check() error {
+	var problems []string
+	if len(p.Disagreeing) > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d servers disagree: %s",
+			len(p.Disagreeing), len(p.Servers), strings.Join(p.Disagreeing, ", ")))
+	}
+	if len(p.Serials) > 1 {
+		problems = append(problems, fmt.Sprintf("authoritative servers serve SOA serials %s",
+			strings.Trim(fmt.Sprint(p.Serials), "[]")))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// findZone returns the zone name belongs to, from the owner of the SOA
+// record the nameserver returns for it
+func findZone(ctx context.Context, name, nameserver string) (string, error) {
+	resp, _, err := exchange(ctx, question(name, dns.TypeSOA, true), nameserver)
+	if err != nil {
+		return "", err
+	}
+	// The SOA is an answer at the zone apex and in the authority section
+	// below it
+	for _, rr := range append(resp.Answer, resp.Ns...) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa.Hdr.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no zone found for %s", name)
+}
+
+// authoritativeServers looks up the zone's nameservers and their addresses
+func authoritativeServers(ctx context.Context, zone, nameserver string) ([]ServerAnswer, error) {
+	resp, _, err := exchange(ctx, question(zone, dns.TypeNS, true), nameserver)
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []ServerAnswer
+	for _, rr := range resp.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		server := ServerAnswer{Name: hostname(ns.Ns), Authoritative: true}
+		server.Address, server.Err = nameserverAddress(ctx, ns.Ns, nameserver)
+		servers = append(servers, server)
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no nameservers found for %s", zone)
+	}
+	slices.SortFunc(servers, func(a, b ServerAnswer) int { return strings.Compare(a.Name, b.Name) })
+	return servers, nil
+}
+
+// nameserverAddress resolves a nameserver's host name, preferring IPv4
+func nameserverAddress(ctx context.Context, host, nameserver string) (string, error) {
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		resp, _, err := exchange(ctx, question(host, qtype, true), nameserver)
+		if err != nil {
+			return "", err
+		}
+		if addresses := answers(resp, qtype); len(addresses) > 0 {
+			return NameserverAddress(addresses[0])
+		}
+	}
+	return "", fmt.Errorf("no address for %s", hostname(host))
+}
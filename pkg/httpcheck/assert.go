@@ -0,0 +1,179 @@
+package httpcheck
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Content match modes, as in monitor_http_extension.content_match_mode
+const (
+	MatchContains    = "contains"
+	MatchNotContains = "not_contains"
+	MatchRegex       = "regex"
+	MatchNotRegex    = "not_regex"
+)
+
+// MatchModes lists the content match modes a check may use
+var MatchModes = []string{MatchContains, MatchNotContains, MatchRegex, MatchNotRegex}
+
+const (
+	// MaxBodyBytes is how much of a response body is read for assertions.
+	// Content past it is not seen, so a not_contains check can pass on a body
+	// that holds the text further down.
+	MaxBodyBytes = 1 << 20
+	// ExcerptBytes bounds the body excerpt reported with a failed assertion
+	ExcerptBytes = 256
+)
+
+// Keys of the check result details that describe a failed assertion
+const (
+	DetailAssertion     = "assertion"
+	DetailAssertionMode = "assertion_mode"
+	DetailAssertionPath = "assertion_path"
+	DetailBodyExcerpt   = "body_excerpt"
+	DetailBodyTruncated = "body_truncated"
+)
+
+// AssertionBody names the response body assertion in DetailAssertion
+const AssertionBody = "body"
+
+// AssertionError reports an assertion on the response that did not hold
+type AssertionError struct {
+	// Assertion names what was checked, e.g. AssertionBody
+	Assertion string
+	// Mode is how it was checked, e.g. MatchRegex or OpEquals
+	Mode string
+	// Path is the JSON path or header an assertion looked at
+	Path string
+	// Excerpt is the part of the body the failure was found in, if any
+	Excerpt string
+	Message string
+}
+
+func (e *AssertionError) Error() string {
+	return e.Message
+}
+
+// Details returns the result details that describe the failure
+func (e *AssertionError) Details() map[string]string {
+	details := map[string]string{
+		DetailAssertion:     e.Assertion,
+		DetailAssertionMode: e.Mode,
+	}
+	if e.Path != "" {
+		details[DetailAssertionPath] = e.Path
+	}
+	if e.Excerpt != "" {
+		details[DetailBodyExcerpt] = e.Excerpt
+	}
+	return details
+}
+
+// ChecksBody reports whether the response body has to be read
+func (c *Config) ChecksBody() bool {
+	if c == nil {
+		return false
+	}
+	return c.ContentMatch != "" || slices.ContainsFunc(c.Assertions, func(a Assertion) bool {
+		return a.Source == SourceJSON
+	})
+}
+
+// MatchMode returns the content match mode, contains by default
+func (c *Config) MatchMode() string {
+	if c == nil || c.ContentMatchMode == "" {
+		return MatchContains
+	}
+	return c.ContentMatchMode
+}
+
+// ValidateContentMatch checks that a content match can be evaluated
+func ValidateContentMatch(match, mode string) error {
+	if mode == "" {
+		mode = MatchContains
+	}
+	if !slices.Contains(MatchModes, mode) {
+		return fmt.Errorf("content match mode must be one of %s", strings.Join(MatchModes, ", "))
+	}
+	if mode == MatchRegex || mode == MatchNotRegex {
+		if _, err := regexp.Compile(match); err != nil {
+			return fmt.Errorf("invalid content match pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// CheckBody evaluates the content match, if any, against body, which may have
+// been cut off at MaxBodyBytes. It returns an *AssertionError if the match
+// fails.
+func (c *Config) CheckBody(body []byte) error {
+	if c == nil || c.ContentMatch == "" {
+		return nil
+	}
+
+	mode := c.MatchMode()
+	fail := func(at int, format string, args ...any) error {
+		return &AssertionError{
+			Assertion: AssertionBody,
+			Mode:      mode,
+			Excerpt:   Excerpt(body, at),
+			Message:   fmt.Sprintf(format, args...),
+		}
+	}
+
+	switch mode {
+	case MatchContains:
+		if !strings.Contains(string(body), c.ContentMatch) {
+			return fail(0, "response body does not contain %q", c.ContentMatch)
+		}
+	case MatchNotContains:
+		if at := strings.Index(string(body), c.ContentMatch); at >= 0 {
+			return fail(at, "response body contains %q", c.ContentMatch)
+		}
+	case MatchRegex, MatchNotRegex:
+		pattern, err := regexp.Compile(c.ContentMatch)
+		if err != nil {
+			return fail(0, "invalid content match pattern: %v", err)
+		}
+		match := pattern.FindIndex(body)
+		if mode == MatchRegex && match == nil {
+			return fail(0, "response body does not match %q", c.ContentMatch)
+		}
+		if mode == MatchNotRegex && match != nil {
+			return fail(match[0], "response body matches %q", c.ContentMatch)
+		}
+	default:
+		return fail(0, "unknown content match mode %q", mode)
+	}
+	return nil
+}
+
+// ReadBody reads the start of a response body for assertions, up to
+// MaxBodyBytes. It reports whether the body went on past the limit, in which
+// case one byte more than returned has been read from r.
+func ReadBody(r io.Reader) (body []byte, truncated bool, err error) {
+	// Read one byte past the limit to tell whether the body was cut off
+	body, err = io.ReadAll(io.LimitReader(r, MaxBodyBytes+1))
+	if len(body) > MaxBodyBytes {
+		return body[:MaxBodyBytes], true, err
+	}
+	return body, false, err
+}
+
+// Excerpt returns up to ExcerptBytes of body around offset at, cut on
+// character boundaries
+func Excerpt(body []byte, at int) string {
+	start := max(min(at-ExcerptBytes/4, len(body)-ExcerptBytes), 0)
+	end := min(start+ExcerptBytes, len(body))
+	for start < end && !utf8.RuneStart(body[start]) {
+		start++
+	}
+	for end > start && end < len(body) && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return strings.ToValidUTF8(string(body[start:end]), "")
+}
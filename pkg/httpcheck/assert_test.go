@@ -0,0 +1,210 @@
+package httpcheck
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidateContentMatch(t *testing.T) {
+	tests := []struct {
+		match   string
+		mode    string
+		wantErr bool
+	}{
+		{"ok", "", false},
+		{"ok", MatchNotContains, false},
+		{"[", MatchContains, false},
+		{`^\{"status":"(ok|degraded)"`, MatchRegex, false},
+		{"[", MatchRegex, true},
+		{"(", MatchNotRegex, true},
+		{"ok", "glob", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateContentMatch(tt.match, tt.mode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateContentMatch(%q, %q) error = %v, wantErr %v", tt.match, tt.mode, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckBody(t *testing.T) {
+	body := []byte(`{"status":"ok","version":"1.2.3","message":"all systems operational"}`)
+
+	tests := []struct {
+		name    string
+		match   string
+		mode    string
+		wantErr string
+	}{
+		{"substring found", `"status":"ok"`, "", ""},
+		{"substring missing", "degraded", MatchContains, `response body does not contain "degraded"`},
+		// Substring matching takes the text literally
+		{"substring is not a pattern", `version":"1.2.\d`, MatchContains, `response body does not contain "version\":\"1.2.\\d"`},
+		{"negated substring absent", "error", MatchNotContains, ""},
+		{"negated substring present", "operational", MatchNotContains, `response body contains "operational"`},
+		{"pattern matches", `"version":"1\.\d+\.\d+"`, MatchRegex, ""},
+		{"pattern does not match", `"status":"(down|degraded)"`, MatchRegex, `response body does not match "\"status\":\"(down|degraded)\""`},
+		{"negated pattern does not match", `"status":"(down|degraded)"`, MatchNotRegex, ""},
+		{"negated pattern matches", `systems? operational`, MatchNotRegex, `response body matches "systems? operational"`},
+		{"invalid pattern", "(", MatchRegex, "invalid content match pattern: error parsing regexp: missing closing ): `(`"},
+		{"unknown mode", "ok", "glob", `unknown content match mode "glob"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{ContentMatch: tt.match, ContentMatchMode: tt.mode}
+			err := config.CheckBody(body)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckBody() error = %v", err)
+				}
+				return
+			}
+
+			var assertion *AssertionError
+			if !errors.As(err, &assertion) {
+				t.Fatalf("CheckBody() error = %v, want *AssertionError", err)
+			}
+			if assertion.Message != tt.wantErr {
+				t.Errorf("Message = %q, want %q", assertion.Message, tt.wantErr)
+			}
+			if assertion.Assertion != AssertionBody || assertion.Mode != config.MatchMode() {
+				t.Errorf("AssertionError = %s/%s, want %s/%s", assertion.Assertion, assertion.Mode, AssertionBody, config.MatchMode())
+			}
+			if assertion.Excerpt == "" {
+				t.Error("Excerpt is empty")
+			}
+		})
+	}
+
+	var config *Config
+	if err := config.CheckBody(body); err != nil {
+		t.Errorf("CheckBody() without a content match error = %v", err)
+	}
+}
+
+func TestCheckBodyExcerpt(t *testing.T) {
+	// The match sits well past the first ExcerptBytes of the body
+	padding := strings.Repeat("x", 4*ExcerptBytes)
+	body := []byte(padding + "FATAL: database unavailable" + padding)
+
+	for _, mode := range []string{MatchNotContains, MatchNotRegex} {
+		config := &Config{ContentMatch: "FATAL", ContentMatchMode: mode}
+		var assertion *AssertionError
+		if !errors.As(config.CheckBody(body), &assertion) {
+			t.Fatalf("%s: CheckBody() did not fail", mode)
+		}
+		if !strings.Contains(assertion.Excerpt, "FATAL: database unavailable") {
+			t.Errorf("%s: excerpt %q does not show the match", mode, assertion.Excerpt)
+		}
+		if len(assertion.Excerpt) != ExcerptBytes {
+			t.Errorf("%s: excerpt is %d bytes, want %d", mode, len(assertion.Excerpt), ExcerptBytes)
+		}
+		if details := assertion.Details(); details[DetailBodyExcerpt] != assertion.Excerpt {
+			t.Errorf("%s: Details() excerpt = %q", mode, details[DetailBodyExcerpt])
+		}
+	}
+
+	// A missing match shows the start of the body
+	config := &Config{ContentMatch: "OK"}
+	var assertion *AssertionError
+	if !errors.As(config.CheckBody(body), &assertion) {
+		t.Fatal("CheckBody() did not fail")
+	}
+	if assertion.Excerpt != padding[:ExcerptBytes] {
+		t.Errorf("excerpt %q is not the start of the body", assertion.Excerpt)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	body := []byte(strings.Repeat("a", 1000))
+
+	tests := []struct {
+		name      string
+		body      []byte
+		at        int
+		wantStart int
+		wantLen   int
+	}{
+		{"short body", []byte("hello"), 3, 0, 5},
+		{"start of body", body, 0, 0, ExcerptBytes},
+		{"some context before the offset", body, 500, 500 - ExcerptBytes/4, ExcerptBytes},
+		{"end of body", body, 990, 1000 - ExcerptBytes, ExcerptBytes},
+		{"empty body", nil, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Excerpt(tt.body, tt.at)
+			want := string(tt.body[tt.wantStart : tt.wantStart+tt.wantLen])
+			if got != want {
+				t.Errorf("Excerpt(%d) = %q, want %q", tt.at, got, want)
+			}
+		})
+	}
+
+	// Multi-byte characters are never split
+	wide := []byte(strings.Repeat("é", 500))
+	for _, at := range []int{0, 1, 101, 333, 999} {
+		got := Excerpt(wide, at)
+		if !utf8.ValidString(got) || len(got) > ExcerptBytes || len(got) < ExcerptBytes-2 {
+			t.Errorf("Excerpt(%d) of a two-byte text = %d bytes, valid %v", at, len(got), utf8.ValidString(got))
+		}
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		size          int
+		wantLen       int
+		wantTruncated bool
+	}{
+		{"empty", 0, 0, false},
+		{"small", 100, 100, false},
+		{"exactly the limit", MaxBodyBytes, MaxBodyBytes, false},
+		{"one byte over", MaxBodyBytes + 1, MaxBodyBytes, true},
+		{"far over", 3 * MaxBodyBytes, MaxBodyBytes, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(bytes.Repeat([]byte("a"), tt.size))
+			body, truncated, err := ReadBody(r)
+			if err != nil {
+				t.Fatalf("ReadBody() error = %v", err)
+			}
+			if len(body) != tt.wantLen || truncated != tt.wantTruncated {
+				t.Errorf("ReadBody() = %d bytes, truncated %v, want %d bytes, truncated %v",
+					len(body), truncated, tt.wantLen, tt.wantTruncated)
+			}
+			// The limit and the byte past it are read, the rest left for
+			// the caller to size the response with
+			if read := tt.size - r.Len(); read != min(tt.size, MaxBodyBytes+1) {
+				t.Errorf("ReadBody() consumed %d bytes", read)
+			}
+		})
+	}
+}
+
+func TestCheckBodyTruncated(t *testing.T) {
+	// Text past the limit is not seen by content matches
+	body := append(bytes.Repeat([]byte(" "), MaxBodyBytes), []byte("maintenance mode")...)
+	seen, truncated, err := ReadBody(bytes.NewReader(body))
+	if err != nil || !truncated {
+		t.Fatalf("ReadBody() = truncated %v, error %v, want a truncated body", truncated, err)
+	}
+
+	notContains := &Config{ContentMatch: "maintenance mode", ContentMatchMode: MatchNotContains}
+	if err := notContains.CheckBody(seen); err != nil {
+		t.Errorf("not_contains on a truncated body error = %v, want it to pass", err)
+	}
+	contains := &Config{ContentMatch: "maintenance mode"}
+	if err := contains.CheckBody(seen); err == nil {
+		t.Error("contains on a truncated body passed, want the text past the limit unseen")
+	}
+}
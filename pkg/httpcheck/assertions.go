@@ -0,0 +1,269 @@
+package httpcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Assertion sources
+const (
+	SourceJSON   = "json"
+	SourceHeader = "header"
+)
+
+// Assertion operators
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "not_equals"
+	OpContains    = "contains"
+	OpNotContains = "not_contains"
+	OpGreaterThan = "greater_than"
+	OpLessThan    = "less_than"
+	OpExists      = "exists"
+	OpNotExists   = "not_exists"
+	OpType        = "type"
+)
+
+// Operators lists the operators an assertion may use
+var Operators = []string{
+	OpEquals, OpNotEquals, OpContains, OpNotContains,
+	OpGreaterThan, OpLessThan, OpExists, OpNotExists, OpType,
+}
+
+// JSONTypes lists the values of a type assertion
+var JSONTypes = []string{"string", "number", "boolean", "object", "array", "null"}
+
+// AssertionResponseTime names the response time budget in DetailAssertion
+const AssertionResponseTime = "response_time"
+
+// Assertion compares part of the response with an expected value
+type Assertion struct {
+	// Source is SourceJSON for a value in the JSON body or SourceHeader for a
+	// response header
+	Source string `json:"source"`
+	// Path is a gjson-style path into the body, e.g. "data.items.0.status",
+	// or the header name
+	Path     string `json:"path"`
+	Operator string `json:"operator"`
+	// Value is what the selection is compared with: a number for
+	// greater_than and less_than and one of JSONTypes for type. It is unused
+	// by exists and not_exists.
+	Value string `json:"value,omitempty"`
+}
+
+// Validate checks that the assertion can be evaluated
+func (a Assertion) Validate() error {
+	if a.Source != SourceJSON && a.Source != SourceHeader {
+		return fmt.Errorf("assertion source must be %s or %s", SourceJSON, SourceHeader)
+	}
+	if a.Path == "" {
+		return errors.New("assertion path is required")
+	}
+	if !slices.Contains(Operators, a.Operator) {
+		return fmt.Errorf("assertion operator must be one of %s", strings.Join(Operators, ", "))
+	}
+
+	switch a.Operator {
+	case OpGreaterThan, OpLessThan:
+		if _, err := strconv.ParseFloat(a.Value, 64); err != nil {
+			return fmt.Errorf("%s needs a numeric value", a.Operator)
+		}
+	case OpType:
+		if a.Source != SourceJSON {
+			return errors.New("type assertions apply to JSON values only")
+		}
+		if !slices.Contains(JSONTypes, a.Value) {
+			return fmt.Errorf("type must be one of %s", strings.Join(JSONTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// String describes the assertion, e.g. `json status equals "ok"`
+func (a Assertion) String() string {
+	if a.Operator == OpExists || a.Operator == OpNotExists {
+		return fmt.Sprintf("%s %s %s", a.Source, a.Path, a.Operator)
+	}
+	return fmt.Sprintf("%s %s %s %q", a.Source, a.Path, a.Operator, a.Value)
+}
+
+// CheckAssertions evaluates every assertion against the response. It returns
+// the outcome of each, keyed assertion_1, assertion_2 and so on in the order
+// they are configured, and an *AssertionError for the first that failed.
+func (c *Config) CheckAssertions(header http.Header, body []byte) (map[string]string, error) {
+	if c == nil || len(c.Assertions) == 0 {
+		return nil, nil
+	}
+
+	// The body is only decoded if something looks into it
+	var document any
+	var documentErr error
+	if slices.ContainsFunc(c.Assertions, func(a Assertion) bool { return a.Source == SourceJSON }) {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			documentErr = errors.New("response body is not valid JSON")
+		}
+	}
+
+	outcomes := make(map[string]string, len(c.Assertions))
+	var first error
+	failed := 0
+	for i, a := range c.Assertions {
+		var err error
+		switch {
+		case a.Source == SourceHeader:
+			values := header.Values(a.Path)
+			err = a.evaluate(strings.Join(values, ", "), len(values) > 0)
+		case documentErr != nil:
+			err = documentErr
+		default:
+			value, found := lookup(document, a.Path)
+			err = a.evaluate(value, found)
+		}
+
+		key := fmt.Sprintf("assertion_%d", i+1)
+		if err == nil {
+			outcomes[key] = a.String() + ": passed"
+			continue
+		}
+		outcomes[key] = fmt.Sprintf("%s: failed, %v", a, err)
+		failed++
+		if first == nil {
+			first = &AssertionError{
+				Assertion: a.Source,
+				Mode:      a.Operator,
+				Path:      a.Path,
+				Message:   fmt.Sprintf("assertion %s failed: %v", a, err),
+			}
+		}
+	}
+
+	if failed > 1 {
+		assertion := first.(*AssertionError)
+		assertion.Message = fmt.Sprintf("%d of %d assertions failed, first %s", failed, len(c.Assertions), assertion.Message)
+	}
+	return outcomes, first
+}
+
+// CheckResponseTime compares how long the response took with the budget
+func (c *Config) CheckResponseTime(elapsed time.Duration) error {
+	if c == nil || c.MaxResponseTimeMs <= 0 || elapsed <= time.Duration(c.MaxResponseTimeMs)*time.Millisecond {
+		return nil
+	}
+	return &AssertionError{
+		Assertion: AssertionResponseTime,
+		Mode:      "max",
+		Message:   fmt.Sprintf("response took %dms, over the %dms budget", elapsed.Milliseconds(), c.MaxResponseTimeMs),
+	}
+}
+
+// evaluate compares a selected value with the assertion's. found says
+// whether the path selected anything.
+func (a Assertion) evaluate(value any, found bool) error {
+	switch a.Operator {
+	case OpExists:
+		if !found {
+			return errors.New("not found")
+		}
+		return nil
+	case OpNotExists:
+		if found {
+			return fmt.Errorf("found %s", describe(value))
+		}
+		return nil
+	}
+	if !found {
+		return errors.New("not found")
+	}
+
+	var ok bool
+	switch a.Operator {
+	case OpEquals:
+		ok = equal(value, a.Value)
+	case OpNotEquals:
+		ok = !equal(value, a.Value)
+	case OpContains:
+		ok = contains(value, a.Value)
+	case OpNotContains:
+		ok = !contains(value, a.Value)
+	case OpGreaterThan, OpLessThan:
+		actual, isNumber := number(value)
+		if !isNumber {
+			return fmt.Errorf("got %s, not a number", describe(value))
+		}
+		expected, _ := strconv.ParseFloat(a.Value, 64)
+		ok = actual > expected
+		if a.Operator == OpLessThan {
+			ok = actual < expected
+		}
+	case OpType:
+		if actual := jsonType(value); actual != a.Value {
+			return fmt.Errorf("got %s", actual)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operator %q", a.Operator)
+	}
+
+	if !ok {
+		return fmt.Errorf("got %s", describe(value))
+	}
+	return nil
+}
+
+// equal compares numbers by value and everything else by its text
+func equal(value any, expected string) bool {
+	if n, ok := value.(json.Number); ok {
+		actual, err := n.Float64()
+		want, wantErr := strconv.ParseFloat(expected, 64)
+		if err == nil && wantErr == nil {
+			return actual == want
+		}
+	}
+	return jsonString(value) == expected
+}
+
+// contains looks for an equal element in arrays and a substring in anything
+// else
+func contains(value any, expected string) bool {
+	if elements, ok := value.([]any); ok {
+		return slices.ContainsFunc(elements, func(element any) bool {
+			return equal(element, expected)
+		})
+	}
+	return strings.Contains(jsonString(value), expected)
+}
+
+// number reads a JSON number, or a string such as a header holding one
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// describe renders a value for a failure message, shortened to fit. Strings
+// are quoted so that they stand apart from numbers and other JSON.
+func describe(value any) string {
+	text := jsonString(value)
+	if len(text) > 100 {
+		text = strings.ToValidUTF8(text[:97], "") + "..."
+	}
+	if _, isString := value.(string); isString {
+		return strconv.Quote(text)
+	}
+	return text
+}
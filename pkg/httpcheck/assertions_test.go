@@ -0,0 +1,201 @@
+package httpcheck
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAssertionValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		assertion Assertion
+		wantErr   bool
+	}{
+		{"json equals", Assertion{Source: SourceJSON, Path: "status", Operator: OpEquals, Value: "ok"}, false},
+		{"header exists", Assertion{Source: SourceHeader, Path: "X-Request-Id", Operator: OpExists}, false},
+		{"numeric comparison", Assertion{Source: SourceJSON, Path: "count", Operator: OpGreaterThan, Value: "1.5"}, false},
+		{"type", Assertion{Source: SourceJSON, Path: "items", Operator: OpType, Value: "array"}, false},
+		{"unknown source", Assertion{Source: "body", Path: "status", Operator: OpEquals}, true},
+		{"no path", Assertion{Source: SourceJSON, Operator: OpExists}, true},
+		{"unknown operator", Assertion{Source: SourceJSON, Path: "status", Operator: "matches"}, true},
+		{"non-numeric comparison", Assertion{Source: SourceJSON, Path: "count", Operator: OpLessThan, Value: "ten"}, true},
+		{"unknown type", Assertion{Source: SourceJSON, Path: "items", Operator: OpType, Value: "list"}, true},
+		{"type of header", Assertion{Source: SourceHeader, Path: "Age", Operator: OpType, Value: "number"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.assertion.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertionEvaluate(t *testing.T) {
+	document := decode(t, `{
+		"status": "ok",
+		"code": "200",
+		"count": 10,
+		"price": 1.50,
+		"healthy": true,
+		"nothing": null,
+		"tags": ["a", "b", 3],
+		"data": {"items": [{"id": 7}]}
+	}`)
+
+	tests := []struct {
+		path     string
+		operator string
+		value    string
+		wantErr  string
+	}{
+		{"status", OpEquals, "ok", ""},
+		{"status", OpEquals, "OK", `got "ok"`},
+		{"status", OpNotEquals, "down", ""},
+		{"status", OpNotEquals, "ok", `got "ok"`},
+		// Numbers compare by value, strings by text
+		{"count", OpEquals, "10", ""},
+		{"count", OpEquals, "10.0", ""},
+		{"count", OpEquals, "1e1", ""},
+		{"price", OpEquals, "1.5", ""},
+		{"code", OpEquals, "200", ""},
+		{"code", OpEquals, "200.0", `got "200"`},
+		{"count", OpEquals, "ten", "got 10"},
+		// Other types compare by their JSON text
+		{"healthy", OpEquals, "true", ""},
+		{"nothing", OpEquals, "null", ""},
+		{"data.items.0", OpEquals, `{"id":7}`, ""},
+		// Arrays look for an element, everything else for a substring
+		{"tags", OpContains, "b", ""},
+		{"tags", OpContains, "3", ""},
+		{"tags", OpContains, "c", `got ["a","b",3]`},
+		{"tags", OpNotContains, "c", ""},
+		{"status", OpContains, "o", ""},
+		{"status", OpNotContains, "k", `got "ok"`},
+		{"data", OpContains, `"id":7`, ""},
+		// Ordering coerces numeric strings but nothing else
+		{"count", OpGreaterThan, "9.5", ""},
+		{"count", OpGreaterThan, "10", "got 10"},
+		{"count", OpLessThan, "11", ""},
+		{"code", OpGreaterThan, "199", ""},
+		{"status", OpGreaterThan, "1", `got "ok", not a number`},
+		{"healthy", OpLessThan, "1", "got true, not a number"},
+		{"data.items.0.id", OpLessThan, "8", ""},
+		// Types
+		{"count", OpType, "number", ""},
+		{"code", OpType, "string", ""},
+		{"code", OpType, "number", "got string"},
+		{"nothing", OpType, "null", ""},
+		{"tags", OpType, "array", ""},
+		{"data", OpType, "object", ""},
+		// Presence
+		{"nothing", OpExists, "", ""},
+		{"data.items.0.id", OpExists, "", ""},
+		{"data.items.1", OpExists, "", "not found"},
+		{"data.items.1", OpNotExists, "", ""},
+		{"status", OpNotExists, "", `found "ok"`},
+		// Everything else fails on a missing path
+		{"absent", OpEquals, "ok", "not found"},
+		{"absent", OpNotEquals, "ok", "not found"},
+		{"absent", OpNotContains, "ok", "not found"},
+		{"absent", OpType, "null", "not found"},
+	}
+
+	for _, tt := range tests {
+		a := Assertion{Source: SourceJSON, Path: tt.path, Operator: tt.operator, Value: tt.value}
+		value, found := lookup(document, tt.path)
+		err := a.evaluate(value, found)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: error = %v, want none", a, err)
+		case tt.wantErr != "" && err == nil:
+			t.Errorf("%s: no error, want %q", a, tt.wantErr)
+		case tt.wantErr != "" && err.Error() != tt.wantErr:
+			t.Errorf("%s: error = %q, want %q", a, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckAssertions(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Add("Cache-Control", "no-cache")
+	header.Add("Cache-Control", "no-store")
+	header.Set("Age", " 42 ")
+	body := []byte(`{"status": "ok", "items": [1, 2, 3]}`)
+
+	t.Run("all pass", func(t *testing.T) {
+		config := &Config{Assertions: []Assertion{
+			{Source: SourceJSON, Path: "status", Operator: OpEquals, Value: "ok"},
+			{Source: SourceJSON, Path: "items.#", Operator: OpEquals, Value: "3"},
+			{Source: SourceHeader, Path: "content-type", Operator: OpContains, Value: "json"},
+			{Source: SourceHeader, Path: "Cache-Control", Operator: OpEquals, Value: "no-cache, no-store"},
+			{Source: SourceHeader, Path: "Age", Operator: OpLessThan, Value: "60"},
+			{Source: SourceHeader, Path: "X-Missing", Operator: OpNotExists},
+		}}
+		outcomes, err := config.CheckAssertions(header, body)
+		if err != nil {
+			t.Fatalf("CheckAssertions() error = %v", err)
+		}
+		if len(outcomes) != len(config.Assertions) {
+			t.Fatalf("CheckAssertions() returned %d outcomes, want %d", len(outcomes), len(config.Assertions))
+		}
+		if got := outcomes["assertion_1"]; got != `json status equals "ok": passed` {
+			t.Errorf("assertion_1 = %q", got)
+		}
+		if got := outcomes["assertion_6"]; got != "header X-Missing not_exists: passed" {
+			t.Errorf("assertion_6 = %q", got)
+		}
+	})
+
+	t.Run("reports the first failure", func(t *testing.T) {
+		config := &Config{Assertions: []Assertion{
+			{Source: SourceJSON, Path: "status", Operator: OpEquals, Value: "ok"},
+			{Source: SourceJSON, Path: "items.#", Operator: OpGreaterThan, Value: "5"},
+			{Source: SourceHeader, Path: "X-Missing", Operator: OpExists},
+		}}
+		outcomes, err := config.CheckAssertions(header, body)
+		var assertion *AssertionError
+		if !errors.As(err, &assertion) {
+			t.Fatalf("CheckAssertions() error = %v, want *AssertionError", err)
+		}
+		if assertion.Assertion != SourceJSON || assertion.Mode != OpGreaterThan || assertion.Path != "items.#" {
+			t.Errorf("AssertionError = %+v, want the items.# assertion", assertion)
+		}
+		if !strings.HasPrefix(assertion.Message, "2 of 3 assertions failed, first ") {
+			t.Errorf("Message = %q, want a count of failures", assertion.Message)
+		}
+		if got := outcomes["assertion_2"]; got != `json items.# greater_than "5": failed, got 3` {
+			t.Errorf("assertion_2 = %q", got)
+		}
+		if got := outcomes["assertion_3"]; got != "header X-Missing exists: failed, not found" {
+			t.Errorf("assertion_3 = %q", got)
+		}
+	})
+
+	t.Run("body that is not JSON", func(t *testing.T) {
+		config := &Config{Assertions: []Assertion{
+			{Source: SourceHeader, Path: "Age", Operator: OpExists},
+			{Source: SourceJSON, Path: "status", Operator: OpNotExists},
+		}}
+		outcomes, err := config.CheckAssertions(header, []byte("<html>"))
+		if err == nil || !strings.Contains(err.Error(), "response body is not valid JSON") {
+			t.Fatalf("CheckAssertions() error = %v, want invalid JSON", err)
+		}
+		if got := outcomes["assertion_1"]; !strings.HasSuffix(got, ": passed") {
+			t.Errorf("header assertion = %q, want it to pass", got)
+		}
+	})
+
+	t.Run("no assertions", func(t *testing.T) {
+		var config *Config
+		outcomes, err := config.CheckAssertions(header, body)
+		if outcomes != nil || err != nil {
+			t.Errorf("CheckAssertions() = %v, %v, want nothing", outcomes, err)
+		}
+	})
+}
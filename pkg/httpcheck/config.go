@@ -0,0 +1,120 @@
+// Package httpcheck describes how a probe worker makes an HTTP check and
+// judges its response. The scheduler builds a Config from the monitor and it
+// travels unchanged through the probe manager to the worker.
+package httpcheck
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultMaxRedirects is how many redirects are followed when the monitor
+	// does not say
+	DefaultMaxRedirects = 5
+	// DefaultUserAgent identifies checks that do not set their own
+	DefaultUserAgent = "monitoring-probe/1.0"
+)
+
+// Keys of the check result details that break down how long a check took,
+// in milliseconds. The phases are those of the last request when redirects
+// were followed, and DetailResponseTime covers them all up to the end of the
+// body.
+const (
+	DetailDNSTime       = "dns_time_ms"
+	DetailConnectTime   = "connect_time_ms"
+	DetailTLSTime       = "tls_handshake_time_ms"
+	DetailFirstByteTime = "first_byte_time_ms"
+	DetailResponseTime  = "response_time_ms"
+	// DetailResponseSize is the size of the response body in bytes
+	DetailResponseSize = "response_size"
+)
+
+// Methods lists the request methods a check may use
+var Methods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// BasicAuth holds credentials sent with every request of a check
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// Config is the HTTP configuration of a check. The zero value sends a GET,
+// follows up to DefaultMaxRedirects redirects, verifies certificates and
+// treats any status below 400 as success.
+type Config struct {
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	// ExpectedStatusCodes replaces the < 400 rule when set
+	ExpectedStatusCodes []int             `json:"expected_status_codes,omitempty"`
+	FollowRedirects     *bool             `json:"follow_redirects,omitempty"`
+	MaxRedirects        *int              `json:"max_redirects,omitempty"`
+	VerifySSL           *bool             `json:"verify_ssl,omitempty"`
+	BasicAuth           *BasicAuth        `json:"basic_auth,omitempty"`
+	UserAgent           string            `json:"user_agent,omitempty"`
+	Cookies             map[string]string `json:"cookies,omitempty"`
+	// ContentMatch is checked against the response body as ContentMatchMode
+	// says; empty leaves the body unread
+	ContentMatch     string `json:"content_match,omitempty"`
+	ContentMatchMode string `json:"content_match_mode,omitempty"`
+	// Assertions are further checks on the JSON body and headers, all of
+	// which must pass
+	Assertions []Assertion `json:"assertions,omitempty"`
+	// MaxResponseTimeMs fails a check whose response, including any body
+	// read for assertions, takes longer; zero means no budget
+	MaxResponseTimeMs int `json:"max_response_time_ms,omitempty"`
+}
+
+// RequestMethod returns the method to send, GET by default
+func (c *Config) RequestMethod() string {
+	if c == nil || c.Method == "" {
+		return http.MethodGet
+	}
+	return strings.ToUpper(c.Method)
+}
+
+// RedirectLimit returns how many redirects to follow; zero means none
+func (c *Config) RedirectLimit() int {
+	if c == nil {
+		return DefaultMaxRedirects
+	}
+	if c.FollowRedirects != nil && !*c.FollowRedirects {
+		return 0
+	}
+	if c.MaxRedirects != nil {
+		return max(*c.MaxRedirects, 0)
+	}
+	return DefaultMaxRedirects
+}
+
+// VerifiesTLS reports whether certificates must be valid, which they must
+// unless the monitor turns verification off
+func (c *Config) VerifiesTLS() bool {
+	return c == nil || c.VerifySSL == nil || *c.VerifySSL
+}
+
+// Agent returns the User-Agent to send
+func (c *Config) Agent() string {
+	if c == nil || c.UserAgent == "" {
+		return DefaultUserAgent
+	}
+	return c.UserAgent
+}
+
+// StatusOK reports whether the response status counts as success
+func (c *Config) StatusOK(code int) bool {
+	if c == nil || len(c.ExpectedStatusCodes) == 0 {
+		return code < 400
+	}
+	return slices.Contains(c.ExpectedStatusCodes, code)
+}
@@ -0,0 +1,85 @@
+package httpcheck
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// lookup finds path in a document decoded with json.Decoder.UseNumber. Paths
+// follow gjson: keys are separated by dots, a number indexes an array, "#"
+// is the length of an array and "\." is a dot within a key, e.g.
+// "data.items.0.name" or "data.items.#".
+func lookup(document any, path string) (any, bool) {
+	value := document
+	for _, key := range splitPath(path) {
+		switch node := value.(type) {
+		case map[string]any:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []any:
+			if key == "#" {
+				value = json.Number(strconv.Itoa(len(node)))
+				continue
+			}
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// splitPath splits a path into its keys
+func splitPath(path string) []string {
+	var keys []string
+	var key strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			key.WriteByte(path[i])
+		case path[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+	return append(keys, key.String())
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// jsonString renders a decoded value for comparison: strings as they are and
+// everything else as compact JSON
+func jsonString(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
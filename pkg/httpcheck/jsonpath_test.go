@@ -0,0 +1,115 @@
+package httpcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// decode decodes a JSON document the way CheckAssertions does
+func decode(t *testing.T, document string) any {
+	t.Helper()
+
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader([]byte(document)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("invalid test document: %v", err)
+	}
+	return value
+}
+
+func TestLookup(t *testing.T) {
+	document := decode(t, `{
+		"status": "ok",
+		"count": 3,
+		"ratio": 0.5,
+		"healthy": true,
+		"missing": null,
+		"data": {
+			"items": [
+				{"name": "first", "tags": ["a", "b"]},
+				{"name": "second", "tags": []}
+			]
+		},
+		"a.b": "dotted"
+	}`)
+
+	tests := []struct {
+		path  string
+		want  string
+		found bool
+	}{
+		{"status", "ok", true},
+		{"count", "3", true},
+		{"ratio", "0.5", true},
+		{"healthy", "true", true},
+		{"missing", "null", true},
+		{"data.items.0.name", "first", true},
+		{"data.items.1.name", "second", true},
+		{"data.items.0.tags.1", "b", true},
+		{"data.items.#", "2", true},
+		{"data.items.1.tags.#", "0", true},
+		{"data.items", `[{"name":"first","tags":["a","b"]},{"name":"second","tags":[]}]`, true},
+		{`a\.b`, "dotted", true},
+		// Missing keys, indexes out of range and paths through scalars
+		{"nope", "", false},
+		{"data.nope", "", false},
+		{"data.items.2", "", false},
+		{"data.items.-1", "", false},
+		{"data.items.first", "", false},
+		{"status.length", "", false},
+		{"a.b", "", false},
+		{"data.items.0.name.#", "", false},
+	}
+
+	for _, tt := range tests {
+		value, found := lookup(document, tt.path)
+		if found != tt.found {
+			t.Errorf("lookup(%q) found = %v, want %v", tt.path, found, tt.found)
+			continue
+		}
+		if found && jsonString(value) != tt.want {
+			t.Errorf("lookup(%q) = %s, want %s", tt.path, jsonString(value), tt.want)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"status", []string{"status"}},
+		{"data.items.0", []string{"data", "items", "0"}},
+		{`a\.b.c`, []string{"a.b", "c"}},
+		{`trailing\`, []string{`trailing\`}},
+		{"a..b", []string{"a", "", "b"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitPath(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("splitPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJSONType(t *testing.T) {
+	document := decode(t, `{"s": "x", "n": 1, "b": false, "o": {}, "a": [], "z": null}`)
+
+	tests := map[string]string{
+		"s": "string",
+		"n": "number",
+		"b": "boolean",
+		"o": "object",
+		"a": "array",
+		"z": "null",
+	}
+	for path, want := range tests {
+		value, _ := lookup(document, path)
+		if got := jsonType(value); got != want {
+			t.Errorf("jsonType(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package maintenance
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the base repetition period of an RRULE
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+)
+
+// maxRuleIterations bounds the number of candidate days examined when
+// searching for an occurrence, so that rules which never match terminate
+const maxRuleIterations = 5 * 366
+
+// Rule is the subset of RFC 5545 recurrence rules used for maintenance
+// windows: FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL, BYDAY, BYMONTHDAY,
+// BYHOUR, BYMINUTE, COUNT and UNTIL. Occurrences start at the rule's DTSTART
+// time of day unless BYHOUR or BYMINUTE say otherwise.
+type Rule struct {
+	Frequency  Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	ByHour     []int
+	ByMinute   []int
+	Count      int
+	Until      time.Time
+
+	dtstart time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRule parses an RRULE such as "FREQ=WEEKLY;BYDAY=SA,SU;BYHOUR=2",
+// optionally prefixed with "RRULE:". Occurrences are generated from dtstart
+// in dtstart's location.
+func ParseRule(spec string, dtstart time.Time) (*Rule, error) {
+	spec = strings.TrimSpace(spec)
+	spec = strings.TrimPrefix(strings.TrimPrefix(spec, "RRULE:"), "rrule:")
+
+	r := &Rule{Interval: 1, dtstart: dtstart}
+	for _, part := range strings.Split(spec, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rrule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Frequency = Frequency(strings.ToUpper(value))
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(value, -31, 31)
+			for _, d := range r.ByMonthDay {
+				if d == 0 {
+					err = fmt.Errorf("day 0 is not valid")
+				}
+			}
+		case "BYHOUR":
+			r.ByHour, err = parseInts(value, 0, 23)
+		case "BYMINUTE":
+			r.ByMinute, err = parseInts(value, 0, 59)
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "WKST":
+			// Weeks always start on Monday
+		default:
+			return nil, fmt.Errorf("unsupported rrule part %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid rrule %s: %w", key, err)
+		}
+	}
+
+	switch r.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	case "":
+		return nil, fmt.Errorf("rrule is missing FREQ")
+	default:
+		return nil, fmt.Errorf("unsupported rrule frequency %q", r.Frequency)
+	}
+
+	sort.Ints(r.ByHour)
+	sort.Ints(r.ByMinute)
+
+	return r, nil
+}
+
+// Next returns the first occurrence strictly after t, or the zero time if the
+// rule has no further occurrences
+func (r *Rule) Next(t time.Time) time.Time {
+	if r.Count > 0 {
+		// COUNT is relative to the start of the series, so occurrences have to
+		// be enumerated from DTSTART
+		occ := r.dtstart.Add(-time.Nanosecond)
+		for i := 0; i < r.Count; i++ {
+			occ = r.next(occ)
+			if occ.IsZero() {
+				return occ
+			}
+			if occ.After(t) {
+				return occ
+			}
+		}
+		return time.Time{}
+	}
+	return r.next(t)
+}
+
+func (r *Rule) next(t time.Time) time.Time {
+	loc := r.dtstart.Location()
+	if t.Before(r.dtstart) {
+		t = r.dtstart.Add(-time.Nanosecond)
+	}
+	t = t.In(loc)
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < maxRuleIterations; i++ {
+		if r.dayMatches(day) {
+			for _, tod := range r.timesOfDay() {
+				occ := time.Date(day.Year(), day.Month(), day.Day(), tod/60, tod%60, 0, 0, loc)
+				if !r.Until.IsZero() && occ.After(r.Until) {
+					return time.Time{}
+				}
+				if occ.After(t) && !occ.Before(r.dtstart) {
+					return occ
+				}
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+		if !r.Until.IsZero() && day.After(r.Until) {
+			return time.Time{}
+		}
+	}
+
+	return time.Time{}
+}
+
+// timesOfDay returns the minutes past midnight at which occurrences start
+func (r *Rule) timesOfDay() []int {
+	hours := r.ByHour
+	if len(hours) == 0 {
+		hours = []int{r.dtstart.Hour()}
+	}
+	minutes := r.ByMinute
+	if len(minutes) == 0 {
+		minutes = []int{r.dtstart.Minute()}
+	}
+
+	times := make([]int, 0, len(hours)*len(minutes))
+	for _, h := range hours {
+		for _, m := range minutes {
+			times = append(times, h*60+m)
+		}
+	}
+	return times
+}
+
+func (r *Rule) dayMatches(day time.Time) bool {
+	start := r.dtstart
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, day.Location())
+
+	switch r.Frequency {
+	case FrequencyDaily:
+		if daysBetween(startDay, day)%r.Interval != 0 {
+			return false
+		}
+		return r.matchesByDay(day) && r.matchesByMonthDay(day)
+	case FrequencyWeekly:
+		weeks := daysBetween(weekStart(startDay), weekStart(day)) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return r.matchesByDay(day)
+	case FrequencyMonthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			return day.Day() == start.Day()
+		}
+		return r.matchesByDay(day) && r.matchesByMonthDay(day)
+	}
+	return false
+}
+
+func (r *Rule) matchesByDay(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if day.Weekday() == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesByMonthDay supports negative days counted back from the end of the
+// month, so -1 is the last day
+func (r *Rule) matchesByMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+	for _, d := range r.ByMonthDay {
+		if d < 0 {
+			d = daysInMonth + d + 1
+		}
+		if day.Day() == d {
+			return true
+		}
+	}
+	return false
+}
+
+func daysBetween(a, b time.Time) int {
+	// Compare calendar dates in UTC so daylight saving shifts do not skew the count
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
+
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return time.Date(day.Year(), day.Month(), day.Day()-offset, 0, 0, 0, 0, day.Location())
+}
+
+func parseInts(value string, min, max int) ([]int, error) {
+	var out []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, err
+		}
+		if n < min || n > max {
+			return nil, fmt.Errorf("value %d out of range %d-%d", n, min, max)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", value)
+}
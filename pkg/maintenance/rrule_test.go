@@ -0,0 +1,185 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRuleErrors(t *testing.T) {
+	dtstart := time.Date(2025, time.January, 6, 2, 0, 0, 0, time.UTC)
+	tests := []string{
+		"",
+		"FREQ",
+		"BYDAY=MO",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=x",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-32",
+		"FREQ=DAILY;BYHOUR=24",
+		"FREQ=DAILY;BYMINUTE=60",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;BYSETPOS=1",
+	}
+
+	for _, spec := range tests {
+		if _, err := ParseRule(spec, dtstart); err == nil {
+			t.Errorf("ParseRule(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	// A Monday
+	dtstart := time.Date(2025, time.January, 6, 2, 0, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2025, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"first occurrence is dtstart", "FREQ=DAILY", at(1, 1, 0, 0), dtstart},
+		{"strictly after", "FREQ=DAILY", dtstart, at(1, 7, 2, 0)},
+		{"prefix and lower case", "rrule:freq=daily", at(1, 6, 3, 0), at(1, 7, 2, 0)},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", at(1, 7, 0, 0), at(1, 9, 2, 0)},
+		{"several times a day", "FREQ=DAILY;BYHOUR=13,1", at(1, 6, 3, 0), at(1, 6, 13, 0)},
+		{"daily on weekdays", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", at(1, 10, 3, 0), at(1, 13, 2, 0)},
+		{"weekly on dtstart's weekday", "FREQ=WEEKLY", at(1, 6, 3, 0), at(1, 13, 2, 0)},
+		{"weekly by day and time", "FREQ=WEEKLY;BYDAY=SA,SU;BYHOUR=2;BYMINUTE=30", at(1, 6, 3, 0), at(1, 11, 2, 30)},
+		{"fortnightly within the week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", at(1, 6, 3, 0), at(1, 8, 2, 0)},
+		{"fortnightly skips a week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", at(1, 9, 0, 0), at(1, 20, 2, 0)},
+		{"monthly on dtstart's day", "FREQ=MONTHLY", at(1, 6, 3, 0), at(2, 6, 2, 0)},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1", at(2, 1, 0, 0), at(2, 28, 2, 0)},
+		{"skips months without the day", "FREQ=MONTHLY;BYMONTHDAY=31", at(2, 1, 0, 0), at(3, 31, 2, 0)},
+		{"quarterly", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1", at(1, 6, 3, 0), at(4, 1, 2, 0)},
+		{"weekday and month day", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13", at(1, 6, 3, 0), at(6, 13, 2, 0)},
+		{"within count", "FREQ=DAILY;COUNT=3", at(1, 7, 2, 0), at(1, 8, 2, 0)},
+		{"count exhausted", "FREQ=DAILY;COUNT=3", at(1, 8, 2, 0), time.Time{}},
+		{"until inclusive", "FREQ=DAILY;UNTIL=20250108T020000Z", at(1, 7, 3, 0), at(1, 8, 2, 0)},
+		{"after until", "FREQ=DAILY;UNTIL=20250108T020000Z", at(1, 8, 2, 0), time.Time{}},
+		{"never matches", "FREQ=MONTHLY;BYMONTHDAY=30;BYDAY=SU;UNTIL=20250301", at(1, 6, 3, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.spec, dtstart)
+			if err != nil {
+				t.Fatalf("ParseRule(%q) error = %v", tt.spec, err)
+			}
+			if got := rule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleNextInLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		dtstart time.Time
+		from    time.Time
+		want    time.Time
+	}{
+		{
+			name:    "same local time after clocks go forward",
+			spec:    "FREQ=DAILY;BYHOUR=9",
+			dtstart: time.Date(2025, 3, 1, 9, 0, 0, 0, newYork),
+			from:    time.Date(2025, 3, 8, 10, 0, 0, 0, newYork),
+			want:    time.Date(2025, 3, 9, 9, 0, 0, 0, newYork),
+		},
+		{
+			// 02:30 does not exist that day and resolves as time.Date does
+			name:    "skipped local time still occurs",
+			spec:    "FREQ=DAILY",
+			dtstart: time.Date(2025, 3, 1, 2, 30, 0, 0, newYork),
+			from:    time.Date(2025, 3, 8, 3, 0, 0, 0, newYork),
+			want:    time.Date(2025, 3, 9, 2, 30, 0, 0, newYork),
+		},
+		{
+			name:    "weekday in the rule's location",
+			spec:    "FREQ=WEEKLY;BYDAY=SU;BYHOUR=22",
+			dtstart: time.Date(2025, 1, 5, 22, 0, 0, 0, newYork),
+			from:    time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC),
+			want:    time.Date(2025, 1, 12, 22, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.spec, tt.dtstart)
+			if err != nil {
+				t.Fatalf("ParseRule(%q) error = %v", tt.spec, err)
+			}
+			if got := rule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowActiveAt(t *testing.T) {
+	start := time.Date(2025, time.January, 6, 2, 0, 0, 0, time.UTC)
+
+	oneOff := &Window{
+		MonitorIDs: []string{"m1"},
+		StartsAt:   start,
+		EndsAt:     start.Add(time.Hour),
+	}
+	recurring := &Window{
+		MonitorIDs: []string{"m1"},
+		StartsAt:   start,
+		EndsAt:     start.AddDate(0, 0, 14),
+		Duration:   time.Hour,
+		Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH",
+	}
+	cronWindow := &Window{
+		MonitorIDs: []string{"m1"},
+		StartsAt:   start,
+		Duration:   30 * time.Minute,
+		Recurrence: "0 3 * * *",
+	}
+	for _, w := range []*Window{oneOff, recurring, cronWindow} {
+		if err := w.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		window *Window
+		at     time.Time
+		want   bool
+	}{
+		{"one-off before", oneOff, start.Add(-time.Minute), false},
+		{"one-off start", oneOff, start, true},
+		{"one-off during", oneOff, start.Add(59 * time.Minute), true},
+		{"one-off end", oneOff, start.Add(time.Hour), false},
+		{"recurring first", recurring, start.Add(30 * time.Minute), true},
+		{"recurring between", recurring, start.AddDate(0, 0, 1), false},
+		{"recurring second", recurring, start.AddDate(0, 0, 3).Add(59 * time.Minute), true},
+		{"recurring occurrence end", recurring, start.AddDate(0, 0, 3).Add(time.Hour), false},
+		{"recurring after series", recurring, start.AddDate(0, 0, 14).Add(time.Minute), false},
+		{"cron during", cronWindow, start.Add(75 * time.Minute), true},
+		{"cron after", cronWindow, start.Add(90 * time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.window.ActiveAt(tt.at); got != tt.want {
+			t.Errorf("%s: ActiveAt(%s) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
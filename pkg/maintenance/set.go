@@ -0,0 +1,89 @@
+package maintenance
+
+import "time"
+
+// Target identifies a monitor for the purpose of matching windows
+type Target struct {
+	MonitorID string
+	UserID    string
+	GroupID   string
+	Tags      []string
+}
+
+// Set indexes validated windows by the monitors, groups and tags they cover
+// so that the active window for a monitor can be found without scanning
+// every window
+type Set struct {
+	byMonitor map[string][]*Window
+	byGroup   map[string][]*Window
+	byTag     map[string][]*Window
+	size      int
+}
+
+func NewSet(windows []*Window) *Set {
+	s := &Set{
+		byMonitor: make(map[string][]*Window),
+		byGroup:   make(map[string][]*Window),
+		byTag:     make(map[string][]*Window),
+		size:      len(windows),
+	}
+	for _, w := range windows {
+		for _, id := range w.MonitorIDs {
+			s.byMonitor[id] = append(s.byMonitor[id], w)
+		}
+		for _, id := range w.GroupIDs {
+			s.byGroup[id] = append(s.byGroup[id], w)
+		}
+		for _, tag := range w.Tags {
+			s.byTag[tag] = append(s.byTag[tag], w)
+		}
+	}
+	return s
+}
+
+// Len returns the number of windows in the set
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return s.size
+}
+
+// Active returns the window covering the target at t, or nil if the target is
+// not under maintenance. When several windows overlap, one that skips checks
+// takes precedence over one that only flags them.
+func (s *Set) Active(target Target, at time.Time) *Window {
+	if s == nil {
+		return nil
+	}
+
+	var active *Window
+	consider := func(windows []*Window) bool {
+		for _, w := range windows {
+			if !w.ownedBy(target.UserID) || !w.ActiveAt(at) {
+				continue
+			}
+			if w.Action == ActionSkip {
+				active = w
+				return true
+			}
+			if active == nil {
+				active = w
+			}
+		}
+		return false
+	}
+
+	if consider(s.byMonitor[target.MonitorID]) {
+		return active
+	}
+	if target.GroupID != "" && consider(s.byGroup[target.GroupID]) {
+		return active
+	}
+	for _, tag := range target.Tags {
+		if consider(s.byTag[tag]) {
+			return active
+		}
+	}
+	return active
+}
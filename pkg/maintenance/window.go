@@ -0,0 +1,219 @@
+// Package maintenance models maintenance windows, during which checks for the
+// covered monitors are either skipped or flagged and alerting is suppressed.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/cron"
+)
+
+// Action controls what happens to checks that fall inside a window
+type Action string
+
+const (
+	// ActionSkip stops checks from being dispatched at all
+	ActionSkip Action = "skip"
+	// ActionFlag dispatches checks as usual but marks their results as taken
+	// during maintenance
+	ActionFlag Action = "flag"
+)
+
+// Recurrence yields the start times of a recurring window's occurrences
+type Recurrence interface {
+	Next(t time.Time) time.Time
+}
+
+// Window is a period during which a set of monitors is under maintenance.
+// A one-off window runs from StartsAt to EndsAt. A recurring window repeats
+// according to Recurrence, either an RRULE or a cron expression, with each
+// occurrence lasting Duration; StartsAt and EndsAt then bound the series.
+type Window struct {
+	ID         string
+	UserID     string
+	Name       string
+	MonitorIDs []string
+	GroupIDs   []string
+	Tags       []string
+	StartsAt   time.Time
+	EndsAt     time.Time
+	Duration   time.Duration
+	Recurrence string
+	Timezone   string
+	Action     Action
+
+	recurrence Recurrence
+}
+
+// Validate checks the window's configuration and prepares its recurrence for
+// evaluation. It must be called before ActiveAt or NextOccurrences.
+func (w *Window) Validate() error {
+	if len(w.MonitorIDs) == 0 && len(w.GroupIDs) == 0 && len(w.Tags) == 0 {
+		return errors.New("maintenance window must target at least one monitor, group or tag")
+	}
+	if w.StartsAt.IsZero() {
+		return errors.New("maintenance window must have a start time")
+	}
+	if !w.EndsAt.IsZero() && !w.EndsAt.After(w.StartsAt) {
+		return errors.New("maintenance window must end after it starts")
+	}
+
+	switch w.Action {
+	case "":
+		w.Action = ActionSkip
+	case ActionSkip, ActionFlag:
+	default:
+		return fmt.Errorf("unsupported maintenance action %q", w.Action)
+	}
+
+	if w.Recurrence == "" {
+		if w.EndsAt.IsZero() {
+			return errors.New("one-off maintenance window must have an end time")
+		}
+		w.recurrence = nil
+		return nil
+	}
+
+	if w.Duration <= 0 {
+		return errors.New("recurring maintenance window must have a duration")
+	}
+
+	loc := time.UTC
+	if w.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", w.Timezone, err)
+		}
+	}
+
+	recurrence, err := ParseRecurrence(w.Recurrence, w.StartsAt.In(loc))
+	if err != nil {
+		return err
+	}
+	if recurrence.Next(w.StartsAt.Add(-time.Nanosecond)).IsZero() {
+		return errors.New("maintenance window recurrence never occurs")
+	}
+	w.recurrence = recurrence
+
+	return nil
+}
+
+// ParseRecurrence parses either an RRULE (recognised by its FREQ part) or a
+// cron expression. Both are evaluated in dtstart's location.
+func ParseRecurrence(spec string, dtstart time.Time) (Recurrence, error) {
+	if strings.Contains(strings.ToUpper(spec), "FREQ=") {
+		return ParseRule(spec, dtstart)
+	}
+
+	schedule, err := cron.ParseInLocation(spec, dtstart.Location())
+	if err != nil {
+		return nil, err
+	}
+	return &boundedSchedule{schedule: schedule, dtstart: dtstart}, nil
+}
+
+// boundedSchedule keeps a cron schedule from firing before the window's
+// series starts
+type boundedSchedule struct {
+	schedule *cron.Schedule
+	dtstart  time.Time
+}
+
+func (b *boundedSchedule) Next(t time.Time) time.Time {
+	if t.Before(b.dtstart) {
+		t = b.dtstart.Add(-time.Nanosecond)
+	}
+	return b.schedule.Next(t)
+}
+
+// Recurring reports whether the window repeats
+func (w *Window) Recurring() bool {
+	return w.recurrence != nil
+}
+
+// ActiveAt reports whether t falls inside the window
+func (w *Window) ActiveAt(t time.Time) bool {
+	if t.Before(w.StartsAt) {
+		return false
+	}
+	if !w.EndsAt.IsZero() && !t.Before(w.EndsAt) {
+		return false
+	}
+	if w.recurrence == nil {
+		return true
+	}
+
+	// An occurrence covers t if it started within the last Duration
+	start := w.recurrence.Next(t.Add(-w.Duration))
+	return !start.IsZero() && !start.After(t)
+}
+
+// Occurrence is a single period of maintenance
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+}
+
+// NextOccurrences returns up to n occurrences that end after t, including one
+// already in progress
+func (w *Window) NextOccurrences(t time.Time, n int) []Occurrence {
+	if w.recurrence == nil {
+		if !w.EndsAt.After(t) || n < 1 {
+			return nil
+		}
+		return []Occurrence{{Start: w.StartsAt, End: w.EndsAt}}
+	}
+
+	var occurrences []Occurrence
+	cursor := t.Add(-w.Duration)
+	for len(occurrences) < n {
+		start := w.recurrence.Next(cursor)
+		if start.IsZero() || (!w.EndsAt.IsZero() && !start.Before(w.EndsAt)) {
+			break
+		}
+		end := start.Add(w.Duration)
+		if !w.EndsAt.IsZero() && end.After(w.EndsAt) {
+			end = w.EndsAt
+		}
+		occurrences = append(occurrences, Occurrence{Start: start, End: end})
+		cursor = start
+	}
+	return occurrences
+}
+
+// Covers reports whether the window applies to the target monitor
+func (w *Window) Covers(target Target) bool {
+	if !w.ownedBy(target.UserID) {
+		return false
+	}
+	for _, id := range w.MonitorIDs {
+		if id == target.MonitorID {
+			return true
+		}
+	}
+	if target.GroupID != "" {
+		for _, id := range w.GroupIDs {
+			if id == target.GroupID {
+				return true
+			}
+		}
+	}
+	for _, want := range w.Tags {
+		for _, tag := range target.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ownedBy reports whether the window may apply to monitors of the given user.
+// Tags and groups are per-user names, so a window never reaches across
+// accounts.
+func (w *Window) ownedBy(userID string) bool {
+	return w.UserID == "" || w.UserID == userID
+}
@@ -1,11 +1,14 @@
 package observability
 
 import (
+	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
 )
 
 var (
@@ -65,3 +68,15 @@ func PrometheusMiddleware() gin.HandlerFunc {
 		httpRequestDuration.WithLabelValues(c.Request.Method, path, status).Observe(duration)
 	}
 }
+
+// ServeMetrics exposes the Prometheus registry on addr for services that do not run their own HTTP server
+func ServeMetrics(addr string, logger *zap.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics server stopped", zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,218 @@
+package pingcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Kinds of socket the echoes are sent over
+const (
+	SocketDatagram = "datagram"
+	SocketRaw      = "raw"
+)
+
+// Pacing of the echoes: one a second, as ping sends them, unless the check's
+// deadline calls for less, but never faster than unprivileged ping allows
+const (
+	packetInterval    = time.Second
+	minPacketInterval = 200 * time.Millisecond
+	// replyWait is how long the last echo is given to be answered
+	replyWait = time.Second
+)
+
+// Protocol numbers ICMP messages are parsed with
+const (
+	protocolICMP   = 1
+	protocolICMPv6 = 58
+)
+
+// family holds what differs between pinging over IPv4 and IPv6
+type family struct {
+	datagram, raw string
+	wildcard      string
+	protocol      int
+	request       icmp.Type
+	reply         icmp.Type
+}
+
+var (
+	familyIPv4 = family{"udp4", "ip4:icmp", "0.0.0.0", protocolICMP, ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply}
+	familyIPv6 = family{"udp6", "ip6:ipv6-icmp", "::", protocolICMPv6, ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply}
+)
+
+// listen opens a datagram ICMP socket, or a raw one if the kernel refuses
+// the datagram socket, and says which it opened
+func (f family) listen() (*icmp.PacketConn, string, error) {
+	conn, err := icmp.ListenPacket(f.datagram, f.wildcard)
+	if err == nil {
+		return conn, SocketDatagram, nil
+	}
+	conn, rawErr := icmp.ListenPacket(f.raw, f.wildcard)
+	if rawErr != nil {
+		return nil, "", fmt.Errorf("cannot open an ICMP socket: datagram: %v; raw: %w", err, rawErr)
+	}
+	return conn, SocketRaw, nil
+}
+
+// Available reports whether this process can open an IPv4 ICMP socket of
+// either kind
+func Available() error {
+	conn, _, err := familyIPv4.listen()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Ping sends the check's echo requests to host, one at a time, and collects
+// the round trips of the replies. Echoes still unanswered when ctx is done
+// count as lost.
+func Ping(ctx context.Context, host string, config *Config) (*Stats, error) {
+	ip, err := resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	f := familyIPv4
+	if ip.To4() == nil {
+		f = familyIPv6
+	}
+
+	conn, socket, err := f.listen()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Datagram sockets only see replies to their own echoes, the kernel
+	// setting the identifier; raw sockets see every reply to the host
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if socket == SocketRaw {
+		dst = &net.IPAddr{IP: ip}
+	}
+	id := rand.N(1 << 16)
+
+	count := config.Count()
+	interval := packetInterval
+	if deadline, ok := ctx.Deadline(); ok {
+		interval = max(min(interval, time.Until(deadline)/time.Duration(count+1)), minPacketInterval)
+	}
+
+	stats := &Stats{Address: ip.String(), Socket: socket}
+	payload := []byte(strings.Repeat("x", config.Size()))
+	sentAt := make([]time.Time, count)
+	answered := make([]bool, count)
+	buf := make([]byte, 1<<16)
+
+	var nextSend, lastWait time.Time
+	for stats.Sent < count || len(stats.RTTs) < stats.Sent {
+		if ctx.Err() != nil {
+			break
+		}
+
+		now := time.Now()
+		if stats.Sent < count && !now.Before(nextSend) {
+			msg := icmp.Message{Type: f.request, Body: &icmp.Echo{ID: id, Seq: stats.Sent, Data: payload}}
+			packet, err := msg.Marshal(nil)
+			if err != nil {
+				return nil, err
+			}
+			sentAt[stats.Sent] = now
+			if _, err := conn.WriteTo(packet, dst); err != nil {
+				return nil, fmt.Errorf("sending echo request to %s: %w", ip, err)
+			}
+			stats.Sent++
+			nextSend = now.Add(interval)
+			if stats.Sent == count {
+				lastWait = now.Add(replyWait)
+			}
+		}
+
+		readUntil := nextSend
+		if stats.Sent == count {
+			if !now.Before(lastWait) {
+				break
+			}
+			readUntil = lastWait
+		}
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(readUntil) {
+			readUntil = deadline
+		}
+		if err := conn.SetReadDeadline(readUntil); err != nil {
+			return nil, err
+		}
+
+		n, peer, err := conn.ReadFrom(buf)
+		received := time.Now()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return nil, fmt.Errorf("reading echo replies from %s: %w", ip, err)
+		}
+		seq, ok := reply(f, buf[:n], peer, ip, id, socket == SocketRaw)
+		if !ok || seq >= stats.Sent || answered[seq] {
+			continue
+		}
+		answered[seq] = true
+		stats.RTTs = append(stats.RTTs, received.Sub(sentAt[seq]))
+	}
+
+	stats.summarise()
+	return stats, nil
+}
+
+// reply returns the sequence number of an echo reply from ip, or false if the
+// packet is anything else. Raw sockets also check the identifier.
+func reply(f family, packet []byte, peer net.Addr, ip net.IP, id int, checkID bool) (int, bool) {
+	var from net.IP
+	switch addr := peer.(type) {
+	case *net.UDPAddr:
+		from = addr.IP
+	case *net.IPAddr:
+		from = addr.IP
+	}
+	if !from.Equal(ip) {
+		return 0, false
+	}
+
+	msg, err := icmp.ParseMessage(f.protocol, packet)
+	if err != nil || msg.Type != f.reply {
+		return 0, false
+	}
+	echo, ok := msg.Body.(*icmp.Echo)
+	if !ok || (checkID && echo.ID != id) {
+		return 0, false
+	}
+	return echo.Seq, true
+}
+
+// resolve looks up the address to ping, preferring IPv4
+func resolve(ctx context.Context, host string) (net.IP, error) {
+	host = strings.Trim(host, "[]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	return addrs[0].IP, nil
+}
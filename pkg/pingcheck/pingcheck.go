@@ -0,0 +1,167 @@
+// Package pingcheck sends ICMP echo requests to a host and judges the round
+// trips against a monitor's thresholds. Echoes go out over an unprivileged
+// datagram socket where the kernel allows one, and over a raw socket
+// otherwise, which needs root or CAP_NET_RAW.
+package pingcheck
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Defaults of the thresholds, matching those of monitor_ping_extension
+const (
+	DefaultPacketCount          = 4
+	DefaultPacketSize           = 56
+	DefaultMaxLatencyMs         = 500
+	DefaultMaxPacketLossPercent = 10
+)
+
+// MaxPacketCount bounds the echoes a single check sends
+const MaxPacketCount = 100
+
+// MaxPacketSize is the largest echo payload that fits in an IPv4 packet
+const MaxPacketSize = 65507
+
+// Keys of the check result details of a ping check
+const (
+	DetailAddress         = "ping_address"
+	DetailSocket          = "ping_socket"
+	DetailPacketsSent     = "packets_sent"
+	DetailPacketsReceived = "packets_received"
+	DetailPacketLoss      = "packet_loss_percent"
+	// Round trip times are in milliseconds, to the microsecond
+	DetailRTTMin = "rtt_min_ms"
+	DetailRTTAvg = "rtt_avg_ms"
+	DetailRTTMax = "rtt_max_ms"
+	// DetailJitter is the mean difference between consecutive round trips
+	DetailJitter = "jitter_ms"
+)
+
+// Config is the ping configuration of a check. Zero fields take the defaults.
+type Config struct {
+	PacketCount int `json:"packet_count,omitempty"`
+	// PacketSize is the size of each echo's payload in bytes
+	PacketSize int `json:"packet_size,omitempty"`
+	// MaxLatencyMs fails checks whose average round trip is longer
+	MaxLatencyMs int `json:"max_latency_ms,omitempty"`
+	// MaxPacketLossPercent is a pointer so that no loss at all can be asked
+	// for
+	MaxPacketLossPercent *int `json:"max_packet_loss_percent,omitempty"`
+}
+
+// Count returns how many echoes to send
+func (c *Config) Count() int {
+	if c == nil || c.PacketCount <= 0 {
+		return DefaultPacketCount
+	}
+	return min(c.PacketCount, MaxPacketCount)
+}
+
+// Size returns the payload size of each echo
+func (c *Config) Size() int {
+	if c == nil || c.PacketSize <= 0 {
+		return DefaultPacketSize
+	}
+	return min(c.PacketSize, MaxPacketSize)
+}
+
+func (c *Config) maxLatency() time.Duration {
+	if c == nil || c.MaxLatencyMs <= 0 {
+		return DefaultMaxLatencyMs * time.Millisecond
+	}
+	return time.Duration(c.MaxLatencyMs) * time.Millisecond
+}
+
+func (c *Config) maxLoss() float64 {
+	if c == nil || c.MaxPacketLossPercent == nil {
+		return DefaultMaxPacketLossPercent
+	}
+	return float64(*c.MaxPacketLossPercent)
+}
+
+// Stats summarises the round trips of a ping check
+type Stats struct {
+	Address string
+	// Socket is "datagram" or "raw"
+	Socket   string
+	Sent     int
+	Received int
+	// RTTs holds the round trip of each echo answered, in the order sent
+	RTTs   []time.Duration
+	Min    time.Duration
+	Avg    time.Duration
+	Max    time.Duration
+	Jitter time.Duration
+}
+
+// Loss returns the percentage of echoes that went unanswered
+func (s *Stats) Loss() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Sent-s.Received) * 100 / float64(s.Sent)
+}
+
+// summarise works out the minimum, average and maximum round trips and the
+// jitter from RTTs
+func (s *Stats) summarise() {
+	s.Received = len(s.RTTs)
+	if s.Received == 0 {
+		return
+	}
+
+	var total, variation time.Duration
+	s.Min, s.Max = s.RTTs[0], s.RTTs[0]
+	for i, rtt := range s.RTTs {
+		total += rtt
+		s.Min = min(s.Min, rtt)
+		s.Max = max(s.Max, rtt)
+		if i > 0 {
+			variation += (rtt - s.RTTs[i-1]).Abs()
+		}
+	}
+	s.Avg = total / time.Duration(s.Received)
+	if s.Received > 1 {
+		s.Jitter = variation / time.Duration(s.Received-1)
+	}
+}
+
+// Details returns the check result details describing the round trips
+func (s *Stats) Details() map[string]string {
+	details := map[string]string{
+		DetailAddress:         s.Address,
+		DetailSocket:          s.Socket,
+		DetailPacketsSent:     strconv.Itoa(s.Sent),
+		DetailPacketsReceived: strconv.Itoa(s.Received),
+		DetailPacketLoss:      strconv.FormatFloat(s.Loss(), 'f', 1, 64),
+	}
+	if s.Received > 0 {
+		details[DetailRTTMin] = milliseconds(s.Min)
+		details[DetailRTTAvg] = milliseconds(s.Avg)
+		details[DetailRTTMax] = milliseconds(s.Max)
+		details[DetailJitter] = milliseconds(s.Jitter)
+	}
+	return details
+}
+
+func milliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', 3, 64)
+}
+
+// Check judges a ping: some echoes must have been answered, no more than the
+// allowed share lost and the average round trip no longer than the maximum
+func (c *Config) Check(host string, stats *Stats) error {
+	if stats.Received == 0 {
+		return fmt.Errorf("no replies from %s to %d echo requests", host, stats.Sent)
+	}
+	if loss := stats.Loss(); loss > c.maxLoss() {
+		return fmt.Errorf("packet loss to %s of %.1f%% exceeds %.0f%%", host, loss, c.maxLoss())
+	}
+	if limit := c.maxLatency(); stats.Avg > limit {
+		return fmt.Errorf("average round trip to %s of %s exceeds %s", host,
+			stats.Avg.Round(time.Microsecond), limit)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package pingcheck
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestCountAndSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		wantCount int
+		wantSize  int
+	}{
+		{"nil config", nil, DefaultPacketCount, DefaultPacketSize},
+		{"zero values", &Config{}, DefaultPacketCount, DefaultPacketSize},
+		{"negative values", &Config{PacketCount: -1, PacketSize: -8}, DefaultPacketCount, DefaultPacketSize},
+		{"within bounds", &Config{PacketCount: 10, PacketSize: 1024}, 10, 1024},
+		{"at the maximum", &Config{PacketCount: MaxPacketCount, PacketSize: MaxPacketSize}, MaxPacketCount, MaxPacketSize},
+		{"over the maximum", &Config{PacketCount: 5000, PacketSize: 1 << 20}, MaxPacketCount, MaxPacketSize},
+		{"one byte too large", &Config{PacketSize: MaxPacketSize + 1}, DefaultPacketCount, MaxPacketSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Count(); got != tt.wantCount {
+				t.Errorf("Count() = %d, want %d", got, tt.wantCount)
+			}
+			if got := tt.config.Size(); got != tt.wantSize {
+				t.Errorf("Size() = %d, want %d", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestSummarise(t *testing.T) {
+	ms := time.Millisecond
+
+	tests := []struct {
+		name       string
+		rtts       []time.Duration
+		wantMin    time.Duration
+		wantAvg    time.Duration
+		wantMax    time.Duration
+		wantJitter time.Duration
+	}{
+		{"no replies", nil, 0, 0, 0, 0},
+		{"one reply has no jitter", []time.Duration{12 * ms}, 12 * ms, 12 * ms, 12 * ms, 0},
+		{"steady", []time.Duration{10 * ms, 10 * ms, 10 * ms}, 10 * ms, 10 * ms, 10 * ms, 0},
+		// Differences 10, 20 and 30 between consecutive round trips
+		{"varying", []time.Duration{10 * ms, 20 * ms, 0, 30 * ms}, 0, 15 * ms, 30 * ms, 20 * ms},
+		// Jitter follows the order sent, not the spread
+		{"rising", []time.Duration{10 * ms, 20 * ms, 30 * ms, 40 * ms}, 10 * ms, 25 * ms, 40 * ms, 10 * ms},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := &Stats{Sent: 4, RTTs: tt.rtts}
+			stats.summarise()
+			if stats.Received != len(tt.rtts) {
+				t.Errorf("Received = %d, want %d", stats.Received, len(tt.rtts))
+			}
+			if stats.Min != tt.wantMin || stats.Avg != tt.wantAvg || stats.Max != tt.wantMax {
+				t.Errorf("min/avg/max = %s/%s/%s, want %s/%s/%s",
+					stats.Min, stats.Avg, stats.Max, tt.wantMin, tt.wantAvg, tt.wantMax)
+			}
+			if stats.Jitter != tt.wantJitter {
+				t.Errorf("Jitter = %s, want %s", stats.Jitter, tt.wantJitter)
+			}
+		})
+	}
+}
+
+func TestLoss(t *testing.T) {
+	tests := []struct {
+		sent, received int
+		want           float64
+	}{
+		{0, 0, 0},
+		{4, 4, 0},
+		{4, 3, 25},
+		{3, 1, 200.0 / 3},
+		{4, 0, 100},
+	}
+
+	for _, tt := range tests {
+		stats := &Stats{Sent: tt.sent, Received: tt.received}
+		if got := stats.Loss(); got != tt.want {
+			t.Errorf("Loss() with %d of %d received = %v, want %v", tt.received, tt.sent, got, tt.want)
+		}
+	}
+}
+
+func TestDetails(t *testing.T) {
+	stats := &Stats{
+		Address: "192.0.2.1",
+		Socket:  "datagram",
+		Sent:    3,
+		RTTs:    []time.Duration{1500 * time.Microsecond, 2500 * time.Microsecond},
+	}
+	stats.summarise()
+
+	want := map[string]string{
+		DetailAddress:         "192.0.2.1",
+		DetailSocket:          "datagram",
+		DetailPacketsSent:     "3",
+		DetailPacketsReceived: "2",
+		DetailPacketLoss:      "33.3",
+		DetailRTTMin:          "1.500",
+		DetailRTTAvg:          "2.000",
+		DetailRTTMax:          "2.500",
+		DetailJitter:          "1.000",
+	}
+	details := stats.Details()
+	for key, value := range want {
+		if details[key] != value {
+			t.Errorf("%s = %q, want %q", key, details[key], value)
+		}
+	}
+
+	// Without replies there are no round trips to report
+	lost := &Stats{Sent: 4}
+	lost.summarise()
+	details = lost.Details()
+	if details[DetailPacketLoss] != "100.0" {
+		t.Errorf("%s = %q, want 100.0", DetailPacketLoss, details[DetailPacketLoss])
+	}
+	for _, key := range []string{DetailRTTMin, DetailRTTAvg, DetailRTTMax, DetailJitter} {
+		if _, ok := details[key]; ok {
+			t.Errorf("%s reported without replies", key)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ms := time.Millisecond
+	stats := func(sent int, rtts ...time.Duration) *Stats {
+		s := &Stats{Sent: sent, RTTs: rtts}
+		s.summarise()
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		config  *Config
+		stats   *Stats
+		wantErr string
+	}{
+		{"healthy with defaults", nil, stats(4, 20*ms, 30*ms, 25*ms, 25*ms), ""},
+		{"no replies", nil, stats(4), "no replies from example.test to 4 echo requests"},
+		{"loss over the default", nil, stats(4, 20*ms, 20*ms, 20*ms), "packet loss to example.test of 25.0% exceeds 10%"},
+		{"loss within a raised limit", &Config{MaxPacketLossPercent: intPtr(50)}, stats(4, 20*ms, 20*ms), ""},
+		{"loss at the limit", &Config{MaxPacketLossPercent: intPtr(25)}, stats(4, 20*ms, 20*ms, 20*ms), ""},
+		{"any loss when none is allowed", &Config{MaxPacketLossPercent: intPtr(0)}, stats(10, 1*ms, 1*ms, 1*ms, 1*ms, 1*ms, 1*ms, 1*ms, 1*ms, 1*ms), "packet loss to example.test of 10.0% exceeds 0%"},
+		{"slow with defaults", nil, stats(2, 400*ms, 700*ms), "average round trip to example.test of 550ms exceeds 500ms"},
+		{"slow against a lower limit", &Config{MaxLatencyMs: 50}, stats(2, 40*ms, 70*ms), "average round trip to example.test of 55ms exceeds 50ms"},
+		{"average at the limit", &Config{MaxLatencyMs: 50}, stats(2, 40*ms, 60*ms), ""},
+		// A single slow echo does not fail a check whose average is fine
+		{"one slow echo", nil, stats(4, 10*ms, 10*ms, 10*ms, 900*ms), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Check("example.test", tt.stats)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Check() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
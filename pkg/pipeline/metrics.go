@@ -0,0 +1,15 @@
+package pipeline
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var messagesHandled = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "pipeline_messages_handled_total",
+		Help: "Total number of pipeline messages handled, by consumer and outcome (ack, nak, deferred, dead_letter)",
+	},
+	[]string{"consumer", "outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(messagesHandled)
+}
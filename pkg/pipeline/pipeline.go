@@ -0,0 +1,311 @@
+// Package pipeline defines the JetStream streams that carry checks, results,
+// analytics and notifications between services, and consumes them through
+// durable consumers so that a restarting service picks up where it left off
+// instead of dropping messages.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// Stream names
+const (
+	StreamCheckRequests    = "CHECK_REQUESTS"
+	StreamCheckAssignments = "CHECK_ASSIGNMENTS"
+	StreamCheckResults     = "CHECK_RESULTS"
+	StreamAnalytics        = "ANALYTICS"
+	StreamNotifications    = "NOTIFICATIONS"
+	StreamDeadLetter       = "DEAD_LETTER"
+)
+
+// Subjects carried by the streams
+const (
+	SubjectCheckRequest      = "probes.check.request"
+	SubjectCheckAssignPrefix = "probes.check.assign."
+	SubjectCheckResult       = "probes.check.result"
+	SubjectAnalyticsIngest   = "analytics.ingest"
+	SubjectNotificationsSend = "notifications.send"
+	SubjectDeadLetterPrefix  = "dlq."
+)
+
+// Headers set on dead-lettered messages
+const (
+	HeaderStream     = "Pipeline-Stream"
+	HeaderConsumer   = "Pipeline-Consumer"
+	HeaderSubject    = "Pipeline-Subject"
+	HeaderDeliveries = "Pipeline-Deliveries"
+	HeaderError      = "Pipeline-Error"
+	HeaderReason     = "Pipeline-Reason"
+)
+
+// ResultRetention is how long the results stream keeps results, and so how
+// long one may still be redelivered to a consumer
+const ResultRetention = 72 * time.Hour
+
+// streams lists every stream the pipeline needs. Hops with a single consumer
+// are work queues, so messages are removed once handled; results fan out to
+// several services and are kept for a while instead. Checks that have waited
+// longer than a few intervals are no longer worth running, so requests and
+// assignments expire much sooner than results.
+var streams = []jetstream.StreamConfig{
+	{
+		Name:      StreamCheckRequests,
+		Subjects:  []string{SubjectCheckRequest},
+		Retention: jetstream.WorkQueuePolicy,
+		MaxAge:    15 * time.Minute,
+	},
+	{
+		Name:      StreamCheckAssignments,
+		Subjects:  []string{SubjectCheckAssignPrefix + ">"},
+		Retention: jetstream.WorkQueuePolicy,
+		MaxAge:    5 * time.Minute,
+	},
+	{
+		Name:      StreamCheckResults,
+		Subjects:  []string{SubjectCheckResult},
+		Retention: jetstream.LimitsPolicy,
+		MaxAge:    ResultRetention,
+	},
+	{
+		Name:      StreamAnalytics,
+		Subjects:  []string{SubjectAnalyticsIngest},
+		Retention: jetstream.WorkQueuePolicy,
+		MaxAge:    72 * time.Hour,
+	},
+	{
+		Name:      StreamNotifications,
+		Subjects:  []string{SubjectNotificationsSend},
+		Retention: jetstream.WorkQueuePolicy,
+		MaxAge:    72 * time.Hour,
+	},
+	{
+		Name:      StreamDeadLetter,
+		Subjects:  []string{SubjectDeadLetterPrefix + ">"},
+		Retention: jetstream.LimitsPolicy,
+		MaxAge:    14 * 24 * time.Hour,
+	},
+}
+
+// EnsureStreams creates the pipeline's streams, or updates them to the
+// current configuration. Every service calls it on start so that none
+// depends on another having run first.
+func EnsureStreams(ctx context.Context, js jetstream.JetStream) error {
+	for _, cfg := range streams {
+		if _, err := js.CreateOrUpdateStream(ctx, cfg); err != nil {
+			return fmt.Errorf("failed to create stream %s: %w", cfg.Name, err)
+		}
+	}
+	return nil
+}
+
+// Handler processes a message's payload. Returning an error redelivers the
+// message after a backoff, unless the error is Permanent.
+type Handler func(data []byte) error
+
+// MsgHandler is a Handler for consumers that also need the message's headers
+// or metadata
+type MsgHandler func(msg jetstream.Msg) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying cannot fix, such as a malformed
+// payload. The message goes straight to the dead-letter stream.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// ErrDeferred is returned by a handler that has kept the message to settle
+// later. The handler then acks, naks or dead-letters it itself, and reports
+// progress while it holds it.
+var ErrDeferred = errors.New("message deferred")
+
+// ConsumerConfig describes a durable consumer
+type ConsumerConfig struct {
+	Stream  string
+	Durable string
+	// FilterSubject narrows the consumer to part of the stream
+	FilterSubject string
+	// MaxDeliver is how often a message is attempted before it is
+	// dead-lettered; defaults to 5
+	MaxDeliver int
+	// AckWait is how long a delivery may take before it is redelivered;
+	// defaults to 30s
+	AckWait time.Duration
+	// MaxBuffered caps the messages pulled ahead of the handler; defaults to
+	// the client's own limit
+	MaxBuffered int
+	// InactiveThreshold removes the consumer once nothing has pulled from it
+	// for this long; zero keeps it forever
+	InactiveThreshold time.Duration
+}
+
+// Consume creates or updates the durable consumer and starts handling its
+// messages. Each message is acked when the handler succeeds and nakked with
+// a growing delay when it fails. Messages that fail MaxDeliver times, or fail
+// permanently, are republished to the dead-letter stream and terminated.
+func Consume(ctx context.Context, js jetstream.JetStream, logger *zap.Logger, cfg ConsumerConfig, handler Handler) (jetstream.ConsumeContext, error) {
+	return ConsumeMsg(ctx, js, logger, cfg, func(msg jetstream.Msg) error {
+		return handler(msg.Data())
+	})
+}
+
+// ConsumeMsg is Consume for handlers that take the whole message
+func ConsumeMsg(ctx context.Context, js jetstream.JetStream, logger *zap.Logger, cfg ConsumerConfig, handler MsgHandler) (jetstream.ConsumeContext, error) {
+	if cfg.MaxDeliver <= 0 {
+		cfg.MaxDeliver = 5
+	}
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = 30 * time.Second
+	}
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+		Durable:           cfg.Durable,
+		FilterSubject:     cfg.FilterSubject,
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		AckWait:           cfg.AckWait,
+		MaxDeliver:        cfg.MaxDeliver,
+		InactiveThreshold: cfg.InactiveThreshold,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer %s on %s: %w", cfg.Durable, cfg.Stream, err)
+	}
+
+	var opts []jetstream.PullConsumeOpt
+	if cfg.MaxBuffered > 0 {
+		opts = append(opts, jetstream.PullMaxMessages(cfg.MaxBuffered))
+	}
+
+	return consumer.Consume(func(msg jetstream.Msg) {
+		handle(js, logger, cfg, handler, msg)
+	}, opts...)
+}
+
+func handle(js jetstream.JetStream, logger *zap.Logger, cfg ConsumerConfig, handler MsgHandler, msg jetstream.Msg) {
+	err := handler(msg)
+	if err == nil {
+		if err := msg.Ack(); err != nil {
+			logger.Error("failed to ack message",
+				zap.String("consumer", cfg.Durable),
+				zap.Error(err))
+		}
+		messagesHandled.WithLabelValues(cfg.Durable, "ack").Inc()
+		return
+	}
+	if errors.Is(err, ErrDeferred) {
+		messagesHandled.WithLabelValues(cfg.Durable, "deferred").Inc()
+		return
+	}
+
+	var deliveries uint64 = 1
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		deliveries = meta.NumDelivered
+	}
+
+	var permanent *permanentError
+	if !errors.As(err, &permanent) && deliveries < uint64(cfg.MaxDeliver) {
+		logger.Warn("failed to handle message, will retry",
+			zap.String("consumer", cfg.Durable),
+			zap.String("subject", msg.Subject()),
+			zap.Uint64("deliveries", deliveries),
+			zap.Error(err))
+		if err := msg.NakWithDelay(backoff(deliveries)); err != nil {
+			logger.Error("failed to nak message",
+				zap.String("consumer", cfg.Durable),
+				zap.Error(err))
+		}
+		messagesHandled.WithLabelValues(cfg.Durable, "nak").Inc()
+		return
+	}
+
+	logger.Error("failed to handle message, moving to dead-letter stream",
+		zap.String("consumer", cfg.Durable),
+		zap.String("subject", msg.Subject()),
+		zap.Uint64("deliveries", deliveries),
+		zap.Error(err))
+	if err := deadLetter(js, cfg, msg, deliveries, "", err); err != nil {
+		// Leave it unacked so it is redelivered rather than lost
+		logger.Error("failed to dead-letter message",
+			zap.String("consumer", cfg.Durable),
+			zap.Error(err))
+		return
+	}
+	if err := msg.Term(); err != nil {
+		logger.Error("failed to terminate message",
+			zap.String("consumer", cfg.Durable),
+			zap.Error(err))
+	}
+	messagesHandled.WithLabelValues(cfg.Durable, "dead_letter").Inc()
+}
+
+// DeadLetter moves a deferred message to the dead-letter stream and
+// terminates it. The reason is a short code for why it was given up on.
+func DeadLetter(js jetstream.JetStream, cfg ConsumerConfig, msg jetstream.Msg, reason string, cause error) error {
+	var deliveries uint64 = 1
+	if meta, err := msg.Metadata(); err == nil {
+		deliveries = meta.NumDelivered
+	}
+	if err := deadLetter(js, cfg, msg, deliveries, reason, cause); err != nil {
+		return err
+	}
+	messagesHandled.WithLabelValues(cfg.Durable, "dead_letter").Inc()
+	return msg.Term()
+}
+
+// deadLetter republishes a message that could not be handled to
+// dlq.<stream>.<consumer>, recording why in its headers
+func deadLetter(js jetstream.JetStream, cfg ConsumerConfig, msg jetstream.Msg, deliveries uint64, reason string, cause error) error {
+	dead := nats.NewMsg(SubjectDeadLetterPrefix + cfg.Stream + "." + cfg.Durable)
+	dead.Data = msg.Data()
+	for key, values := range msg.Headers() {
+		dead.Header[key] = values
+	}
+	dead.Header.Set(HeaderStream, cfg.Stream)
+	dead.Header.Set(HeaderConsumer, cfg.Durable)
+	dead.Header.Set(HeaderSubject, msg.Subject())
+	dead.Header.Set(HeaderDeliveries, strconv.FormatUint(deliveries, 10))
+	dead.Header.Set(HeaderError, cause.Error())
+	if reason != "" {
+		dead.Header.Set(HeaderReason, reason)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := js.PublishMsg(ctx, dead)
+	return err
+}
+
+// backoff returns the redelivery delay after the given number of attempts
+func backoff(deliveries uint64) time.Duration {
+	delay := time.Second << min(deliveries-1, 6)
+	return min(delay, time.Minute)
+}
+
+// Publish stores a message in its stream, waiting for the server to confirm
+// it has been persisted
+func Publish(js jetstream.JetStream, subject string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := js.Publish(ctx, subject, data)
+	return err
+}
+
+// PublishMsg is Publish for messages that carry headers
+func PublishMsg(js jetstream.JetStream, msg *nats.Msg) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := js.PublishMsg(ctx, msg)
+	return err
+}
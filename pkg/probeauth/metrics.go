@@ -0,0 +1,15 @@
+package probeauth
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var resultsRejected = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "probe_results_rejected_total",
+		Help: "Total number of check results rejected, by reason (unsigned, invalid_signature, stale, unregistered_worker, worker_mismatch, account_mismatch)",
+	},
+	[]string{"reason"},
+)
+
+func init() {
+	prometheus.MustRegister(resultsRejected)
+}
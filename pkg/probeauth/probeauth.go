@@ -0,0 +1,91 @@
+// Package probeauth signs the check results probe workers publish and
+// verifies them against the keys workers register with, so that only
+// registered workers can report results.
+//
+// Each worker generates an Ed25519 key pair on start and registers the public
+// key with the probe manager, which keeps it in the worker registry bucket.
+// Results carry the worker's ID, the time they were signed and a signature
+// over both and the payload in their headers.
+package probeauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// WorkersBucket is the NATS KV bucket holding the worker registry, keyed by
+// worker ID
+const WorkersBucket = "probe_workers"
+
+// Headers carried by signed results
+const (
+	HeaderWorkerID  = "Probe-Worker-Id"
+	HeaderSignedAt  = "Probe-Signed-At"
+	HeaderSignature = "Probe-Signature"
+)
+
+// OpenRegistry returns the worker registry bucket, creating it if needed so
+// that no service depends on another having started first
+func OpenRegistry(ctx context.Context, js jetstream.JetStream) (jetstream.KeyValue, error) {
+	kv, err := js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      WorkersBucket,
+		Description: "Probe worker registry",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workers bucket: %w", err)
+	}
+	return kv, nil
+}
+
+// Signer signs messages on behalf of one worker
+type Signer struct {
+	workerID string
+	key      ed25519.PrivateKey
+}
+
+// NewSigner generates a key pair for the worker
+func NewSigner(workerID string) (*Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return &Signer{workerID: workerID, key: key}, nil
+}
+
+// PublicKey returns the key to register with, base64 encoded
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Sign returns a message for the subject carrying data and its signature
+func (s *Signer) Sign(subject string, data []byte) *nats.Msg {
+	signedAt := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	msg.Header.Set(HeaderWorkerID, s.workerID)
+	msg.Header.Set(HeaderSignedAt, signedAt)
+	msg.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(
+		ed25519.Sign(s.key, signedContent(s.workerID, signedAt, data))))
+	return msg
+}
+
+// signedContent is what the signature covers. Binding the worker ID and
+// signing time stops a signature being moved to another worker's result or
+// replayed much later.
+func signedContent(workerID, signedAt string, data []byte) []byte {
+	content := make([]byte, 0, len(workerID)+len(signedAt)+len(data)+2)
+	content = append(content, workerID...)
+	content = append(content, '\n')
+	content = append(content, signedAt...)
+	content = append(content, '\n')
+	return append(content, data...)
+}
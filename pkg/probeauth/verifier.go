@@ -0,0 +1,261 @@
+package probeauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// Reasons a result is rejected
+const (
+	ReasonUnsigned           = "unsigned"
+	ReasonInvalidSignature   = "invalid_signature"
+	ReasonStale              = "stale"
+	ReasonUnregisteredWorker = "unregistered_worker"
+	ReasonWorkerMismatch     = "worker_mismatch"
+	ReasonAccountMismatch    = "account_mismatch"
+)
+
+// DefaultMaxAge is how far a result's signing time may be from the time it
+// reached its stream
+const DefaultMaxAge = 5 * time.Minute
+
+// RejectedError is returned for results that must not be trusted. Retrying
+// them cannot help.
+type RejectedError struct {
+	Reason string
+	Err    error
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("result rejected (%s): %v", e.Reason, e.Err)
+}
+
+func (e *RejectedError) Unwrap() error { return e.Err }
+
+// Reject counts a rejected result and returns the error describing it.
+// Services use it for checks of their own, such as monitor ownership.
+func Reject(reason string, err error) error {
+	resultsRejected.WithLabelValues(reason).Inc()
+	return &RejectedError{Reason: reason, Err: err}
+}
+
+// IsRejected reports whether err rejects the result outright, as opposed to
+// a failure to verify it that may go away on retry
+func IsRejected(err error) bool {
+	var rejected *RejectedError
+	return errors.As(err, &rejected)
+}
+
+// Worker is the part of a registry entry needed to verify its results
+type Worker struct {
+	ID        string `json:"id"`
+	PublicKey string `json:"public_key"`
+	// AccountID is set for private agents, whose results may only be for
+	// that account's monitors
+	AccountID string `json:"account_id,omitempty"`
+	// RetiredAt is set once the worker has left. Its entry stays in the
+	// registry for a while so that results it published before leaving can
+	// still be verified when they are redelivered.
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+
+	key ed25519.PublicKey
+}
+
+// Verifier checks signed results against the keys in the worker registry,
+// which it follows as workers come and go
+type Verifier struct {
+	logger *zap.Logger
+	kv     jetstream.KeyValue
+	maxAge time.Duration
+
+	workers map[string]*Worker
+	mu      sync.RWMutex
+}
+
+// NewVerifier loads the worker registry and keeps following it. Results
+// signed more than maxAge before or after they reached their stream are
+// rejected as stale.
+func NewVerifier(ctx context.Context, js jetstream.JetStream, logger *zap.Logger, maxAge time.Duration) (*Verifier, error) {
+	kv, err := OpenRegistry(ctx, js)
+	if err != nil {
+		return nil, err
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	v := &Verifier{
+		logger:  logger,
+		kv:      kv,
+		maxAge:  maxAge,
+		workers: make(map[string]*Worker),
+	}
+
+	// The watch outlives ctx, which only bounds loading the current entries
+	watcher, err := kv.WatchAll(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch workers bucket: %w", err)
+	}
+	for {
+		select {
+		case entry := <-watcher.Updates():
+			// A nil entry marks the end of the current values
+			if entry == nil {
+				go v.follow(watcher)
+				return v, nil
+			}
+			v.apply(entry)
+		case <-ctx.Done():
+			watcher.Stop()
+			return nil, fmt.Errorf("failed to load workers bucket: %w", ctx.Err())
+		}
+	}
+}
+
+// follow applies registry changes until the watch ends
+func (v *Verifier) follow(watcher jetstream.KeyWatcher) {
+	for entry := range watcher.Updates() {
+		if entry != nil {
+			v.apply(entry)
+		}
+	}
+	v.logger.Warn("stopped following worker registry")
+}
+
+// apply records a registry change
+func (v *Verifier) apply(entry jetstream.KeyValueEntry) {
+	if entry.Operation() != jetstream.KeyValuePut {
+		v.mu.Lock()
+		delete(v.workers, entry.Key())
+		v.mu.Unlock()
+		return
+	}
+
+	worker, err := parseWorker(entry.Value())
+	if err != nil {
+		v.logger.Warn("ignoring worker without a usable key",
+			zap.String("worker_id", entry.Key()),
+			zap.Error(err))
+		v.mu.Lock()
+		delete(v.workers, entry.Key())
+		v.mu.Unlock()
+		return
+	}
+
+	v.mu.Lock()
+	v.workers[entry.Key()] = worker
+	v.mu.Unlock()
+}
+
+func parseWorker(data []byte) (*Worker, error) {
+	var worker Worker
+	if err := json.Unmarshal(data, &worker); err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(worker.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key")
+	}
+	worker.key = key
+	return &worker, nil
+}
+
+// lookup returns the registered worker. A worker that registered moments ago
+// may not have reached the watch yet, so the bucket is asked directly before
+// giving up on it.
+func (v *Verifier) lookup(id string) (*Worker, error) {
+	v.mu.RLock()
+	worker, exists := v.workers[id]
+	v.mu.RUnlock()
+	if exists {
+		return worker, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	entry, err := v.kv.Get(ctx, id)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up worker %s: %w", id, err)
+	}
+	worker, err = parseWorker(entry.Value())
+	if err != nil {
+		return nil, nil
+	}
+	return worker, nil
+}
+
+// Verify checks that the result was signed by a registered worker, around
+// the time it reached the stream, and that the worker it names is the one
+// that signed it. It returns the signing worker. Errors that are not
+// RejectedError are failures to verify and worth retrying.
+func (v *Verifier) Verify(msg jetstream.Msg) (*Worker, error) {
+	// Age is measured against when the result was stored rather than now, so
+	// that results redelivered after an outage are not mistaken for replays
+	received := time.Now()
+	if meta, err := msg.Metadata(); err == nil {
+		received = meta.Timestamp
+	}
+	return v.verify(msg.Headers(), msg.Data(), received)
+}
+
+// VerifyMsg is Verify for results seen on a plain subscription, which are
+// checked against the time they arrive
+func (v *Verifier) VerifyMsg(msg *nats.Msg) (*Worker, error) {
+	return v.verify(msg.Header, msg.Data, time.Now())
+}
+
+func (v *Verifier) verify(headers nats.Header, data []byte, received time.Time) (*Worker, error) {
+	workerID := headers.Get(HeaderWorkerID)
+	signedAt := headers.Get(HeaderSignedAt)
+	signature := headers.Get(HeaderSignature)
+	if workerID == "" || signedAt == "" || signature == "" {
+		return nil, Reject(ReasonUnsigned, errors.New("missing signature headers"))
+	}
+
+	worker, err := v.lookup(workerID)
+	if err != nil {
+		return nil, err
+	}
+	if worker == nil {
+		return nil, Reject(ReasonUnregisteredWorker, fmt.Errorf("worker %s is not registered", workerID))
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !ed25519.Verify(worker.key, signedContent(workerID, signedAt, data), sig) {
+		return nil, Reject(ReasonInvalidSignature, fmt.Errorf("signature does not match worker %s", workerID))
+	}
+
+	millis, err := strconv.ParseInt(signedAt, 10, 64)
+	if err != nil {
+		return nil, Reject(ReasonInvalidSignature, fmt.Errorf("invalid signing time %q", signedAt))
+	}
+	if worker.RetiredAt != nil && received.After(*worker.RetiredAt) {
+		return nil, Reject(ReasonUnregisteredWorker, fmt.Errorf("worker %s retired before the result was received", workerID))
+	}
+	if age := received.Sub(time.UnixMilli(millis)); age > v.maxAge || age < -v.maxAge {
+		return nil, Reject(ReasonStale, fmt.Errorf("signed %s before it was received", age.Round(time.Second)))
+	}
+
+	var payload struct {
+		WorkerID string `json:"worker_id"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || payload.WorkerID != workerID {
+		return nil, Reject(ReasonWorkerMismatch, fmt.Errorf("result names worker %q but was signed by %s", payload.WorkerID, workerID))
+	}
+
+	return worker, nil
+}
@@ -0,0 +1,308 @@
+package probeauth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// fakeKV is a worker registry bucket that only answers Get
+type fakeKV struct {
+	jetstream.KeyValue
+	entries map[string][]byte
+	err     error
+}
+
+func (kv *fakeKV) Get(_ context.Context, key string) (jetstream.KeyValueEntry, error) {
+	if kv.err != nil {
+		return nil, kv.err
+	}
+	value, ok := kv.entries[key]
+	if !ok {
+		return nil, jetstream.ErrKeyNotFound
+	}
+	return &fakeEntry{key: key, value: value}, nil
+}
+
+type fakeEntry struct {
+	jetstream.KeyValueEntry
+	key   string
+	value []byte
+}
+
+func (e *fakeEntry) Key() string   { return e.key }
+func (e *fakeEntry) Value() []byte { return e.value }
+
+// streamMsg is a result as a consumer of the results stream sees it, stored
+// at a given time
+type streamMsg struct {
+	jetstream.Msg
+	msg    *nats.Msg
+	stored time.Time
+}
+
+func (m *streamMsg) Headers() nats.Header { return m.msg.Header }
+func (m *streamMsg) Data() []byte         { return m.msg.Data }
+func (m *streamMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{Timestamp: m.stored, NumDelivered: 1}, nil
+}
+
+// registryEntry is the registry bucket value for the signer's worker
+func registryEntry(t *testing.T, s *Signer, retiredAt *time.Time) []byte {
+	t.Helper()
+	data, err := json.Marshal(Worker{ID: s.workerID, PublicKey: s.PublicKey(), RetiredAt: retiredAt})
+	if err != nil {
+		t.Fatalf("failed to marshal worker: %v", err)
+	}
+	return data
+}
+
+// newTestVerifier returns a verifier that has seen the given workers through
+// its watch, and whose bucket holds kv
+func newTestVerifier(t *testing.T, kv *fakeKV, workers ...[]byte) *Verifier {
+	t.Helper()
+	if kv == nil {
+		kv = &fakeKV{}
+	}
+	v := &Verifier{
+		logger:  zap.NewNop(),
+		kv:      kv,
+		maxAge:  DefaultMaxAge,
+		workers: make(map[string]*Worker),
+	}
+	for _, data := range workers {
+		worker, err := parseWorker(data)
+		if err != nil {
+			t.Fatalf("invalid test worker: %v", err)
+		}
+		v.workers[worker.ID] = worker
+	}
+	return v
+}
+
+func newTestSigner(t *testing.T, workerID string) *Signer {
+	t.Helper()
+	s, err := NewSigner(workerID)
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	return s
+}
+
+// signAt signs data as the signer would have at the given time
+func signAt(s *Signer, data []byte, at time.Time) *nats.Msg {
+	signedAt := strconv.FormatInt(at.UnixMilli(), 10)
+	msg := nats.NewMsg("probes.check.result")
+	msg.Data = data
+	msg.Header.Set(HeaderWorkerID, s.workerID)
+	msg.Header.Set(HeaderSignedAt, signedAt)
+	msg.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(
+		ed25519.Sign(s.key, signedContent(s.workerID, signedAt, data))))
+	return msg
+}
+
+// result is a check result payload naming the worker
+func result(workerID string) []byte {
+	return []byte(`{"monitor_id":"m1","worker_id":"` + workerID + `","success":true}`)
+}
+
+// wantRejected fails unless err rejects the result for reason
+func wantRejected(t *testing.T, err error, reason string) {
+	t.Helper()
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("error = %v, want a rejection for %s", err, reason)
+	}
+	if rejected.Reason != reason {
+		t.Errorf("rejected for %s (%v), want %s", rejected.Reason, rejected.Err, reason)
+	}
+	if !IsRejected(err) {
+		t.Error("IsRejected() = false for a rejection")
+	}
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	signer := newTestSigner(t, "worker-a")
+	v := newTestVerifier(t, nil, registryEntry(t, signer, nil))
+
+	msg := signer.Sign("probes.check.result", result("worker-a"))
+	worker, err := v.VerifyMsg(msg)
+	if err != nil {
+		t.Fatalf("VerifyMsg() error = %v", err)
+	}
+	if worker.ID != "worker-a" {
+		t.Errorf("VerifyMsg() worker = %s, want worker-a", worker.ID)
+	}
+
+	worker, err = v.Verify(&streamMsg{msg: msg, stored: time.Now()})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if worker.ID != "worker-a" {
+		t.Errorf("Verify() worker = %s, want worker-a", worker.ID)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	signer := newTestSigner(t, "worker-a")
+	other := newTestSigner(t, "worker-b")
+	v := newTestVerifier(t, nil, registryEntry(t, signer, nil), registryEntry(t, other, nil))
+
+	t.Run("payload", func(t *testing.T) {
+		msg := signer.Sign("probes.check.result", result("worker-a"))
+		msg.Data = []byte(`{"monitor_id":"m1","worker_id":"worker-a","success":false}`)
+		_, err := v.VerifyMsg(msg)
+		wantRejected(t, err, ReasonInvalidSignature)
+	})
+
+	t.Run("signing time", func(t *testing.T) {
+		msg := signer.Sign("probes.check.result", result("worker-a"))
+		signedAt, _ := strconv.ParseInt(msg.Header.Get(HeaderSignedAt), 10, 64)
+		msg.Header.Set(HeaderSignedAt, strconv.FormatInt(signedAt+1000, 10))
+		_, err := v.VerifyMsg(msg)
+		wantRejected(t, err, ReasonInvalidSignature)
+	})
+
+	t.Run("signature moved to another worker", func(t *testing.T) {
+		msg := signer.Sign("probes.check.result", result("worker-b"))
+		msg.Header.Set(HeaderWorkerID, "worker-b")
+		_, err := v.VerifyMsg(msg)
+		wantRejected(t, err, ReasonInvalidSignature)
+	})
+
+	t.Run("garbled signature", func(t *testing.T) {
+		msg := signer.Sign("probes.check.result", result("worker-a"))
+		msg.Header.Set(HeaderSignature, "not base64!")
+		_, err := v.VerifyMsg(msg)
+		wantRejected(t, err, ReasonInvalidSignature)
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		msg := nats.NewMsg("probes.check.result")
+		msg.Data = result("worker-a")
+		_, err := v.VerifyMsg(msg)
+		wantRejected(t, err, ReasonUnsigned)
+	})
+}
+
+func TestVerifyStale(t *testing.T) {
+	signer := newTestSigner(t, "worker-a")
+	v := newTestVerifier(t, nil, registryEntry(t, signer, nil))
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		signedAt time.Time
+		stored   time.Time
+		reason   string
+	}{
+		{"fresh", now, now, ""},
+		{"just inside the window", now.Add(-DefaultMaxAge + time.Second), now, ""},
+		{"replayed later", now.Add(-DefaultMaxAge - time.Second), now, ReasonStale},
+		{"signed in the future", now.Add(DefaultMaxAge + time.Second), now, ReasonStale},
+		// Age is measured against when the stream stored the result, so a
+		// redelivery long after is still accepted
+		{"redelivered after an outage", now.Add(-2 * time.Hour), now.Add(-2 * time.Hour), ""},
+		{"stored long after signing", now.Add(-2 * time.Hour), now.Add(-time.Hour), ReasonStale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := signAt(signer, result("worker-a"), tt.signedAt)
+			_, err := v.Verify(&streamMsg{msg: msg, stored: tt.stored})
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %v", err)
+				}
+				return
+			}
+			wantRejected(t, err, tt.reason)
+		})
+	}
+
+	// Plain subscriptions see results as they are published
+	_, err := v.VerifyMsg(signAt(signer, result("worker-a"), now.Add(-DefaultMaxAge-time.Minute)))
+	wantRejected(t, err, ReasonStale)
+}
+
+func TestVerifyWorkerMismatch(t *testing.T) {
+	signer := newTestSigner(t, "worker-a")
+	v := newTestVerifier(t, nil, registryEntry(t, signer, nil))
+
+	for name, data := range map[string][]byte{
+		"names another worker": result("worker-b"),
+		"names no worker":      []byte(`{"monitor_id":"m1","success":true}`),
+		"not JSON":             []byte("worker-a"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.VerifyMsg(signer.Sign("probes.check.result", data))
+			wantRejected(t, err, ReasonWorkerMismatch)
+		})
+	}
+}
+
+func TestVerifyRegistry(t *testing.T) {
+	signer := newTestSigner(t, "worker-a")
+
+	t.Run("unregistered", func(t *testing.T) {
+		v := newTestVerifier(t, nil)
+		_, err := v.VerifyMsg(signer.Sign("probes.check.result", result("worker-a")))
+		wantRejected(t, err, ReasonUnregisteredWorker)
+	})
+
+	t.Run("registered but not yet watched", func(t *testing.T) {
+		kv := &fakeKV{entries: map[string][]byte{"worker-a": registryEntry(t, signer, nil)}}
+		v := newTestVerifier(t, kv)
+		if _, err := v.VerifyMsg(signer.Sign("probes.check.result", result("worker-a"))); err != nil {
+			t.Errorf("VerifyMsg() error = %v", err)
+		}
+	})
+
+	t.Run("registered with another key", func(t *testing.T) {
+		impostor := newTestSigner(t, "worker-a")
+		v := newTestVerifier(t, nil, registryEntry(t, signer, nil))
+		_, err := v.VerifyMsg(impostor.Sign("probes.check.result", result("worker-a")))
+		wantRejected(t, err, ReasonInvalidSignature)
+	})
+
+	t.Run("registry unavailable", func(t *testing.T) {
+		v := newTestVerifier(t, &fakeKV{err: errors.New("timeout")})
+		_, err := v.VerifyMsg(signer.Sign("probes.check.result", result("worker-a")))
+		if err == nil || IsRejected(err) {
+			t.Errorf("VerifyMsg() error = %v, want a failure worth retrying", err)
+		}
+	})
+}
+
+func TestVerifyRetiredWorker(t *testing.T) {
+	signer := newTestSigner(t, "worker-a")
+	now := time.Now()
+	retiredAt := now.Add(-time.Hour)
+	v := newTestVerifier(t, nil, registryEntry(t, signer, &retiredAt))
+
+	// A result published before the worker left is redelivered within
+	// ResultRetention, long after it retired
+	published := retiredAt.Add(-10 * time.Minute)
+	msg := signAt(signer, result("worker-a"), published)
+	if _, err := v.Verify(&streamMsg{msg: msg, stored: published}); err != nil {
+		t.Errorf("Verify() of a result stored before retirement error = %v", err)
+	}
+
+	// Anything stored after it left is refused
+	late := retiredAt.Add(time.Minute)
+	msg = signAt(signer, result("worker-a"), late)
+	_, err := v.Verify(&streamMsg{msg: msg, stored: late})
+	wantRejected(t, err, ReasonUnregisteredWorker)
+
+	_, err = v.VerifyMsg(signer.Sign("probes.check.result", result("worker-a")))
+	wantRejected(t, err, ReasonUnregisteredWorker)
+}
@@ -0,0 +1,61 @@
+package main
+
+import "time"
+
+// locationVerdictTTL is how long a location's last result counts towards a
+// rule, so that a location removed from a monitor stops holding its incident
+// open
+const locationVerdictTTL = time.Hour
+
+// locationVerdict is whether the latest result from a location met a rule's
+// condition
+type locationVerdict struct {
+	alerting bool
+	at       time.Time
+}
+
+// locationsAlerting records the verdict of a result taken from location and
+// reports whether enough of the monitor's locations now meet the rule's
+// condition to alert. Each location's verdict is kept separately, so that a
+// failing region cannot be cleared by a healthy one reporting in between.
+func (am *AlertManager) locationsAlerting(ruleID, location string, alerting bool, at time.Time) bool {
+	am.locationsLock.Lock()
+	defer am.locationsLock.Unlock()
+
+	verdicts, ok := am.locations[ruleID]
+	if !ok {
+		verdicts = make(map[string]locationVerdict)
+		am.locations[ruleID] = verdicts
+	}
+	// A redelivered older result does not override a newer verdict
+	if previous, ok := verdicts[location]; !ok || !at.Before(previous.at) {
+		verdicts[location] = locationVerdict{alerting: alerting, at: at}
+	}
+
+	var failing, reporting int
+	for loc, verdict := range verdicts {
+		if at.Sub(verdict.at) > locationVerdictTTL {
+			delete(verdicts, loc)
+			continue
+		}
+		reporting++
+		if verdict.alerting {
+			failing++
+		}
+	}
+	return am.quorumReached(failing, reporting)
+}
+
+// forgetLocations drops the location verdicts kept for a rule
+func (am *AlertManager) forgetLocations(ruleID string) {
+	am.locationsLock.Lock()
+	defer am.locationsLock.Unlock()
+	delete(am.locations, ruleID)
+}
+
+// quorumReached reports whether failing of the reporting locations are enough
+// to alert. Monitors checked from fewer locations than the quorum alert when
+// all of them fail.
+func (am *AlertManager) quorumReached(failing, reporting int) bool {
+	return failing > 0 && failing >= min(am.minFailingLocations, reporting)
+}
@@ -4,25 +4,57 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jjkirkpatrick/monitoring/internal/database"
+	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	"github.com/jjkirkpatrick/monitoring/pkg/certcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
+	"github.com/jjkirkpatrick/monitoring/pkg/probeauth"
 	_ "github.com/lib/pq"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.uber.org/zap"
 )
 
 type AlertManager struct {
 	logger    *zap.Logger
 	natsConn  *nats.Conn
+	js        jetstream.JetStream
 	db        *sql.DB
 	cache     map[string]*AlertRule
 	cacheLock sync.RWMutex
+
+	// monitorDB holds the monitors that results from private agents are
+	// checked to belong to the agent's account
+	monitorDB *database.DB
+
+	// maintenance maps monitors reported to be in maintenance to the report
+	maintenance     map[string]maintenanceEntry
+	maintenanceLock sync.RWMutex
+
+	// locations holds, per rule, the verdict of each location's latest
+	// result; a rule alerts once minFailingLocations of them meet it
+	locations           map[string]map[string]locationVerdict
+	locationsLock       sync.Mutex
+	minFailingLocations int
+
+	// verifier rejects results not signed by a registered worker, so that
+	// nothing else can raise or resolve alerts
+	verifier *probeauth.Verifier
 }
 
 type AlertRule struct {
@@ -51,13 +83,20 @@ type Incident struct {
 	UpdateTime  time.Time `json:"update_time"`
 }
 
-func NewAlertManager(logger *zap.Logger, natsConn *nats.Conn, db *sql.DB) *AlertManager {
+func NewAlertManager(logger *zap.Logger, natsConn *nats.Conn, js jetstream.JetStream, db *sql.DB, monitorDB *database.DB, verifier *probeauth.Verifier, minFailingLocations int) *AlertManager {
 	return &AlertManager{
 		logger:    logger,
 		natsConn:  natsConn,
+		js:        js,
 		db:        db,
+		monitorDB: monitorDB,
 		cache:     make(map[string]*AlertRule),
 		cacheLock: sync.RWMutex{},
+
+		maintenance:         make(map[string]maintenanceEntry),
+		locations:           make(map[string]map[string]locationVerdict),
+		minFailingLocations: minFailingLocations,
+		verifier:            verifier,
 	}
 }
 
@@ -72,8 +111,19 @@ func (am *AlertManager) Start() error {
 		return err
 	}
 
-	// Subscribe to check results
-	if _, err := am.natsConn.Subscribe("probes.check.result", am.handleCheckResult); err != nil {
+	// Consume check results durably so that no result goes unevaluated
+	// across a restart
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := pipeline.ConsumeMsg(ctx, am.js, am.logger, pipeline.ConsumerConfig{
+		Stream:  pipeline.StreamCheckResults,
+		Durable: "alert-manager",
+	}, am.handleCheckResult); err != nil {
+		return err
+	}
+
+	// Subscribe to maintenance transitions from the scheduler
+	if _, err := am.natsConn.Subscribe("monitors.maintenance", am.handleMaintenanceEvent); err != nil {
 		return err
 	}
 
@@ -150,52 +200,149 @@ func (am *AlertManager) loadAlertRules() error {
 	return rows.Err()
 }
 
-func (am *AlertManager) handleCheckResult(msg *nats.Msg) {
-	var result struct {
-		MonitorID string    `json:"monitor_id"`
-		Success   bool      `json:"success"`
-		Duration  int64     `json:"duration"`
-		Timestamp time.Time `json:"timestamp"`
+// handleCheckResult evaluates the monitor's rules against a result. If any
+// rule fails to evaluate the result is redelivered; rules that already
+// alerted on it are held back by their alert frequency.
+func (am *AlertManager) handleCheckResult(msg jetstream.Msg) error {
+	worker, err := am.verifier.Verify(msg)
+	if err != nil {
+		return verifyError(err)
 	}
-	if err := json.Unmarshal(msg.Data, &result); err != nil {
-		am.logger.Error("failed to unmarshal check result", zap.Error(err))
-		return
+
+	var result CheckResult
+	if err := json.Unmarshal(msg.Data(), &result); err != nil {
+		return pipeline.Permanent(fmt.Errorf("failed to unmarshal check result: %w", err))
+	}
+
+	// Private agents may only report on their own account's monitors
+	if worker.AccountID != "" {
+		if err := am.checkOwner(result.MonitorID, worker.AccountID); err != nil {
+			return verifyError(err)
+		}
+	}
+
+	// Failures during maintenance are expected and must not page anyone
+	if result.Maintenance || am.inMaintenance(result.MonitorID) {
+		am.logger.Debug("suppressing alerts for monitor in maintenance",
+			zap.String("monitor_id", result.MonitorID))
+		return nil
 	}
 
 	// Evaluate applicable rules
 	am.cacheLock.RLock()
 	defer am.cacheLock.RUnlock()
 
+	var errs []error
 	for _, rule := range am.cache {
 		if rule.MonitorID == result.MonitorID {
 			if err := am.evaluateRule(rule, result); err != nil {
-				am.logger.Error("failed to evaluate rule",
-					zap.Error(err),
-					zap.String("rule_id", rule.ID))
+				errs = append(errs, fmt.Errorf("failed to evaluate rule %s: %w", rule.ID, err))
 			}
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// checkOwner rejects the result unless the monitor belongs to the account
+func (am *AlertManager) checkOwner(monitorID, accountID string) error {
+	monitor, err := uuid.Parse(monitorID)
+	if err != nil {
+		return probeauth.Reject(probeauth.ReasonAccountMismatch, fmt.Errorf("invalid monitor id %q", monitorID))
+	}
+	account, err := uuid.Parse(accountID)
+	if err != nil {
+		return probeauth.Reject(probeauth.ReasonAccountMismatch, fmt.Errorf("invalid account id %q", accountID))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = am.monitorDB.Queries.GetMonitor(ctx, sqlc.GetMonitorParams{ID: monitor, UserID: account})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return probeauth.Reject(probeauth.ReasonAccountMismatch,
+			fmt.Errorf("monitor %s does not belong to account %s", monitorID, accountID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up monitor %s: %w", monitorID, err)
+	}
+	return nil
+}
+
+// verifyError sends rejected results to the dead-letter stream and retries
+// failures to verify them
+func verifyError(err error) error {
+	if probeauth.IsRejected(err) {
+		return pipeline.Permanent(err)
+	}
+	return err
+}
+
+// defaultExpiryDays is how many days ahead ssl_expiry and dnssec_expiry rules
+// without a threshold warn of an expiring certificate or signature
+const defaultExpiryDays = 30
+
+// expiresSoon reports whether expiry falls within the rule's threshold, in
+// days, of now
+func (rule *AlertRule) expiresSoon(expiry, now time.Time) bool {
+	days := rule.ThresholdValue
+	if days <= 0 {
+		days = defaultExpiryDays
+	}
+	return expiry.Sub(now) < time.Duration(days*24)*time.Hour
 }
 
 type CheckResult struct {
-	MonitorID string    `json:"monitor_id"`
-	Success   bool      `json:"success"`
-	Duration  int64     `json:"duration"`
-	Timestamp time.Time `json:"timestamp"`
+	MonitorID   string            `json:"monitor_id"`
+	Success     bool              `json:"success"`
+	Duration    int64             `json:"duration"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Maintenance bool              `json:"maintenance"`
+	Location    string            `json:"location,omitempty"`
+	Details     map[string]string `json:"details"`
 }
 
-func (am *AlertManager) evaluateRule(rule *AlertRule, result CheckResult) error {
-	// Check if it's time to alert based on frequency
-	if time.Since(rule.LastAlertTime) < rule.AlertFrequency {
-		return nil
+// failedBodyMatch reports whether the result failed a response body
+// assertion in one of modes
+func (r CheckResult) failedBodyMatch(modes ...string) bool {
+	if r.Success || r.Details[httpcheck.DetailAssertion] != httpcheck.AssertionBody {
+		return false
 	}
+	return slices.Contains(modes, r.Details[httpcheck.DetailAssertionMode])
+}
 
+func (am *AlertManager) evaluateRule(rule *AlertRule, result CheckResult) error {
 	var shouldAlert bool
 	switch rule.Condition {
 	case "status == error":
 		shouldAlert = !result.Success
 	case "response_time > threshold":
 		shouldAlert = float64(result.Duration) > rule.ThresholdValue
+	case "keyword":
+		shouldAlert = result.failedBodyMatch(httpcheck.MatchContains, httpcheck.MatchNotContains)
+	case "pattern":
+		shouldAlert = result.failedBodyMatch(httpcheck.MatchRegex, httpcheck.MatchNotRegex)
+	case "ssl_expiry":
+		// Results without a certificate, such as failed connections, say
+		// nothing about when it expires
+		expiry, ok := certcheck.Expiry(result.Details)
+		if !ok {
+			return nil
+		}
+		shouldAlert = rule.expiresSoon(expiry, result.Timestamp)
+	case "dnssec_expiry":
+		// Only DNSSEC checks whose chain validated report signature expiry
+		expiry, ok := dnscheck.SignatureExpiry(result.Details)
+		if !ok {
+			return nil
+		}
+		shouldAlert = rule.expiresSoon(expiry, result.Timestamp)
+	}
+
+	// The result only speaks for its own location
+	shouldAlert = am.locationsAlerting(rule.ID, result.Location, shouldAlert, result.Timestamp)
+
+	// Check if it's time to alert based on frequency
+	if time.Since(rule.LastAlertTime) < rule.AlertFrequency {
+		return nil
 	}
 
 	if shouldAlert {
@@ -224,8 +371,8 @@ func (am *AlertManager) evaluateRule(rule *AlertRule, result CheckResult) error
 			"timestamp":   result.Timestamp,
 			"message":     rule.Name,
 		})
-		if err := am.natsConn.Publish("notifications.send", notification); err != nil {
-			am.logger.Error("failed to publish notification", zap.Error(err))
+		if err := pipeline.Publish(am.js, pipeline.SubjectNotificationsSend, notification); err != nil {
+			return fmt.Errorf("failed to publish notification: %w", err)
 		}
 
 		rule.LastAlertTime = result.Timestamp
@@ -285,6 +432,7 @@ func (am *AlertManager) handleRuleUpdate(msg *nats.Msg) {
 
 	if rule.Status == "deleted" {
 		delete(am.cache, rule.ID)
+		am.forgetLocations(rule.ID)
 	} else {
 		am.cache[rule.ID] = &rule
 	}
@@ -297,6 +445,10 @@ func (am *AlertManager) runPeriodicEvaluation() {
 	for range ticker.C {
 		am.cacheLock.RLock()
 		for _, rule := range am.cache {
+			if am.inMaintenance(rule.MonitorID) {
+				continue
+			}
+
 			// Evaluate window-based conditions
 			if err := am.evaluateWindowedRule(rule); err != nil {
 				am.logger.Error("failed to evaluate windowed rule",
@@ -308,29 +460,50 @@ func (am *AlertManager) runPeriodicEvaluation() {
 	}
 }
 
+// evaluateWindowedRule judges the failure rate of each location over the
+// rule's window, and alerts when enough locations exceed the threshold
 func (am *AlertManager) evaluateWindowedRule(rule *AlertRule) error {
 	// Query aggregated data for the window
-	var failureCount, totalCount int
-	err := am.db.QueryRow(`
+	rows, err := am.db.Query(`
 		SELECT
 			COUNT(*) FILTER (WHERE success = false) as failure_count,
 			COUNT(*) as total_count
 		FROM check_results
 		WHERE
 			monitor_id = $1 AND
-			timestamp > NOW() - $2::interval`,
+			timestamp > NOW() - $2::interval AND
+			NOT maintenance
+		GROUP BY location`,
 		rule.MonitorID, rule.WindowDuration,
-	).Scan(&failureCount, &totalCount)
+	)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
+
+	var failing, reporting int
+	var failureRate float64
+	for rows.Next() {
+		var failureCount, totalCount int
+		if err := rows.Scan(&failureCount, &totalCount); err != nil {
+			return err
+		}
+		reporting++
+		rate := float64(failureCount) / float64(totalCount) * 100
+		if rate > rule.ThresholdValue {
+			failing++
+			failureRate = max(failureRate, rate)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-	if totalCount == 0 {
+	if reporting == 0 {
 		return nil
 	}
 
-	failureRate := float64(failureCount) / float64(totalCount) * 100
-	if failureRate > rule.ThresholdValue {
+	if am.quorumReached(failing, reporting) {
 		// Create incident and send notification
 		if !rule.IncidentID.Valid {
 			incidentID, err := am.createIncident(rule.ID, rule.MonitorID, time.Now())
@@ -346,10 +519,11 @@ func (am *AlertManager) evaluateWindowedRule(rule *AlertRule) error {
 				"incident_id": incidentID,
 				"channels":    rule.Channels,
 				"timestamp":   time.Now(),
-				"message":     fmt.Sprintf("%s: Failure rate %.2f%% exceeds threshold %.2f%%", rule.Name, failureRate, rule.ThresholdValue),
+				"message": fmt.Sprintf("%s: Failure rate %.2f%% exceeds threshold %.2f%% in %d of %d locations",
+					rule.Name, failureRate, rule.ThresholdValue, failing, reporting),
 			})
-			if err := am.natsConn.Publish("notifications.send", notification); err != nil {
-				am.logger.Error("failed to publish notification", zap.Error(err))
+			if err := pipeline.Publish(am.js, pipeline.SubjectNotificationsSend, notification); err != nil {
+				return fmt.Errorf("failed to publish notification: %w", err)
 			}
 		}
 	} else if rule.IncidentID.Valid {
@@ -382,6 +556,14 @@ func main() {
 	}
 	defer db.Close()
 
+	// Connect to the monitors database, which results from private agents
+	// are checked against
+	monitorDB, err := database.New(context.Background())
+	if err != nil {
+		logger.Fatal("failed to initialize monitors database", zap.Error(err))
+	}
+	defer monitorDB.Close()
+
 	// Test database connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -401,8 +583,47 @@ func main() {
 	}
 	defer nc.Close()
 
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Fatal("failed to create JetStream context", zap.Error(err))
+	}
+
+	streamCtx, streamCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pipeline.EnsureStreams(streamCtx, js)
+	streamCancel()
+	if err != nil {
+		logger.Fatal("failed to create pipeline streams", zap.Error(err))
+	}
+
+	// Only results signed by registered workers are evaluated
+	maxAge := probeauth.DefaultMaxAge
+	if v := os.Getenv("RESULT_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid RESULT_MAX_AGE", zap.Error(err))
+		}
+		maxAge = d
+	}
+	verifyCtx, verifyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	verifier, err := probeauth.NewVerifier(verifyCtx, js, logger, maxAge)
+	verifyCancel()
+	if err != nil {
+		logger.Fatal("failed to load probe worker registry", zap.Error(err))
+	}
+
+	// A rule alerts once this many of a monitor's locations meet it, or all
+	// of them if it has fewer
+	minFailingLocations := 1
+	if v := os.Getenv("ALERT_MIN_FAILING_LOCATIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatal("invalid ALERT_MIN_FAILING_LOCATIONS", zap.String("value", v))
+		}
+		minFailingLocations = n
+	}
+
 	// Create and start alert manager
-	manager := NewAlertManager(logger, nc, db)
+	manager := NewAlertManager(logger, nc, js, db, monitorDB, verifier, minFailingLocations)
 	if err := manager.Start(); err != nil {
 		logger.Fatal("failed to start alert manager", zap.Error(err))
 	}
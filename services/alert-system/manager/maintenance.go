@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// defaultMaintenanceExpiry bounds how long an event without an expiry keeps
+// alerts suppressed, twice the scheduler's default reconcile interval
+const defaultMaintenanceExpiry = 2 * time.Minute
+
+// MaintenanceEvent is published by the scheduler when a monitor enters or
+// leaves a maintenance window, and repeated while it stays in one. Alerts are
+// suppressed until ExpiresAt unless the event is repeated, so a lost leave
+// event only delays alerting.
+type MaintenanceEvent struct {
+	MonitorID     string    `json:"monitor_id"`
+	InMaintenance bool      `json:"in_maintenance"`
+	WindowID      string    `json:"window_id,omitempty"`
+	Action        string    `json:"action,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// maintenanceEntry is the window a monitor was last reported to be in and
+// when that report lapses
+type maintenanceEntry struct {
+	windowID  string
+	expiresAt time.Time
+}
+
+func (am *AlertManager) handleMaintenanceEvent(msg *nats.Msg) {
+	var event MaintenanceEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		am.logger.Error("failed to unmarshal maintenance event", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	am.maintenanceLock.Lock()
+	previous, ok := am.maintenance[event.MonitorID]
+	wasInMaintenance := ok && now.Before(previous.expiresAt)
+	if event.InMaintenance {
+		expiresAt := event.ExpiresAt
+		if expiresAt.IsZero() {
+			expiresAt = now.Add(defaultMaintenanceExpiry)
+		}
+		am.maintenance[event.MonitorID] = maintenanceEntry{windowID: event.WindowID, expiresAt: expiresAt}
+	} else {
+		delete(am.maintenance, event.MonitorID)
+	}
+	// Drop reports that lapsed without a leave event
+	for id, entry := range am.maintenance {
+		if !now.Before(entry.expiresAt) {
+			delete(am.maintenance, id)
+		}
+	}
+	_, inMaintenance := am.maintenance[event.MonitorID]
+	am.maintenanceLock.Unlock()
+
+	if wasInMaintenance != inMaintenance {
+		am.logger.Info("monitor maintenance changed",
+			zap.String("monitor_id", event.MonitorID),
+			zap.Bool("in_maintenance", inMaintenance),
+			zap.String("window_id", event.WindowID))
+	}
+}
+
+// inMaintenance reports whether alerts for the monitor are suppressed
+func (am *AlertManager) inMaintenance(monitorID string) bool {
+	am.maintenanceLock.RLock()
+	defer am.maintenanceLock.RUnlock()
+	entry, ok := am.maintenance[monitorID]
+	return ok && time.Now().Before(entry.expiresAt)
+}
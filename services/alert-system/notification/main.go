@@ -12,8 +12,10 @@ import (
 	"time"
 
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
 	_ "github.com/lib/pq"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -21,6 +23,7 @@ import (
 type NotificationService struct {
 	logger    *zap.Logger
 	natsConn  *nats.Conn
+	js        jetstream.JetStream
 	db        *sql.DB
 	redis     *redis.Client
 	providers map[string]NotificationProvider
@@ -74,10 +77,11 @@ func (p *SMSProvider) Send(n *Notification) error {
 	return nil
 }
 
-func NewNotificationService(logger *zap.Logger, natsConn *nats.Conn, db *sql.DB, redis *redis.Client) *NotificationService {
+func NewNotificationService(logger *zap.Logger, natsConn *nats.Conn, js jetstream.JetStream, db *sql.DB, redis *redis.Client) *NotificationService {
 	service := &NotificationService{
 		logger:    logger,
 		natsConn:  natsConn,
+		js:        js,
 		db:        db,
 		redis:     redis,
 		providers: make(map[string]NotificationProvider),
@@ -96,8 +100,14 @@ func (s *NotificationService) Start() error {
 		return err
 	}
 
-	// Subscribe to notification requests
-	if _, err := s.natsConn.Subscribe("notifications.send", s.handleNotification); err != nil {
+	// Consume notification requests durably so none are dropped while the
+	// service restarts
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := pipeline.Consume(ctx, s.js, s.logger, pipeline.ConsumerConfig{
+		Stream:  pipeline.StreamNotifications,
+		Durable: "notification-service",
+	}, s.handleNotification); err != nil {
 		return err
 	}
 
@@ -130,11 +140,13 @@ func (s *NotificationService) initDatabase() error {
 	return err
 }
 
-func (s *NotificationService) handleNotification(msg *nats.Msg) {
+// handleNotification stores a notification and starts sending it. The
+// message is acked once the notification is stored, after which the retry
+// worker is responsible for delivering it.
+func (s *NotificationService) handleNotification(data []byte) error {
 	var notification Notification
-	if err := json.Unmarshal(msg.Data, &notification); err != nil {
-		s.logger.Error("failed to unmarshal notification", zap.Error(err))
-		return
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return pipeline.Permanent(fmt.Errorf("failed to unmarshal notification: %w", err))
 	}
 
 	// Generate notification ID if not provided
@@ -148,14 +160,12 @@ func (s *NotificationService) handleNotification(msg *nats.Msg) {
 
 	// Store notification
 	if err := s.storeNotification(&notification); err != nil {
-		s.logger.Error("failed to store notification",
-			zap.Error(err),
-			zap.String("notification_id", notification.ID))
-		return
+		return fmt.Errorf("failed to store notification %s: %w", notification.ID, err)
 	}
 
 	// Process notification
 	go s.processNotification(&notification)
+	return nil
 }
 
 func (s *NotificationService) storeNotification(n *Notification) error {
@@ -349,8 +359,20 @@ func main() {
 	}
 	defer nc.Close()
 
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Fatal("failed to create JetStream context", zap.Error(err))
+	}
+
+	streamCtx, streamCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pipeline.EnsureStreams(streamCtx, js)
+	streamCancel()
+	if err != nil {
+		logger.Fatal("failed to create pipeline streams", zap.Error(err))
+	}
+
 	// Create and start notification service
-	service := NewNotificationService(logger, nc, db, redisClient)
+	service := NewNotificationService(logger, nc, js, db, redisClient)
 	if err := service.Start(); err != nil {
 		logger.Fatal("failed to start notification service", zap.Error(err))
 	}
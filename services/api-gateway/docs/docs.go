@@ -24,14 +24,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/alerts": {
+        "/admin/probe-workers": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get all alert configurations for the authenticated user",
+                "description": "Get every probe worker known to the probe manager, with its labels, status and last-seen time. Requires an admin token.",
                 "consumes": [
                     "application/json"
                 ],
@@ -39,14 +39,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "alerts"
+                    "admin"
                 ],
-                "summary": "List alert configurations",
+                "summary": "List probe workers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.ListAlertConfigsResponse"
+                            "$ref": "#/definitions/types.ListProbeWorkersResponse"
                         }
                     },
                     "401": {
@@ -58,63 +58,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    }
-                }
-            },
-            "post": {
-                "security": [
-                    {
-                        "Bearer": []
-                    }
-                ],
-                "description": "Create a new alert configuration for a monitor",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "alerts"
-                ],
-                "summary": "Create alert config",
-                "parameters": [
-                    {
-                        "description": "Alert config details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.CreateAlertConfigRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/types.GetAlertConfigResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -122,8 +67,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -134,14 +79,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/alerts/{id}": {
-            "get": {
+        "/admin/probe-workers/{id}/cordon": {
+            "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get details for a specific alert config by ID",
+                "description": "Stop assigning new checks to a probe worker. Checks it already has are left to finish. Requires an admin token.",
                 "consumes": [
                     "application/json"
                 ],
@@ -149,13 +94,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "alerts"
+                    "admin"
                 ],
-                "summary": "Get alert config details",
+                "summary": "Cordon probe worker",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Alert Config ID",
+                        "description": "Probe Worker ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -165,11 +110,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.GetAlertConfigResponse"
+                            "$ref": "#/definitions/types.ProbeWorkerResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -177,8 +122,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -186,8 +131,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -196,14 +150,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/probe-workers/{id}/drain": {
+            "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Update an existing alert configuration by ID",
+                "description": "Cordon a probe worker and move the checks it has not started to other workers. The worker can be stopped once its in_flight count reaches zero. Requires an admin token.",
                 "consumes": [
                     "application/json"
                 ],
@@ -211,36 +167,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "alerts"
+                    "admin"
                 ],
-                "summary": "Update alert config",
+                "summary": "Drain probe worker",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Alert Config ID",
+                        "description": "Probe Worker ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Alert config update request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.UpdateAlertConfigRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.GetAlertConfigResponse"
+                            "$ref": "#/definitions/types.ProbeWorkerResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -248,8 +195,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -257,8 +204,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -267,14 +223,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/probe-workers/{id}/uncordon": {
+            "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Delete an alert configuration by ID",
+                "description": "Return a cordoned or drained probe worker to rotation. Requires an admin token.",
                 "consumes": [
                     "application/json"
                 ],
@@ -282,24 +240,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "alerts"
+                    "admin"
                 ],
-                "summary": "Delete alert config",
+                "summary": "Uncordon probe worker",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Alert Config ID",
+                        "description": "Probe Worker ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ProbeWorkerResponse"
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -307,8 +268,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -316,34 +277,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/health": {
-            "get": {
-                "description": "Returns OK if the service is healthy",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "health"
-                ],
-                "summary": "Health check endpoint",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -354,14 +298,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/monitors": {
+        "/alerts": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get all monitors for the authenticated user",
+                "description": "Get all alert configurations for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
@@ -369,14 +313,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "monitors"
+                    "alerts"
                 ],
-                "summary": "List monitors",
+                "summary": "List alert configurations",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.ListMonitorsResponse"
+                            "$ref": "#/definitions/types.ListAlertConfigsResponse"
                         }
                     },
                     "401": {
@@ -405,7 +349,7 @@ const docTemplate = `{
                         "Bearer": []
                     }
                 ],
-                "description": "Creates a new monitor for the authenticated user",
+                "description": "Create a new alert configuration for a monitor",
                 "consumes": [
                     "application/json"
                 ],
@@ -413,17 +357,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "monitors"
+                    "alerts"
                 ],
-                "summary": "Create a new monitor",
+                "summary": "Create alert config",
                 "parameters": [
                     {
-                        "description": "Monitor to create",
-                        "name": "monitor",
+                        "description": "Alert config details",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.CreateMonitorRequest"
+                            "$ref": "#/definitions/types.CreateAlertConfigRequest"
                         }
                     }
                 ],
@@ -431,7 +375,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.GetMonitorResponse"
+                            "$ref": "#/definitions/types.GetAlertConfigResponse"
                         }
                     },
                     "400": {
@@ -464,14 +408,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/monitors/{id}": {
+        "/alerts/{id}": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get details for a specific monitor by ID",
+                "description": "Get details for a specific alert config by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -479,13 +423,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "monitors"
+                    "alerts"
                 ],
-                "summary": "Get monitor details",
+                "summary": "Get alert config details",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Monitor ID",
+                        "description": "Alert Config ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -495,7 +439,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.GetMonitorResponse"
+                            "$ref": "#/definitions/types.GetAlertConfigResponse"
                         }
                     },
                     "400": {
@@ -533,7 +477,7 @@ const docTemplate = `{
                         "Bearer": []
                     }
                 ],
-                "description": "Updates an existing monitor for the authenticated user",
+                "description": "Update an existing alert configuration by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -541,24 +485,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "monitors"
+                    "alerts"
                 ],
-                "summary": "Update a monitor",
+                "summary": "Update alert config",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Monitor ID",
+                        "description": "Alert Config ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Monitor to update",
-                        "name": "monitor",
+                        "description": "Alert config update request",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.UpdateMonitorRequest"
+                            "$ref": "#/definitions/types.UpdateAlertConfigRequest"
                         }
                     }
                 ],
@@ -566,7 +510,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.GetMonitorResponse"
+                            "$ref": "#/definitions/types.GetAlertConfigResponse"
                         }
                     },
                     "400": {
@@ -604,7 +548,7 @@ const docTemplate = `{
                         "Bearer": []
                     }
                 ],
-                "description": "Deletes a monitor for the authenticated user",
+                "description": "Delete an alert configuration by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -612,13 +556,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "monitors"
+                    "alerts"
                 ],
-                "summary": "Delete a monitor",
+                "summary": "Delete alert config",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Monitor ID",
+                        "description": "Alert Config ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -658,14 +602,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/settings": {
+        "/health": {
+            "get": {
+                "description": "Returns OK if the service is healthy",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Health check endpoint",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/maintenance-windows": {
             "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Get settings for the authenticated user",
+                "description": "Get all maintenance windows for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
@@ -673,14 +643,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "maintenance"
                 ],
-                "summary": "Get user settings",
+                "summary": "List maintenance windows",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.GetSettingsResponse"
+                            "$ref": "#/definitions/types.ListMaintenanceWindowsResponse"
                         }
                     },
                     "401": {
@@ -703,13 +673,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Updates all settings for the authenticated user",
+                "description": "Create a one-off or recurring maintenance window covering monitors by ID, group or tag",
                 "consumes": [
                     "application/json"
                 ],
@@ -717,25 +687,89 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "maintenance"
                 ],
-                "summary": "Update all user settings",
+                "summary": "Create maintenance window",
                 "parameters": [
                     {
-                        "description": "Settings to update",
-                        "name": "settings",
+                        "description": "Maintenance window details",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.UpdateSettingsRequest"
+                            "$ref": "#/definitions/types.CreateMaintenanceWindowRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.GetMaintenanceWindowResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/maintenance-windows/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get a maintenance window by ID, including its upcoming occurrences",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "maintenance"
+                ],
+                "summary": "Get maintenance window details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Maintenance Window ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.UserSettings"
+                            "$ref": "#/definitions/types.GetMaintenanceWindowResponse"
                         }
                     },
                     "400": {
@@ -756,6 +790,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -767,13 +810,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "patch": {
+            "put": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Updates a single setting for the authenticated user",
+                "description": "Update an existing maintenance window by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -781,17 +824,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "maintenance"
                 ],
-                "summary": "Update a single setting",
+                "summary": "Update maintenance window",
                 "parameters": [
                     {
-                        "description": "Setting to update",
-                        "name": "setting",
+                        "type": "string",
+                        "description": "Maintenance Window ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Maintenance window update request",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.UpdateSettingsRequest"
+                            "$ref": "#/definitions/types.UpdateMaintenanceWindowRequest"
                         }
                     }
                 ],
@@ -799,7 +849,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.UserSettings"
+                            "$ref": "#/definitions/types.GetMaintenanceWindowResponse"
                         }
                     },
                     "400": {
@@ -820,6 +870,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -830,16 +889,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/settings/api-key": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Generates a new API key for the authenticated user",
+                "description": "Delete a maintenance window by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -847,14 +904,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "maintenance"
+                ],
+                "summary": "Delete maintenance window",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Maintenance Window ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Generate a new API key",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/types.GenerateAPIKeyResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "401": {
@@ -878,14 +950,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/settings/reset": {
-            "post": {
+        "/monitors": {
+            "get": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Resets all user settings to their default values",
+                "description": "Get all monitors for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
@@ -893,14 +965,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "monitors"
                 ],
-                "summary": "Reset user settings to defaults",
+                "summary": "List monitors",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.UserSettings"
+                            "$ref": "#/definitions/types.ListMonitorsResponse"
                         }
                     },
                     "401": {
@@ -922,16 +994,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/settings/test-webhook": {
+            },
             "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Tests the webhook configuration by sending a test payload",
+                "description": "Creates a new monitor for the authenticated user",
                 "consumes": [
                     "application/json"
                 ],
@@ -939,17 +1009,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "monitors"
                 ],
-                "summary": "Test webhook configuration",
+                "summary": "Create a new monitor",
                 "parameters": [
                     {
-                        "description": "Webhook configuration to test",
-                        "name": "webhook",
+                        "description": "Monitor to create",
+                        "name": "monitor",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.TestWebhookRequest"
+                            "$ref": "#/definitions/types.CreateMonitorRequest"
                         }
                     }
                 ],
@@ -957,7 +1027,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.TestWebhookResponse"
+                            "$ref": "#/definitions/types.GetMonitorResponse"
                         }
                     },
                     "400": {
@@ -990,14 +1060,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/settings/toggle/{setting}": {
+        "/monitors/schedule/preview": {
             "post": {
                 "security": [
                     {
                         "Bearer": []
                     }
                 ],
-                "description": "Toggles a boolean setting for the authenticated user",
+                "description": "Validates a cron schedule and returns the next times it would run",
                 "consumes": [
                     "application/json"
                 ],
@@ -1005,14 +1075,71 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "monitors"
                 ],
-                "summary": "Toggle a boolean setting",
+                "summary": "Preview a monitor schedule",
+                "parameters": [
+                    {
+                        "description": "Schedule to preview",
+                        "name": "schedule",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.SchedulePreviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.SchedulePreviewResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/monitors/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get details for a specific monitor by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "monitors"
+                ],
+                "summary": "Get monitor details",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Setting name to toggle",
-                        "name": "setting",
+                        "description": "Monitor ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -1021,7 +1148,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.ToggleSettingResponse"
+                            "$ref": "#/definitions/types.GetMonitorResponse"
                         }
                     },
                     "400": {
@@ -1052,45 +1179,802 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "types.AlertCondition": {
-            "type": "string",
-            "enum": [
-                "status_code",
-                "latency",
-                "availability",
-                "ssl_expiry",
-                "keyword",
-                "pattern"
-            ],
-            "x-enum-varnames": [
-                "AlertConditionStatusCode",
-                "AlertConditionLatency",
-                "AlertConditionAvailability",
-                "AlertConditionSSLExpiry",
-                "AlertConditionKeyword",
-                "AlertConditionPattern"
-            ]
-        },
-        "types.AlertConfig": {
-            "type": "object",
-            "properties": {
-                "condition": {
-                    "$ref": "#/definitions/types.AlertCondition"
-                },
-                "consecutive_count": {
-                    "type": "integer"
-                },
-                "cooldown_minutes": {
-                    "type": "integer"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "enabled": {
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Updates an existing monitor for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "monitors"
+                ],
+                "summary": "Update a monitor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Monitor ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Monitor to update",
+                        "name": "monitor",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.UpdateMonitorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.GetMonitorResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Deletes a monitor for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "monitors"
+                ],
+                "summary": "Delete a monitor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Monitor ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/probe-agents": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get the private probe agents registered with the authenticated user's tokens, with their status, last heartbeat and load",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "probe-agents"
+                ],
+                "summary": "List private probe agents",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListProbeAgentsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/probe-agents/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get all private probe agent tokens for the authenticated user, including revoked ones",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "probe-agents"
+                ],
+                "summary": "List probe agent tokens",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ListProbeAgentTokensResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Issue a token for a private probe agent. Agents started with it only run checks for the authenticated user's monitors. The token is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "probe-agents"
+                ],
+                "summary": "Create probe agent token",
+                "parameters": [
+                    {
+                        "description": "Token details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.CreateProbeAgentTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/types.CreateProbeAgentTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/probe-agents/tokens/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Revoke a probe agent token. Agents registered with it are dropped by the probe manager and their checks are moved to other workers.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "probe-agents"
+                ],
+                "summary": "Revoke probe agent token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Probe Agent Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ProbeAgentTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/settings": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Get settings for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get user settings",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.GetSettingsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Updates all settings for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Update all user settings",
+                "parameters": [
+                    {
+                        "description": "Settings to update",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.UpdateSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Updates a single setting for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Update a single setting",
+                "parameters": [
+                    {
+                        "description": "Setting to update",
+                        "name": "setting",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.UpdateSettingsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/api-key": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Generates a new API key for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Generate a new API key",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.GenerateAPIKeyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/reset": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Resets all user settings to their default values",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Reset user settings to defaults",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserSettings"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/test-webhook": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Tests the webhook configuration by sending a test payload",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Test webhook configuration",
+                "parameters": [
+                    {
+                        "description": "Webhook configuration to test",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.TestWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.TestWebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/toggle/{setting}": {
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "description": "Toggles a boolean setting for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Toggle a boolean setting",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Setting name to toggle",
+                        "name": "setting",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.ToggleSettingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "types.AlertCondition": {
+            "type": "string",
+            "enum": [
+                "status_code",
+                "latency",
+                "availability",
+                "ssl_expiry",
+                "keyword",
+                "pattern",
+                "dnssec_expiry"
+            ],
+            "x-enum-varnames": [
+                "AlertConditionStatusCode",
+                "AlertConditionLatency",
+                "AlertConditionAvailability",
+                "AlertConditionSSLExpiry",
+                "AlertConditionKeyword",
+                "AlertConditionPattern",
+                "AlertConditionDNSSECExpiry"
+            ]
+        },
+        "types.AlertConfig": {
+            "type": "object",
+            "properties": {
+                "condition": {
+                    "$ref": "#/definitions/types.AlertCondition"
+                },
+                "consecutive_count": {
+                    "type": "integer"
+                },
+                "cooldown_minutes": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
                     "type": "boolean"
                 },
                 "id": {
@@ -1130,7 +2014,7 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "days_in_advance": {
-                    "description": "For SSL expiry alerts",
+                    "description": "For SSL and DNSSEC expiry alerts",
                     "type": "integer"
                 },
                 "exact_match": {
@@ -1184,10 +2068,69 @@ const docTemplate = `{
                 }
             }
         },
+        "types.CreateMaintenanceWindowRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "starts_at"
+            ],
+            "properties": {
+                "action": {
+                    "enum": [
+                        "skip",
+                        "flag"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MaintenanceAction"
+                        }
+                    ]
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration_seconds": {
+                    "type": "integer",
+                    "minimum": 60
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "group_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "monitor_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "recurrence": {
+                    "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
         "types.CreateMonitorRequest": {
             "type": "object",
             "required": [
-                "interval",
                 "locations",
                 "name",
                 "target",
@@ -1195,8 +2138,26 @@ const docTemplate = `{
                 "type"
             ],
             "properties": {
+                "dns": {
+                    "description": "DNS sets the nameserver and expected answers of DNS monitors",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.DNSOptions"
+                        }
+                    ]
+                },
                 "dns_record_type": {
-                    "type": "string"
+                    "type": "string",
+                    "enum": [
+                        "A",
+                        "AAAA",
+                        "CNAME",
+                        "MX",
+                        "TXT",
+                        "NS",
+                        "SOA",
+                        "SRV"
+                    ]
                 },
                 "expected_response": {
                     "type": "string"
@@ -1210,6 +2171,14 @@ const docTemplate = `{
                 "follow_redirects": {
                     "type": "boolean"
                 },
+                "http": {
+                    "description": "HTTP sets the request HTTP monitors send",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.HTTPOptions"
+                        }
+                    ]
+                },
                 "interval": {
                     "type": "integer",
                     "minimum": 30
@@ -1227,6 +2196,19 @@ const docTemplate = `{
                 "port": {
                     "type": "integer"
                 },
+                "probe_selector": {
+                    "description": "ProbeSelector restricts which probe workers run the checks, e.g.\n{\"provider\": \"aws\", \"ip_family\": \"ipv6\"}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "schedule_timezone": {
+                    "type": "string"
+                },
                 "status": {
                     "$ref": "#/definitions/types.MonitorStatus"
                 },
@@ -1245,6 +2227,60 @@ const docTemplate = `{
                 }
             }
         },
+        "types.CreateProbeAgentTokenRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                }
+            }
+        },
+        "types.CreateProbeAgentTokenResponse": {
+            "type": "object",
+            "properties": {
+                "agent_token": {
+                    "$ref": "#/definitions/types.ProbeAgentToken"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.DNSOptions": {
+            "type": "object",
+            "properties": {
+                "check_propagation": {
+                    "type": "boolean"
+                },
+                "dnssec": {
+                    "type": "boolean"
+                },
+                "expected_values": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "nameserver": {
+                    "type": "string"
+                },
+                "resolvers": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "trust_anchor": {
+                    "type": "string"
+                }
+            }
+        },
         "types.GenerateAPIKeyResponse": {
             "type": "object",
             "properties": {
@@ -1267,6 +2303,14 @@ const docTemplate = `{
                 }
             }
         },
+        "types.GetMaintenanceWindowResponse": {
+            "type": "object",
+            "properties": {
+                "maintenance_window": {
+                    "$ref": "#/definitions/types.MaintenanceWindow"
+                }
+            }
+        },
         "types.GetMonitorResponse": {
             "type": "object",
             "properties": {
@@ -1275,45 +2319,305 @@ const docTemplate = `{
                 }
             }
         },
-        "types.GetSettingsResponse": {
+        "types.GetSettingsResponse": {
+            "type": "object",
+            "properties": {
+                "limits": {
+                    "$ref": "#/definitions/types.UserLimits"
+                },
+                "settings": {
+                    "$ref": "#/definitions/types.UserSettings"
+                },
+                "usage": {
+                    "$ref": "#/definitions/types.UserUsageStats"
+                }
+            }
+        },
+        "types.HTTPAssertion": {
+            "type": "object",
+            "required": [
+                "operator",
+                "path",
+                "source"
+            ],
+            "properties": {
+                "operator": {
+                    "type": "string",
+                    "enum": [
+                        "equals",
+                        "not_equals",
+                        "contains",
+                        "not_contains",
+                        "greater_than",
+                        "less_than",
+                        "exists",
+                        "not_exists",
+                        "type"
+                    ]
+                },
+                "path": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string",
+                    "enum": [
+                        "json",
+                        "header"
+                    ]
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.HTTPBasicAuth": {
+            "type": "object",
+            "required": [
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.HTTPOptions": {
+            "type": "object",
+            "properties": {
+                "assertions": {
+                    "description": "Assertions must all pass for the check to succeed",
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "$ref": "#/definitions/types.HTTPAssertion"
+                    }
+                },
+                "basic_auth": {
+                    "$ref": "#/definitions/types.HTTPBasicAuth"
+                },
+                "body": {
+                    "type": "string"
+                },
+                "content_match_mode": {
+                    "description": "ContentMatchMode says how expected_response is matched against the\nresponse body, contains by default",
+                    "type": "string",
+                    "enum": [
+                        "contains",
+                        "not_contains",
+                        "regex",
+                        "not_regex"
+                    ]
+                },
+                "cookies": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "max_redirects": {
+                    "type": "integer",
+                    "maximum": 20,
+                    "minimum": 0
+                },
+                "max_response_time_ms": {
+                    "description": "MaxResponseTimeMs fails checks whose response takes longer",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "method": {
+                    "type": "string",
+                    "enum": [
+                        "GET",
+                        "HEAD",
+                        "POST",
+                        "PUT",
+                        "PATCH",
+                        "DELETE",
+                        "OPTIONS"
+                    ]
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ListAlertConfigsResponse": {
+            "type": "object",
+            "properties": {
+                "alert_configs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.AlertConfig"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ListMaintenanceWindowsResponse": {
+            "type": "object",
+            "properties": {
+                "maintenance_windows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.MaintenanceWindow"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ListMonitorsResponse": {
+            "type": "object",
+            "properties": {
+                "monitors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.Monitor"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ListProbeAgentTokensResponse": {
+            "type": "object",
+            "properties": {
+                "agent_tokens": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ProbeAgentToken"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ListProbeAgentsResponse": {
+            "type": "object",
+            "properties": {
+                "agents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ProbeAgent"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ListProbeWorkersResponse": {
+            "type": "object",
+            "properties": {
+                "total": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ProbeWorker"
+                    }
+                }
+            }
+        },
+        "types.MaintenanceAction": {
+            "type": "string",
+            "enum": [
+                "skip",
+                "flag"
+            ],
+            "x-enum-varnames": [
+                "MaintenanceActionSkip",
+                "MaintenanceActionFlag"
+            ]
+        },
+        "types.MaintenanceOccurrence": {
             "type": "object",
             "properties": {
-                "limits": {
-                    "$ref": "#/definitions/types.UserLimits"
-                },
-                "settings": {
-                    "$ref": "#/definitions/types.UserSettings"
+                "ends_at": {
+                    "type": "string"
                 },
-                "usage": {
-                    "$ref": "#/definitions/types.UserUsageStats"
+                "starts_at": {
+                    "type": "string"
                 }
             }
         },
-        "types.ListAlertConfigsResponse": {
+        "types.MaintenanceWindow": {
             "type": "object",
             "properties": {
-                "alert_configs": {
+                "action": {
+                    "$ref": "#/definitions/types.MaintenanceAction"
+                },
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration_seconds": {
+                    "type": "integer"
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "group_ids": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/types.AlertConfig"
+                        "type": "string"
                     }
                 },
-                "total": {
-                    "type": "integer"
-                }
-            }
-        },
-        "types.ListMonitorsResponse": {
-            "type": "object",
-            "properties": {
-                "monitors": {
+                "id": {
+                    "type": "string"
+                },
+                "monitor_ids": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/types.Monitor"
+                        "type": "string"
                     }
                 },
-                "total": {
-                    "type": "integer"
+                "name": {
+                    "type": "string"
+                },
+                "next_occurrences": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.MaintenanceOccurrence"
+                    }
+                },
+                "recurrence": {
+                    "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
@@ -1323,6 +2627,9 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "dns": {
+                    "$ref": "#/definitions/types.DNSOptions"
+                },
                 "dns_record_type": {
                     "type": "string"
                 },
@@ -1338,6 +2645,9 @@ const docTemplate = `{
                 "follow_redirects": {
                     "type": "boolean"
                 },
+                "http": {
+                    "$ref": "#/definitions/types.HTTPOptions"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -1353,9 +2663,27 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "next_runs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "port": {
                     "type": "integer"
                 },
+                "probe_selector": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "schedule_timezone": {
+                    "type": "string"
+                },
                 "status": {
                     "$ref": "#/definitions/types.MonitorStatus"
                 },
@@ -1407,6 +2735,176 @@ const docTemplate = `{
                 "MonitorTypeDNS"
             ]
         },
+        "types.ProbeAgent": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "type": "integer"
+                },
+                "check_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "in_flight": {
+                    "type": "integer"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number"
+                },
+                "queue_depth": {
+                    "type": "integer"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "token_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ProbeAgentToken": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "revoked": {
+                    "type": "boolean"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "token_prefix": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ProbeAgentTokenResponse": {
+            "type": "object",
+            "properties": {
+                "agent_token": {
+                    "$ref": "#/definitions/types.ProbeAgentToken"
+                }
+            }
+        },
+        "types.ProbeWorker": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "type": "integer"
+                },
+                "check_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cordoned": {
+                    "type": "boolean"
+                },
+                "draining": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "in_flight": {
+                    "type": "integer"
+                },
+                "ip_family": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "queue_depth": {
+                    "type": "integer"
+                },
+                "region": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ProbeWorkerResponse": {
+            "type": "object",
+            "properties": {
+                "worker": {
+                    "$ref": "#/definitions/types.ProbeWorker"
+                }
+            }
+        },
+        "types.SchedulePreviewRequest": {
+            "type": "object",
+            "required": [
+                "schedule"
+            ],
+            "properties": {
+                "schedule": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.SchedulePreviewResponse": {
+            "type": "object",
+            "properties": {
+                "next_runs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
         "types.TestWebhookRequest": {
             "type": "object",
             "required": [
@@ -1507,11 +3005,85 @@ const docTemplate = `{
                 }
             }
         },
+        "types.UpdateMaintenanceWindowRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "enum": [
+                        "skip",
+                        "flag"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MaintenanceAction"
+                        }
+                    ]
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration_seconds": {
+                    "type": "integer",
+                    "minimum": 60
+                },
+                "ends_at": {
+                    "type": "string"
+                },
+                "group_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "monitor_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "recurrence": {
+                    "type": "string"
+                },
+                "starts_at": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timezone": {
+                    "type": "string"
+                }
+            }
+        },
         "types.UpdateMonitorRequest": {
             "type": "object",
             "properties": {
+                "dns": {
+                    "description": "DNS sets the nameserver and expected answers of DNS monitors",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.DNSOptions"
+                        }
+                    ]
+                },
                 "dns_record_type": {
-                    "type": "string"
+                    "type": "string",
+                    "enum": [
+                        "A",
+                        "AAAA",
+                        "CNAME",
+                        "MX",
+                        "TXT",
+                        "NS",
+                        "SOA",
+                        "SRV"
+                    ]
                 },
                 "expected_response": {
                     "type": "string"
@@ -1525,6 +3097,14 @@ const docTemplate = `{
                 "follow_redirects": {
                     "type": "boolean"
                 },
+                "http": {
+                    "description": "HTTP sets the request HTTP monitors send",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.HTTPOptions"
+                        }
+                    ]
+                },
                 "interval": {
                     "type": "integer",
                     "minimum": 30
@@ -1542,6 +3122,19 @@ const docTemplate = `{
                 "port": {
                     "type": "integer"
                 },
+                "probe_selector": {
+                    "description": "ProbeSelector restricts which probe workers run the checks, e.g.\n{\"provider\": \"aws\", \"ip_family\": \"ipv6\"}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "schedule": {
+                    "type": "string"
+                },
+                "schedule_timezone": {
+                    "type": "string"
+                },
                 "status": {
                     "$ref": "#/definitions/types.MonitorStatus"
                 },
@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/types"
+	"go.uber.org/zap"
+)
+
+// requestTimeout bounds how long the probe manager has to answer
+const requestTimeout = 5 * time.Second
+
+type Handler struct {
+	*handlers.Handler
+}
+
+func NewHandler(h *handlers.Handler) *Handler {
+	return &Handler{Handler: h}
+}
+
+// ListWorkers godoc
+// @Summary      List probe workers
+// @Description  Get every probe worker known to the probe manager, with its labels, status and last-seen time. Requires an admin token.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  types.ListProbeWorkersResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/probe-workers [get]
+func (h *Handler) ListWorkers(c *gin.Context) {
+	msg, err := h.NATS.Request("probes.workers.list", nil, requestTimeout)
+	if err != nil {
+		h.Logger.Error("failed to request probe workers", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "probe manager unavailable"})
+		return
+	}
+
+	var response types.ListProbeWorkersResponse
+	if err := json.Unmarshal(msg.Data, &response); err != nil {
+		h.Logger.Error("failed to unmarshal probe workers", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "invalid reply from probe manager"})
+		return
+	}
+	if response.Workers == nil {
+		response.Workers = []types.ProbeWorker{}
+	}
+	response.Total = len(response.Workers)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Cordon godoc
+// @Summary      Cordon probe worker
+// @Description  Stop assigning new checks to a probe worker. Checks it already has are left to finish. Requires an admin token.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "Probe Worker ID"
+// @Success      200  {object}  types.ProbeWorkerResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/probe-workers/{id}/cordon [post]
+func (h *Handler) Cordon(c *gin.Context) {
+	h.workerCommand(c, "probes.workers.cordon")
+}
+
+// Uncordon godoc
+// @Summary      Uncordon probe worker
+// @Description  Return a cordoned or drained probe worker to rotation. Requires an admin token.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "Probe Worker ID"
+// @Success      200  {object}  types.ProbeWorkerResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/probe-workers/{id}/uncordon [post]
+func (h *Handler) Uncordon(c *gin.Context) {
+	h.workerCommand(c, "probes.workers.uncordon")
+}
+
+// Drain godoc
+// @Summary      Drain probe worker
+// @Description  Cordon a probe worker and move the checks it has not started to other workers. The worker can be stopped once its in_flight count reaches zero. Requires an admin token.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "Probe Worker ID"
+// @Success      200  {object}  types.ProbeWorkerResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /admin/probe-workers/{id}/drain [post]
+func (h *Handler) Drain(c *gin.Context) {
+	h.workerCommand(c, "probes.workers.drain")
+}
+
+// workerCommand sends a command for the worker in the path to the probe
+// manager and writes its reply
+func (h *Handler) workerCommand(c *gin.Context, subject string) {
+	request, _ := json.Marshal(map[string]string{"worker_id": c.Param("id")})
+	msg, err := h.NATS.Request(subject, request, requestTimeout)
+	if err != nil {
+		h.Logger.Error("failed to send probe worker command",
+			zap.String("subject", subject),
+			zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "probe manager unavailable"})
+		return
+	}
+
+	var reply struct {
+		Worker *types.ProbeWorker `json:"worker"`
+		Error  string             `json:"error"`
+	}
+	if err := json.Unmarshal(msg.Data, &reply); err != nil {
+		h.Logger.Error("failed to unmarshal probe worker command reply", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "invalid reply from probe manager"})
+		return
+	}
+	if reply.Worker == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "probe worker not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ProbeWorkerResponse{Worker: *reply.Worker})
+}
@@ -0,0 +1,233 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	"github.com/jjkirkpatrick/monitoring/pkg/auth"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/types"
+	"go.uber.org/zap"
+)
+
+// requestTimeout bounds how long the probe manager has to answer
+const requestTimeout = 5 * time.Second
+
+type Handler struct {
+	*handlers.Handler
+}
+
+func NewHandler(h *handlers.Handler) *Handler {
+	return &Handler{Handler: h}
+}
+
+// ListAgents godoc
+// @Summary      List private probe agents
+// @Description  Get the private probe agents registered with the authenticated user's tokens, with their status, last heartbeat and load
+// @Tags         probe-agents
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  types.ListProbeAgentsResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /probe-agents [get]
+func (h *Handler) ListAgents(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	request, _ := json.Marshal(map[string]string{"account_id": userID})
+	msg, err := h.NATS.Request("probes.agents.list", request, requestTimeout)
+	if err != nil {
+		h.Logger.Error("failed to request probe agents", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "probe manager unavailable"})
+		return
+	}
+
+	var reply struct {
+		Workers []types.ProbeAgent `json:"workers"`
+	}
+	if err := json.Unmarshal(msg.Data, &reply); err != nil {
+		h.Logger.Error("failed to unmarshal probe agents", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "invalid reply from probe manager"})
+		return
+	}
+	if reply.Workers == nil {
+		reply.Workers = []types.ProbeAgent{}
+	}
+
+	c.JSON(http.StatusOK, types.ListProbeAgentsResponse{
+		Agents: reply.Workers,
+		Total:  len(reply.Workers),
+	})
+}
+
+// ListTokens godoc
+// @Summary      List probe agent tokens
+// @Description  Get all private probe agent tokens for the authenticated user, including revoked ones
+// @Tags         probe-agents
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  types.ListProbeAgentTokensResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /probe-agents/tokens [get]
+func (h *Handler) ListTokens(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := h.DB.Queries.ListProbeAgentTokens(c, uuid.MustParse(userID))
+	if err != nil {
+		h.Logger.Error("failed to list probe agent tokens", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list probe agent tokens"})
+		return
+	}
+
+	response := types.ListProbeAgentTokensResponse{
+		AgentTokens: make([]types.ProbeAgentToken, len(rows)),
+		Total:       len(rows),
+	}
+	for i, row := range rows {
+		response.AgentTokens[i] = toResponse(row)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateToken godoc
+// @Summary      Create probe agent token
+// @Description  Issue a token for a private probe agent. Agents started with it only run checks for the authenticated user's monitors. The token is only returned in this response.
+// @Tags         probe-agents
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request  body      types.CreateProbeAgentTokenRequest  true  "Token details"
+// @Success      201      {object}  types.CreateProbeAgentTokenResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /probe-agents/tokens [post]
+func (h *Handler) CreateToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.CreateProbeAgentTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := auth.GenerateAgentToken()
+	if err != nil {
+		h.Logger.Error("failed to generate probe agent token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate probe agent token"})
+		return
+	}
+
+	row, err := h.DB.Queries.CreateProbeAgentToken(c, sqlc.CreateProbeAgentTokenParams{
+		UserID:      uuid.MustParse(userID),
+		Name:        req.Name,
+		TokenHash:   auth.HashAgentToken(token),
+		TokenPrefix: auth.AgentTokenPrefix(token),
+	})
+	if err != nil {
+		h.Logger.Error("failed to create probe agent token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create probe agent token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.CreateProbeAgentTokenResponse{
+		AgentToken: toResponse(row),
+		Token:      token,
+	})
+}
+
+// RevokeToken godoc
+// @Summary      Revoke probe agent token
+// @Description  Revoke a probe agent token. Agents registered with it are dropped by the probe manager and their checks are moved to other workers.
+// @Tags         probe-agents
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "Probe Agent Token ID"
+// @Success      200  {object}  types.ProbeAgentTokenResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /probe-agents/tokens/{id} [delete]
+func (h *Handler) RevokeToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid probe agent token id"})
+		return
+	}
+
+	row, err := h.DB.Queries.RevokeProbeAgentToken(c, sqlc.RevokeProbeAgentTokenParams{
+		ID:     id,
+		UserID: uuid.MustParse(userID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "probe agent token not found or already revoked"})
+			return
+		}
+		h.Logger.Error("failed to revoke probe agent token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke probe agent token"})
+		return
+	}
+
+	// Agents already registered keep running until the probe manager
+	// hears about the revocation; they are refused on their next
+	// registration either way
+	event, _ := json.Marshal(map[string]string{"token_id": row.ID.String()})
+	if err := h.NATS.Publish("probes.agents.revoked", event); err != nil {
+		h.Logger.Error("failed to publish probe agent token revocation",
+			zap.String("token_id", row.ID.String()),
+			zap.Error(err))
+	}
+
+	c.JSON(http.StatusOK, types.ProbeAgentTokenResponse{AgentToken: toResponse(row)})
+}
+
+func toResponse(row sqlc.ProbeAgentToken) types.ProbeAgentToken {
+	return types.ProbeAgentToken{
+		ID:          row.ID,
+		Name:        row.Name,
+		TokenPrefix: row.TokenPrefix,
+		Revoked:     row.RevokedAt.Valid,
+		LastUsedAt:  getTimePtr(row.LastUsedAt),
+		RevokedAt:   getTimePtr(row.RevokedAt),
+		CreatedAt:   row.CreatedAt.Time,
+	}
+}
+
+func getTimePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
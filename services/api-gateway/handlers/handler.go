@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/jjkirkpatrick/monitoring/internal/database"
+	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
@@ -9,12 +10,14 @@ import (
 type Handler struct {
 	Logger *zap.Logger
 	DB     *database.DB
+	NATS   *nats.Conn
 }
 
 // NewHandler creates a new base handler with common dependencies
-func NewHandler(logger *zap.Logger, db *database.DB) *Handler {
+func NewHandler(logger *zap.Logger, db *database.DB, nc *nats.Conn) *Handler {
 	return &Handler{
 		Logger: logger,
 		DB:     db,
+		NATS:   nc,
 	}
 }
@@ -0,0 +1,488 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	pkgmaintenance "github.com/jjkirkpatrick/monitoring/pkg/maintenance"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/types"
+	"go.uber.org/zap"
+)
+
+// upcomingOccurrences is the number of future occurrences included in responses
+const upcomingOccurrences = 5
+
+type Handler struct {
+	*handlers.Handler
+}
+
+func NewHandler(h *handlers.Handler) *Handler {
+	return &Handler{Handler: h}
+}
+
+// List godoc
+// @Summary      List maintenance windows
+// @Description  Get all maintenance windows for the authenticated user
+// @Tags         maintenance
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Success      200  {object}  types.ListMaintenanceWindowsResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /maintenance-windows [get]
+func (h *Handler) List(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := h.DB.Queries.ListMaintenanceWindows(c, uuid.MustParse(userID))
+	if err != nil {
+		h.Logger.Error("failed to list maintenance windows", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list maintenance windows"})
+		return
+	}
+
+	now := time.Now()
+	response := types.ListMaintenanceWindowsResponse{
+		MaintenanceWindows: make([]types.MaintenanceWindow, len(rows)),
+		Total:              len(rows),
+	}
+	for i, row := range rows {
+		response.MaintenanceWindows[i] = toResponse(row, now)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Get godoc
+// @Summary      Get maintenance window details
+// @Description  Get a maintenance window by ID, including its upcoming occurrences
+// @Tags         maintenance
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "Maintenance Window ID"
+// @Success      200  {object}  types.GetMaintenanceWindowResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /maintenance-windows/{id} [get]
+func (h *Handler) Get(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid maintenance window id"})
+		return
+	}
+
+	row, err := h.DB.Queries.GetMaintenanceWindow(c, sqlc.GetMaintenanceWindowParams{
+		ID:     id,
+		UserID: uuid.MustParse(userID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "maintenance window not found"})
+			return
+		}
+		h.Logger.Error("failed to get maintenance window", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get maintenance window"})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.GetMaintenanceWindowResponse{
+		MaintenanceWindow: toResponse(row, time.Now()),
+	})
+}
+
+// Create godoc
+// @Summary      Create maintenance window
+// @Description  Create a one-off or recurring maintenance window covering monitors by ID, group or tag
+// @Tags         maintenance
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        request  body      types.CreateMaintenanceWindowRequest  true  "Maintenance window details"
+// @Success      201      {object}  types.GetMaintenanceWindowResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /maintenance-windows [post]
+func (h *Handler) Create(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.CreateMaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	row := sqlc.MaintenanceWindow{
+		UserID:          uuid.MustParse(userID),
+		Name:            req.Name,
+		Description:     stringToNullString(req.Description),
+		MonitorIds:      req.MonitorIDs,
+		GroupIds:        req.GroupIDs,
+		Tags:            req.Tags,
+		StartsAt:        pgtype.Timestamptz{Time: req.StartsAt, Valid: true},
+		EndsAt:          timeToNullTime(req.EndsAt),
+		DurationSeconds: intToNullInt(req.DurationSeconds),
+		Recurrence:      stringToNullString(req.Recurrence),
+		Timezone:        "UTC",
+		Action:          string(types.MaintenanceActionSkip),
+	}
+	if req.Timezone != nil {
+		row.Timezone = *req.Timezone
+	}
+	if req.Action != "" {
+		row.Action = string(req.Action)
+	}
+
+	if !h.validate(c, row) {
+		return
+	}
+
+	created, err := h.DB.Queries.CreateMaintenanceWindow(c, sqlc.CreateMaintenanceWindowParams{
+		UserID:          row.UserID,
+		Name:            row.Name,
+		Description:     row.Description,
+		MonitorIds:      nonNilUUIDs(row.MonitorIds),
+		GroupIds:        nonNilUUIDs(row.GroupIds),
+		Tags:            nonNilStrings(row.Tags),
+		StartsAt:        row.StartsAt,
+		EndsAt:          row.EndsAt,
+		DurationSeconds: row.DurationSeconds,
+		Recurrence:      row.Recurrence,
+		Timezone:        row.Timezone,
+		Action:          row.Action,
+	})
+	if err != nil {
+		h.Logger.Error("failed to create maintenance window", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create maintenance window"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.GetMaintenanceWindowResponse{
+		MaintenanceWindow: toResponse(created, time.Now()),
+	})
+}
+
+// Update godoc
+// @Summary      Update maintenance window
+// @Description  Update an existing maintenance window by ID
+// @Tags         maintenance
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        id       path      string                                true  "Maintenance Window ID"
+// @Param        request  body      types.UpdateMaintenanceWindowRequest  true  "Maintenance window update request"
+// @Success      200      {object}  types.GetMaintenanceWindowResponse
+// @Failure      400      {object}  map[string]string
+// @Failure      401      {object}  map[string]string
+// @Failure      404      {object}  map[string]string
+// @Failure      500      {object}  map[string]string
+// @Router       /maintenance-windows/{id} [put]
+func (h *Handler) Update(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid maintenance window id"})
+		return
+	}
+
+	var req types.UpdateMaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	row, err := h.DB.Queries.GetMaintenanceWindow(c, sqlc.GetMaintenanceWindowParams{
+		ID:     id,
+		UserID: uuid.MustParse(userID),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "maintenance window not found"})
+			return
+		}
+		h.Logger.Error("failed to get maintenance window", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get maintenance window"})
+		return
+	}
+
+	// Apply the changes to the stored window so the result is validated as a whole
+	if req.Name != nil {
+		row.Name = *req.Name
+	}
+	if req.Description != nil {
+		row.Description = stringToNullString(req.Description)
+	}
+	if req.MonitorIDs != nil {
+		row.MonitorIds = *req.MonitorIDs
+	}
+	if req.GroupIDs != nil {
+		row.GroupIds = *req.GroupIDs
+	}
+	if req.Tags != nil {
+		row.Tags = *req.Tags
+	}
+	if req.StartsAt != nil {
+		row.StartsAt = pgtype.Timestamptz{Time: *req.StartsAt, Valid: true}
+	}
+	if req.EndsAt != nil {
+		row.EndsAt = timeToNullTime(req.EndsAt)
+	}
+	if req.DurationSeconds != nil {
+		row.DurationSeconds = intToNullInt(req.DurationSeconds)
+	}
+	if req.Recurrence != nil {
+		row.Recurrence = pgtype.Text{String: *req.Recurrence, Valid: *req.Recurrence != ""}
+	}
+	if req.Timezone != nil {
+		row.Timezone = *req.Timezone
+	}
+	if req.Action != nil {
+		row.Action = string(*req.Action)
+	}
+
+	if !h.validate(c, row) {
+		return
+	}
+
+	updated, err := h.DB.Queries.UpdateMaintenanceWindow(c, sqlc.UpdateMaintenanceWindowParams{
+		ID:              row.ID,
+		UserID:          row.UserID,
+		Name:            row.Name,
+		Description:     row.Description,
+		MonitorIds:      nonNilUUIDs(row.MonitorIds),
+		GroupIds:        nonNilUUIDs(row.GroupIds),
+		Tags:            nonNilStrings(row.Tags),
+		StartsAt:        row.StartsAt,
+		EndsAt:          row.EndsAt,
+		DurationSeconds: row.DurationSeconds,
+		Recurrence:      row.Recurrence,
+		Timezone:        row.Timezone,
+		Action:          row.Action,
+	})
+	if err != nil {
+		h.Logger.Error("failed to update maintenance window", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update maintenance window"})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.GetMaintenanceWindowResponse{
+		MaintenanceWindow: toResponse(updated, time.Now()),
+	})
+}
+
+// Delete godoc
+// @Summary      Delete maintenance window
+// @Description  Delete a maintenance window by ID
+// @Tags         maintenance
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        id   path      string  true  "Maintenance Window ID"
+// @Success      204  {object}  nil
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /maintenance-windows/{id} [delete]
+func (h *Handler) Delete(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid maintenance window id"})
+		return
+	}
+
+	err = h.DB.Queries.DeleteMaintenanceWindow(c, sqlc.DeleteMaintenanceWindowParams{
+		ID:     id,
+		UserID: uuid.MustParse(userID),
+	})
+	if err != nil {
+		h.Logger.Error("failed to delete maintenance window", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete maintenance window"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// validate checks the window's schedule and that every monitor it names
+// belongs to the user, writing an error response and returning false if not
+func (h *Handler) validate(c *gin.Context, row sqlc.MaintenanceWindow) bool {
+	if err := toWindow(row).Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	for _, monitorID := range row.MonitorIds {
+		_, err := h.DB.Queries.GetMonitor(c, sqlc.GetMonitorParams{
+			ID:     monitorID,
+			UserID: row.UserID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("monitor %s not found", monitorID)})
+			return false
+		}
+		if err != nil {
+			h.Logger.Error("failed to get monitor", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate maintenance window"})
+			return false
+		}
+	}
+
+	return true
+}
+
+// Helper functions
+
+func toWindow(row sqlc.MaintenanceWindow) *pkgmaintenance.Window {
+	w := &pkgmaintenance.Window{
+		ID:         row.ID.String(),
+		UserID:     row.UserID.String(),
+		Name:       row.Name,
+		Tags:       row.Tags,
+		StartsAt:   row.StartsAt.Time,
+		Recurrence: row.Recurrence.String,
+		Timezone:   row.Timezone,
+		Action:     pkgmaintenance.Action(row.Action),
+	}
+	for _, id := range row.MonitorIds {
+		w.MonitorIDs = append(w.MonitorIDs, id.String())
+	}
+	for _, id := range row.GroupIds {
+		w.GroupIDs = append(w.GroupIDs, id.String())
+	}
+	if row.EndsAt.Valid {
+		w.EndsAt = row.EndsAt.Time
+	}
+	if row.DurationSeconds.Valid {
+		w.Duration = time.Duration(row.DurationSeconds.Int32) * time.Second
+	}
+	return w
+}
+
+func toResponse(row sqlc.MaintenanceWindow, now time.Time) types.MaintenanceWindow {
+	response := types.MaintenanceWindow{
+		ID:              row.ID,
+		Name:            row.Name,
+		Description:     getStringPtr(row.Description),
+		MonitorIDs:      nonNilUUIDs(row.MonitorIds),
+		GroupIDs:        nonNilUUIDs(row.GroupIds),
+		Tags:            nonNilStrings(row.Tags),
+		StartsAt:        row.StartsAt.Time,
+		EndsAt:          getTimePtr(row.EndsAt),
+		DurationSeconds: getIntPtr(row.DurationSeconds),
+		Recurrence:      getStringPtr(row.Recurrence),
+		Timezone:        row.Timezone,
+		Action:          types.MaintenanceAction(row.Action),
+		NextOccurrences: []types.MaintenanceOccurrence{},
+		CreatedAt:       row.CreatedAt.Time,
+		UpdatedAt:       row.UpdatedAt.Time,
+	}
+
+	// Stored windows were validated on write; this only fails if the
+	// timezone database changed underneath us
+	window := toWindow(row)
+	if err := window.Validate(); err != nil {
+		return response
+	}
+	response.Active = window.ActiveAt(now)
+	for _, occ := range window.NextOccurrences(now, upcomingOccurrences) {
+		response.NextOccurrences = append(response.NextOccurrences, types.MaintenanceOccurrence{
+			StartsAt: occ.Start,
+			EndsAt:   occ.End,
+		})
+	}
+
+	return response
+}
+
+func nonNilUUIDs(ids []uuid.UUID) []uuid.UUID {
+	if ids == nil {
+		return []uuid.UUID{}
+	}
+	return ids
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func stringToNullString(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{Valid: false}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
+
+func intToNullInt(i *int) pgtype.Int4 {
+	if i == nil {
+		return pgtype.Int4{Valid: false}
+	}
+	return pgtype.Int4{Int32: int32(*i), Valid: true}
+}
+
+func timeToNullTime(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{Valid: false}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+func getStringPtr(s pgtype.Text) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func getIntPtr(n pgtype.Int4) *int {
+	if !n.Valid {
+		return nil
+	}
+	i := int(n.Int32)
+	return &i
+}
+
+func getTimePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,225 @@
+package monitors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/types"
+)
+
+// The settings of HTTP and DNS monitors live in monitor_http_extension and
+// monitor_dns_extension, which the dashboard's monitor functions write too, so
+// that the scheduler reads one place whichever way a monitor was created.
+
+// getHTTPExtension returns the monitor's HTTP extension, or nil if it has none
+func getHTTPExtension(ctx context.Context, q *sqlc.Queries, monitorID uuid.UUID) (*sqlc.GetHttpExtensionRow, error) {
+	row, err := q.GetHttpExtension(ctx, monitorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// httpExtensionParams stores the HTTP options of a monitor, and its expected
+// response as the content to match. A basic_auth without a password keeps the
+// password in previous if the username is unchanged.
+func httpExtensionParams(monitor sqlc.Monitor, options *types.HTTPOptions, previous *sqlc.GetHttpExtensionRow) sqlc.UpsertHttpExtensionParams {
+	params := sqlc.UpsertHttpExtensionParams{
+		MonitorID:    monitor.ID,
+		Url:          monitor.Target,
+		ContentMatch: monitor.ExpectedResponse,
+	}
+	if options == nil {
+		return params
+	}
+
+	params.Method = textOrNull(options.Method)
+	params.Headers = jsonOrNull(options.Headers)
+	params.Body = textOrNull(options.Body)
+	params.MaxRedirects = intToNullInt(options.MaxRedirects)
+	params.ContentMatchMode = textOrNull(options.ContentMatchMode)
+	params.UserAgent = textOrNull(options.UserAgent)
+	params.Cookies = jsonOrNull(options.Cookies)
+	if len(options.Assertions) > 0 {
+		params.Assertions, _ = json.Marshal(options.Assertions)
+	}
+	if options.MaxResponseTimeMs > 0 {
+		params.MaxResponseTimeMs = pgtype.Int4{Int32: int32(options.MaxResponseTimeMs), Valid: true}
+	}
+	if options.BasicAuth != nil {
+		params.BasicAuthUser = textOrNull(options.BasicAuth.Username)
+		params.BasicAuthPassword = textOrNull(options.BasicAuth.Password)
+		if options.BasicAuth.Password == "" && previous != nil &&
+			previous.BasicAuthUser.String == options.BasicAuth.Username {
+			params.BasicAuthPassword = previous.BasicAuthPassword
+		}
+	}
+	return params
+}
+
+// httpOptionsFromExtension returns the stored HTTP options without the basic
+// auth password
+func httpOptionsFromExtension(row *sqlc.GetHttpExtensionRow) *types.HTTPOptions {
+	if row == nil {
+		return nil
+	}
+	options := &types.HTTPOptions{
+		Method:            row.Method.String,
+		Body:              row.Body.String,
+		MaxRedirects:      getIntPtr(row.MaxRedirects),
+		UserAgent:         row.UserAgent.String,
+		ContentMatchMode:  row.ContentMatchMode.String,
+		MaxResponseTimeMs: int(row.MaxResponseTimeMs.Int32),
+	}
+	// Malformed JSON columns are left out rather than failing the request
+	_ = json.Unmarshal(row.Headers, &options.Headers)
+	_ = json.Unmarshal(row.Cookies, &options.Cookies)
+	_ = json.Unmarshal(row.Assertions, &options.Assertions)
+	if len(options.Headers) == 0 {
+		options.Headers = nil
+	}
+	if row.BasicAuthUser.String != "" {
+		options.BasicAuth = &types.HTTPBasicAuth{Username: row.BasicAuthUser.String}
+	}
+	return options
+}
+
+// httpOptionsByMonitor returns the HTTP options of each of the user's
+// monitors that has them
+func httpOptionsByMonitor(ctx context.Context, q *sqlc.Queries, userID uuid.UUID) (map[uuid.UUID]*types.HTTPOptions, error) {
+	rows, err := q.ListUserHttpExtensions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	options := make(map[uuid.UUID]*types.HTTPOptions, len(rows))
+	for _, row := range rows {
+		stored := sqlc.GetHttpExtensionRow(row)
+		options[row.MonitorID] = httpOptionsFromExtension(&stored)
+	}
+	return options, nil
+}
+
+// contentMatchMode returns the content match mode set by options, or by the
+// stored extension when options are not being replaced
+func contentMatchMode(options *types.HTTPOptions, stored *sqlc.GetHttpExtensionRow) string {
+	if options != nil {
+		return options.ContentMatchMode
+	}
+	if stored != nil {
+		return stored.ContentMatchMode.String
+	}
+	return ""
+}
+
+// getDNSExtension returns the monitor's DNS extension, or nil if it has none
+func getDNSExtension(ctx context.Context, q *sqlc.Queries, monitorID uuid.UUID) (*sqlc.GetDnsExtensionRow, error) {
+	row, err := q.GetDnsExtension(ctx, monitorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// dnsExtensionParams stores the DNS options of a monitor along with its
+// record type. Without expected values its expected response is the one
+// expected value. Addresses go in expected_ip and any other answer in
+// expected_value, which validateDNSOptions keeps to one.
+func dnsExtensionParams(monitor sqlc.Monitor, options *types.DNSOptions) sqlc.UpsertDnsExtensionParams {
+	recordType := dnsRecordType(monitor.DnsRecordType.String)
+	params := sqlc.UpsertDnsExtensionParams{
+		MonitorID:  monitor.ID,
+		Hostname:   monitor.Target,
+		RecordType: recordType,
+	}
+
+	var values []string
+	if options != nil {
+		params.Nameserver = textOrNull(options.Nameserver)
+		params.CheckPropagation = pgtype.Bool{Bool: options.CheckPropagation, Valid: true}
+		params.Resolvers = options.Resolvers
+		params.Dnssec = pgtype.Bool{Bool: options.DNSSEC, Valid: true}
+		params.TrustAnchor = textOrNull(options.TrustAnchor)
+		values = options.ExpectedValues
+	}
+	if len(values) == 0 && monitor.ExpectedResponse.String != "" {
+		values = []string{monitor.ExpectedResponse.String}
+	}
+	if isAddressRecord(recordType) {
+		params.ExpectedIp = values
+	} else if len(values) > 0 {
+		params.ExpectedValue = textOrNull(values[0])
+	}
+	return params
+}
+
+// dnsOptionsFromExtension returns the stored DNS options
+func dnsOptionsFromExtension(row *sqlc.GetDnsExtensionRow) *types.DNSOptions {
+	if row == nil {
+		return nil
+	}
+	options := &types.DNSOptions{
+		Nameserver:       row.Nameserver.String,
+		ExpectedValues:   row.ExpectedIp,
+		CheckPropagation: row.CheckPropagation.Bool,
+		Resolvers:        row.Resolvers,
+		DNSSEC:           row.Dnssec.Bool,
+		TrustAnchor:      row.TrustAnchor.String,
+	}
+	if row.ExpectedValue.String != "" {
+		options.ExpectedValues = append(options.ExpectedValues, row.ExpectedValue.String)
+	}
+	return options
+}
+
+// dnsOptionsByMonitor returns the DNS options of each of the user's monitors
+// that has them
+func dnsOptionsByMonitor(ctx context.Context, q *sqlc.Queries, userID uuid.UUID) (map[uuid.UUID]*types.DNSOptions, error) {
+	rows, err := q.ListUserDnsExtensions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	options := make(map[uuid.UUID]*types.DNSOptions, len(rows))
+	for _, row := range rows {
+		stored := sqlc.GetDnsExtensionRow(row)
+		options[row.MonitorID] = dnsOptionsFromExtension(&stored)
+	}
+	return options, nil
+}
+
+// dnsRecordType returns the record type a DNS monitor queries
+func dnsRecordType(recordType string) string {
+	return (&dnscheck.Config{RecordType: recordType}).Type()
+}
+
+// isAddressRecord reports whether the record type's answers are addresses
+func isAddressRecord(recordType string) bool {
+	switch dnsRecordType(recordType) {
+	case "A", "AAAA":
+		return true
+	}
+	return false
+}
+
+func textOrNull(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
+
+func jsonOrNull(m map[string]string) []byte {
+	if len(m) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(m)
+	return data
+}
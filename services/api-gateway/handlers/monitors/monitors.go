@@ -1,12 +1,17 @@
 package monitors
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
 	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers"
 	"github.com/jjkirkpatrick/monitoring/services/api-gateway/types"
 	"go.uber.org/zap"
@@ -45,6 +50,18 @@ func (h *Handler) List(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitors"})
 		return
 	}
+	httpOptions, err := httpOptionsByMonitor(c, h.DB.Queries, uuid.MustParse(userID))
+	if err != nil {
+		h.Logger.Error("failed to get monitor http options", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitors"})
+		return
+	}
+	dnsOptions, err := dnsOptionsByMonitor(c, h.DB.Queries, uuid.MustParse(userID))
+	if err != nil {
+		h.Logger.Error("failed to get monitor dns options", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitors"})
+		return
+	}
 
 	// Convert to response type
 	response := types.ListMonitorsResponse{
@@ -69,6 +86,12 @@ func (h *Handler) List(c *gin.Context) {
 			Port:                getIntPtr(m.Port),
 			DNSRecordType:       getStringPtr(m.DnsRecordType),
 			ExpectedResponse:    getStringPtr(m.ExpectedResponse),
+			Schedule:            getScheduleString(m.Schedule),
+			ScheduleTimezone:    getScheduleString(m.ScheduleTimezone),
+			NextRuns:            nextRuns(m.Schedule, m.ScheduleTimezone),
+			ProbeSelector:       selectorFromJSON(m.ProbeSelector),
+			HTTP:                httpOptions[m.ID],
+			DNS:                 dnsOptions[m.ID],
 			CreatedAt:           m.CreatedAt.Time,
 			UpdatedAt:           m.UpdatedAt.Time,
 		}
@@ -113,6 +136,18 @@ func (h *Handler) Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitor"})
 		return
 	}
+	httpExtension, err := getHTTPExtension(c, h.DB.Queries, monitor.ID)
+	if err != nil {
+		h.Logger.Error("failed to get monitor http options", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitor"})
+		return
+	}
+	dnsExtension, err := getDNSExtension(c, h.DB.Queries, monitor.ID)
+	if err != nil {
+		h.Logger.Error("failed to get monitor dns options", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitor"})
+		return
+	}
 
 	// Convert to response type
 	response := types.GetMonitorResponse{
@@ -132,6 +167,12 @@ func (h *Handler) Get(c *gin.Context) {
 			Port:                getIntPtr(monitor.Port),
 			DNSRecordType:       getStringPtr(monitor.DnsRecordType),
 			ExpectedResponse:    getStringPtr(monitor.ExpectedResponse),
+			Schedule:            getScheduleString(monitor.Schedule),
+			ScheduleTimezone:    getScheduleString(monitor.ScheduleTimezone),
+			NextRuns:            nextRuns(monitor.Schedule, monitor.ScheduleTimezone),
+			ProbeSelector:       selectorFromJSON(monitor.ProbeSelector),
+			HTTP:                httpOptionsFromExtension(httpExtension),
+			DNS:                 dnsOptionsFromExtension(dnsExtension),
 			CreatedAt:           monitor.CreatedAt.Time,
 			UpdatedAt:           monitor.UpdatedAt.Time,
 		},
@@ -166,22 +207,77 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
-	// Create monitor in database
-	monitor, err := h.DB.Queries.CreateMonitor(c, sqlc.CreateMonitorParams{
-		UserID:              uuid.MustParse(userID),
-		Name:                req.Name,
-		Type:                sqlc.MonitorType(req.Type),
-		Target:              req.Target,
-		Interval:            int32(req.Interval),
-		Timeout:             int32(req.Timeout),
-		Status:              sqlc.MonitorStatus(req.Status),
-		Locations:           req.Locations,
-		ExpectedStatusCodes: intSlice64To32(req.ExpectedStatusCodes),
-		FollowRedirects:     boolToNullBool(req.FollowRedirects),
-		VerifySsl:           boolToNullBool(req.VerifySSL),
-		Port:                intToNullInt(req.Port),
-		DnsRecordType:       stringToNullString(req.DNSRecordType),
-		ExpectedResponse:    stringToNullString(req.ExpectedResponse),
+	if req.Schedule != nil && *req.Schedule != "" {
+		schedule, err := parseSchedule(*req.Schedule, stringValue(req.ScheduleTimezone))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Interval == 0 {
+			req.Interval = scheduleInterval(schedule)
+		}
+	}
+	if req.Interval == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval is required for monitors without a schedule"})
+		return
+	}
+
+	if err := validateHTTPOptions(req.HTTP); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateDNSOptions(req.DNS, stringValue(req.DNSRecordType)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpectedResponse != nil {
+		if err := httpcheck.ValidateContentMatch(*req.ExpectedResponse, contentMatchMode(req.HTTP, nil)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Create the monitor and, for HTTP and DNS monitors, their settings
+	var monitor sqlc.Monitor
+	var httpExtension *sqlc.GetHttpExtensionRow
+	var dnsExtension *sqlc.GetDnsExtensionRow
+	err := h.DB.WithTx(c, func(q *sqlc.Queries) error {
+		var err error
+		monitor, err = q.CreateMonitor(c, sqlc.CreateMonitorParams{
+			UserID:              uuid.MustParse(userID),
+			Name:                req.Name,
+			Type:                sqlc.MonitorType(req.Type),
+			Target:              req.Target,
+			Interval:            int32(req.Interval),
+			Timeout:             int32(req.Timeout),
+			Status:              sqlc.MonitorStatus(req.Status),
+			Locations:           req.Locations,
+			ExpectedStatusCodes: intSlice64To32(req.ExpectedStatusCodes),
+			FollowRedirects:     boolToNullBool(req.FollowRedirects),
+			VerifySsl:           boolToNullBool(req.VerifySSL),
+			Port:                intToNullInt(req.Port),
+			DnsRecordType:       stringToNullString(req.DNSRecordType),
+			ExpectedResponse:    stringToNullString(req.ExpectedResponse),
+			Schedule:            stringToNullString(req.Schedule),
+			ScheduleTimezone:    stringToNullString(req.ScheduleTimezone),
+			ProbeSelector:       selectorToJSON(req.ProbeSelector),
+		})
+		if err != nil {
+			return err
+		}
+		switch monitor.Type {
+		case sqlc.MonitorTypeHttp:
+			if err := q.UpsertHttpExtension(c, httpExtensionParams(monitor, req.HTTP, nil)); err != nil {
+				return err
+			}
+			httpExtension, err = getHTTPExtension(c, q, monitor.ID)
+		case sqlc.MonitorTypeDns:
+			if err := q.UpsertDnsExtension(c, dnsExtensionParams(monitor, req.DNS)); err != nil {
+				return err
+			}
+			dnsExtension, err = getDNSExtension(c, q, monitor.ID)
+		}
+		return err
 	})
 	if err != nil {
 		h.Logger.Error("failed to create monitor", zap.Error(err))
@@ -207,6 +303,12 @@ func (h *Handler) Create(c *gin.Context) {
 			Port:                getIntPtr(monitor.Port),
 			DNSRecordType:       getStringPtr(monitor.DnsRecordType),
 			ExpectedResponse:    getStringPtr(monitor.ExpectedResponse),
+			Schedule:            getScheduleString(monitor.Schedule),
+			ScheduleTimezone:    getScheduleString(monitor.ScheduleTimezone),
+			NextRuns:            nextRuns(monitor.Schedule, monitor.ScheduleTimezone),
+			ProbeSelector:       selectorFromJSON(monitor.ProbeSelector),
+			HTTP:                httpOptionsFromExtension(httpExtension),
+			DNS:                 dnsOptionsFromExtension(dnsExtension),
 			CreatedAt:           monitor.CreatedAt.Time,
 			UpdatedAt:           monitor.UpdatedAt.Time,
 		},
@@ -249,7 +351,7 @@ func (h *Handler) Update(c *gin.Context) {
 	}
 
 	// Get existing monitor to ensure ownership
-	_, err = h.DB.Queries.GetMonitor(c, sqlc.GetMonitorParams{
+	existing, err := h.DB.Queries.GetMonitor(c, sqlc.GetMonitorParams{
 		ID:     id,
 		UserID: uuid.MustParse(userID),
 	})
@@ -258,24 +360,131 @@ func (h *Handler) Update(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitor"})
 		return
 	}
+	httpExtension, err := getHTTPExtension(c, h.DB.Queries, id)
+	if err != nil {
+		h.Logger.Error("failed to get monitor http options", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitor"})
+		return
+	}
+	dnsExtension, err := getDNSExtension(c, h.DB.Queries, id)
+	if err != nil {
+		h.Logger.Error("failed to get monitor dns options", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get monitor"})
+		return
+	}
+
+	// Validate the schedule as it will be after the update
+	if req.Schedule != nil || req.ScheduleTimezone != nil {
+		schedule, timezone := existing.Schedule.String, existing.ScheduleTimezone.String
+		if req.Schedule != nil {
+			schedule = *req.Schedule
+		}
+		if req.ScheduleTimezone != nil {
+			timezone = *req.ScheduleTimezone
+		}
+		if schedule != "" {
+			parsed, err := parseSchedule(schedule, timezone)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			// The interval follows the new schedule unless one is given
+			if req.Interval == nil {
+				interval := scheduleInterval(parsed)
+				req.Interval = &interval
+			}
+		} else if _, err := time.LoadLocation(timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schedule timezone"})
+			return
+		} else if req.Interval == nil && existing.Interval == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "interval is required for monitors without a schedule"})
+			return
+		}
+	}
+
+	if err := validateHTTPOptions(req.HTTP); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recordType := existing.DnsRecordType.String
+	if req.DNSRecordType != nil {
+		recordType = *req.DNSRecordType
+	}
+	// DNS options not being replaced must still suit the record type
+	dnsOptions := req.DNS
+	if dnsOptions == nil {
+		dnsOptions = dnsOptionsFromExtension(dnsExtension)
+	}
+	if err := validateDNSOptions(dnsOptions, recordType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Update monitor in database
-	monitor, err := h.DB.Queries.UpdateMonitor(c, sqlc.UpdateMonitorParams{
-		ID:                  id,
-		UserID:              uuid.MustParse(userID),
-		Name:                stringValue(req.Name),
-		Type:                sqlc.MonitorType(stringValue((*string)(req.Type))),
-		Target:              stringValue(req.Target),
-		Interval:            int32Value(req.Interval),
-		Timeout:             int32Value(req.Timeout),
-		Status:              sqlc.MonitorStatus(stringValue((*string)(req.Status))),
-		Locations:           req.Locations,
-		ExpectedStatusCodes: intSlice64To32(req.ExpectedStatusCodes),
-		FollowRedirects:     boolToNullBool(req.FollowRedirects),
-		VerifySsl:           boolToNullBool(req.VerifySSL),
-		Port:                intToNullInt(req.Port),
-		DnsRecordType:       stringToNullString(req.DNSRecordType),
-		ExpectedResponse:    stringToNullString(req.ExpectedResponse),
+	// Validate the content match as it will be after the update
+	if req.ExpectedResponse != nil || req.HTTP != nil {
+		match := existing.ExpectedResponse.String
+		if req.ExpectedResponse != nil {
+			match = *req.ExpectedResponse
+		}
+		if err := httpcheck.ValidateContentMatch(match, contentMatchMode(req.HTTP, httpExtension)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Update the monitor and, when they change or it has just become an HTTP
+	// or DNS monitor, its type's settings
+	var monitor sqlc.Monitor
+	err = h.DB.WithTx(c, func(q *sqlc.Queries) error {
+		var err error
+		monitor, err = q.UpdateMonitor(c, sqlc.UpdateMonitorParams{
+			ID:                  id,
+			UserID:              uuid.MustParse(userID),
+			Name:                stringValue(req.Name),
+			Type:                sqlc.MonitorType(stringValue((*string)(req.Type))),
+			Target:              stringValue(req.Target),
+			Interval:            int32Value(req.Interval),
+			Timeout:             int32Value(req.Timeout),
+			Status:              sqlc.MonitorStatus(stringValue((*string)(req.Status))),
+			Locations:           req.Locations,
+			ExpectedStatusCodes: intSlice64To32(req.ExpectedStatusCodes),
+			FollowRedirects:     boolToNullBool(req.FollowRedirects),
+			VerifySsl:           boolToNullBool(req.VerifySSL),
+			Port:                intToNullInt(req.Port),
+			DnsRecordType:       stringToNullString(req.DNSRecordType),
+			ExpectedResponse:    stringToNullString(req.ExpectedResponse),
+			Schedule:            stringToNullString(req.Schedule),
+			ScheduleTimezone:    stringToNullString(req.ScheduleTimezone),
+			ProbeSelector:       selectorToJSON(req.ProbeSelector),
+		})
+		if err != nil {
+			return err
+		}
+		switch monitor.Type {
+		case sqlc.MonitorTypeHttp:
+			if req.HTTP == nil && req.ExpectedResponse == nil && httpExtension != nil {
+				return nil
+			}
+			// A new expected response alone keeps the stored options
+			options := req.HTTP
+			if options == nil {
+				options = httpOptionsFromExtension(httpExtension)
+			}
+			if err := q.UpsertHttpExtension(c, httpExtensionParams(monitor, options, httpExtension)); err != nil {
+				return err
+			}
+			httpExtension, err = getHTTPExtension(c, q, monitor.ID)
+		case sqlc.MonitorTypeDns:
+			if req.DNS == nil && req.DNSRecordType == nil && req.ExpectedResponse == nil &&
+				req.Target == nil && dnsExtension != nil {
+				return nil
+			}
+			if err := q.UpsertDnsExtension(c, dnsExtensionParams(monitor, dnsOptions)); err != nil {
+				return err
+			}
+			dnsExtension, err = getDNSExtension(c, q, monitor.ID)
+		}
+		return err
 	})
 	if err != nil {
 		h.Logger.Error("failed to update monitor", zap.Error(err))
@@ -301,6 +510,12 @@ func (h *Handler) Update(c *gin.Context) {
 			Port:                getIntPtr(monitor.Port),
 			DNSRecordType:       getStringPtr(monitor.DnsRecordType),
 			ExpectedResponse:    getStringPtr(monitor.ExpectedResponse),
+			Schedule:            getScheduleString(monitor.Schedule),
+			ScheduleTimezone:    getScheduleString(monitor.ScheduleTimezone),
+			NextRuns:            nextRuns(monitor.Schedule, monitor.ScheduleTimezone),
+			ProbeSelector:       selectorFromJSON(monitor.ProbeSelector),
+			HTTP:                httpOptionsFromExtension(httpExtension),
+			DNS:                 dnsOptionsFromExtension(dnsExtension),
 			CreatedAt:           monitor.CreatedAt.Time,
 			UpdatedAt:           monitor.UpdatedAt.Time,
 		},
@@ -394,6 +609,67 @@ func stringToNullString(s *string) pgtype.Text {
 	return pgtype.Text{String: *s, Valid: true}
 }
 
+func selectorToJSON(selector map[string]string) []byte {
+	if selector == nil {
+		return nil
+	}
+	data, _ := json.Marshal(selector)
+	return data
+}
+
+func selectorFromJSON(data []byte) map[string]string {
+	if len(data) == 0 {
+		return nil
+	}
+	var selector map[string]string
+	if err := json.Unmarshal(data, &selector); err != nil || len(selector) == 0 {
+		return nil
+	}
+	return selector
+}
+
+// validateHTTPOptions checks what binding cannot, such as the values of
+// assertions
+func validateHTTPOptions(options *types.HTTPOptions) error {
+	if options == nil {
+		return nil
+	}
+	for i, assertion := range options.Assertions {
+		if err := httpcheck.Assertion(assertion).Validate(); err != nil {
+			return fmt.Errorf("assertion %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateDNSOptions checks that the nameserver and resolvers can be queried,
+// that the trust anchor is a DS record, and that only address records expect
+// more than one value, as the extension table keeps one for other types
+func validateDNSOptions(options *types.DNSOptions, recordType string) error {
+	if options == nil {
+		return nil
+	}
+	if !isAddressRecord(recordType) && len(options.ExpectedValues) > 1 {
+		return fmt.Errorf("%s records take one expected value", dnsRecordType(recordType))
+	}
+	if options.Nameserver != "" {
+		if _, err := dnscheck.NameserverAddress(options.Nameserver); err != nil {
+			return err
+		}
+	}
+	for _, resolver := range options.Resolvers {
+		if _, err := dnscheck.NameserverAddress(resolver); err != nil {
+			return err
+		}
+	}
+	if options.TrustAnchor != "" {
+		if _, err := dnscheck.ParseTrustAnchor(options.TrustAnchor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func getIntPtr(n pgtype.Int4) *int {
 	if !n.Valid {
 		return nil
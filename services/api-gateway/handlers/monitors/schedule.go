@@ -0,0 +1,108 @@
+package monitors
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jjkirkpatrick/monitoring/pkg/cron"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/types"
+)
+
+// previewRuns is the number of upcoming run times returned for a schedule
+const previewRuns = 5
+
+// Preview godoc
+// @Summary      Preview a monitor schedule
+// @Description  Validates a cron schedule and returns the next times it would run
+// @Tags         monitors
+// @Accept       json
+// @Produce      json
+// @Security     Bearer
+// @Param        schedule  body      types.SchedulePreviewRequest  true  "Schedule to preview"
+// @Success      200       {object}  types.SchedulePreviewResponse
+// @Failure      400       {object}  map[string]string
+// @Failure      401       {object}  map[string]string
+// @Router       /monitors/schedule/preview [post]
+func (h *Handler) Preview(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req types.SchedulePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := parseSchedule(req.Schedule, req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.SchedulePreviewResponse{
+		Schedule: schedule.String(),
+		Timezone: schedule.Location().String(),
+		NextRuns: schedule.NextN(time.Now(), previewRuns),
+	})
+}
+
+// parseSchedule parses a monitor's cron schedule and checks that it runs at
+// all. Cron runs are at least a minute apart, more than the shortest interval
+// of interval-based monitors.
+func parseSchedule(expr, timezone string) (*cron.Schedule, error) {
+	schedule, err := cron.ParseInTimezone(expr, timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %w", err)
+	}
+
+	if runs := schedule.NextN(time.Now(), previewRuns); len(runs) == 0 {
+		return nil, fmt.Errorf("invalid schedule: %q never runs", expr)
+	}
+	return schedule, nil
+}
+
+// scheduleInterval derives an interval, in seconds, for a scheduled monitor
+// from the shortest gap between its upcoming runs. The interval is used to
+// judge whether a check is overdue.
+func scheduleInterval(schedule *cron.Schedule) int {
+	runs := schedule.NextN(time.Now(), previewRuns)
+	if len(runs) < 2 {
+		return int((24 * time.Hour).Seconds())
+	}
+
+	gap := runs[1].Sub(runs[0])
+	for i := 2; i < len(runs); i++ {
+		if d := runs[i].Sub(runs[i-1]); d < gap {
+			gap = d
+		}
+	}
+	return int(gap.Seconds())
+}
+
+// nextRuns returns the upcoming run times of a stored schedule, or nil for
+// interval-based monitors
+func nextRuns(expr, timezone pgtype.Text) []time.Time {
+	if expr.String == "" {
+		return nil
+	}
+	schedule, err := cron.ParseInTimezone(expr.String, timezone.String)
+	if err != nil {
+		return nil
+	}
+	return schedule.NextN(time.Now(), previewRuns)
+}
+
+// getScheduleString treats an empty stored schedule the same as a missing one,
+// since clearing a schedule stores an empty string
+func getScheduleString(s pgtype.Text) *string {
+	if s.String == "" {
+		return nil
+	}
+	return &s.String
+}
@@ -15,10 +15,14 @@ import (
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
 	_ "github.com/jjkirkpatrick/monitoring/services/api-gateway/docs"
 	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers/admin"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers/agents"
 	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers/alerts"
 	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers/health"
+	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers/maintenance"
 	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers/monitors"
 	"github.com/jjkirkpatrick/monitoring/services/api-gateway/handlers/settings"
+	"github.com/nats-io/nats.go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -66,6 +70,18 @@ func main() {
 	}
 	defer db.Close()
 
+	// Connect to NATS
+	natsURL := os.Getenv("NATS_URL")
+	if natsURL == "" {
+		natsURL = nats.DefaultURL
+	}
+
+	nc, err := nats.Connect(natsURL)
+	if err != nil {
+		logger.Fatal("failed to connect to NATS", zap.Error(err))
+	}
+	defer nc.Close()
+
 	// Initialize Prometheus metrics
 	prometheus.MustRegister(collectors.NewBuildInfoCollector())
 
@@ -109,13 +125,16 @@ func main() {
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Initialize base handler
-	baseHandler := handlers.NewHandler(logger, db)
+	baseHandler := handlers.NewHandler(logger, db, nc)
 
 	// Initialize domain handlers
 	healthHandler := health.NewHandler(baseHandler)
 	monitorsHandler := monitors.NewHandler(baseHandler)
 	alertsHandler := alerts.NewHandler(baseHandler)
 	settingsHandler := settings.NewHandler(baseHandler)
+	maintenanceHandler := maintenance.NewHandler(baseHandler)
+	agentsHandler := agents.NewHandler(baseHandler)
+	adminHandler := admin.NewHandler(baseHandler)
 
 	// API Routes
 	v1 := router.Group("/api/v1")
@@ -134,6 +153,7 @@ func main() {
 			{
 				monitors.GET("", monitorsHandler.List)
 				monitors.POST("", monitorsHandler.Create)
+				monitors.POST("/schedule/preview", monitorsHandler.Preview)
 				monitors.GET("/:id", monitorsHandler.Get)
 				monitors.PUT("/:id", monitorsHandler.Update)
 				monitors.DELETE("/:id", monitorsHandler.Delete)
@@ -148,6 +168,15 @@ func main() {
 				alerts.DELETE("/:id", alertsHandler.Delete)
 			}
 
+			maintenance := protected.Group("/maintenance-windows")
+			{
+				maintenance.GET("", maintenanceHandler.List)
+				maintenance.POST("", maintenanceHandler.Create)
+				maintenance.GET("/:id", maintenanceHandler.Get)
+				maintenance.PUT("/:id", maintenanceHandler.Update)
+				maintenance.DELETE("/:id", maintenanceHandler.Delete)
+			}
+
 			settings := protected.Group("/settings")
 			{
 				settings.GET("", settingsHandler.Get)
@@ -158,6 +187,23 @@ func main() {
 				settings.POST("/test-webhook", settingsHandler.TestWebhook)
 				settings.POST("/reset", settingsHandler.ResetSettings)
 			}
+
+			probeAgents := protected.Group("/probe-agents")
+			{
+				probeAgents.GET("", agentsHandler.ListAgents)
+				probeAgents.GET("/tokens", agentsHandler.ListTokens)
+				probeAgents.POST("/tokens", agentsHandler.CreateToken)
+				probeAgents.DELETE("/tokens/:id", agentsHandler.RevokeToken)
+			}
+
+			admin := protected.Group("/admin")
+			admin.Use(auth.AdminMiddleware(logger))
+			{
+				admin.GET("/probe-workers", adminHandler.ListWorkers)
+				admin.POST("/probe-workers/:id/cordon", adminHandler.Cordon)
+				admin.POST("/probe-workers/:id/uncordon", adminHandler.Uncordon)
+				admin.POST("/probe-workers/:id/drain", adminHandler.Drain)
+			}
 		}
 	}
 
@@ -0,0 +1,32 @@
+package types
+
+import "time"
+
+// ProbeWorker represents a probe worker known to the probe manager
+type ProbeWorker struct {
+	ID         string            `json:"id"`
+	Status     string            `json:"status"`
+	LastSeen   time.Time         `json:"last_seen"`
+	CheckTypes []string          `json:"check_types"`
+	Region     string            `json:"region"`
+	Provider   string            `json:"provider,omitempty"`
+	IPFamily   string            `json:"ip_family,omitempty"`
+	Capacity   int               `json:"capacity,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Cordoned   bool              `json:"cordoned"`
+	Draining   bool              `json:"draining"`
+	InFlight   int               `json:"in_flight"`
+	QueueDepth int               `json:"queue_depth"`
+	LatencyMs  float64           `json:"latency_ms"`
+}
+
+// ListProbeWorkersResponse represents the response for listing probe workers
+type ListProbeWorkersResponse struct {
+	Workers []ProbeWorker `json:"workers"`
+	Total   int           `json:"total"`
+}
+
+// ProbeWorkerResponse represents the response for a command on a single probe worker
+type ProbeWorkerResponse struct {
+	Worker ProbeWorker `json:"worker"`
+}
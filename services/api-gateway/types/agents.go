@@ -0,0 +1,64 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateProbeAgentTokenRequest represents the request body for creating a private probe agent token
+type CreateProbeAgentTokenRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// ProbeAgentToken represents a token private probe agents register with. The token itself is
+// only returned when it is created.
+type ProbeAgentToken struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Revoked     bool       `json:"revoked"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateProbeAgentTokenResponse represents the response for creating a probe agent token.
+// Token is the value to set as PROBE_AGENT_TOKEN on the agent and cannot be retrieved again.
+type CreateProbeAgentTokenResponse struct {
+	AgentToken ProbeAgentToken `json:"agent_token"`
+	Token      string          `json:"token"`
+}
+
+// ProbeAgentTokenResponse represents the response for a single probe agent token
+type ProbeAgentTokenResponse struct {
+	AgentToken ProbeAgentToken `json:"agent_token"`
+}
+
+// ListProbeAgentTokensResponse represents the response for listing probe agent tokens
+type ListProbeAgentTokensResponse struct {
+	AgentTokens []ProbeAgentToken `json:"agent_tokens"`
+	Total       int               `json:"total"`
+}
+
+// ProbeAgent represents a private probe agent registered with the probe manager. Agents
+// that have not sent a heartbeat for a minute are reported as inactive.
+type ProbeAgent struct {
+	ID         string            `json:"id"`
+	TokenID    string            `json:"token_id"`
+	Status     string            `json:"status"`
+	LastSeen   time.Time         `json:"last_seen"`
+	Region     string            `json:"region"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	CheckTypes []string          `json:"check_types"`
+	Capacity   int               `json:"capacity,omitempty"`
+	InFlight   int               `json:"in_flight"`
+	QueueDepth int               `json:"queue_depth"`
+	LatencyMs  float64           `json:"latency_ms"`
+}
+
+// ListProbeAgentsResponse represents the response for listing private probe agents
+type ListProbeAgentsResponse struct {
+	Agents []ProbeAgent `json:"agents"`
+	Total  int          `json:"total"`
+}
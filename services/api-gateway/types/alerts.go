@@ -35,6 +35,7 @@ const (
 	AlertConditionSSLExpiry     AlertCondition = "ssl_expiry"
 	AlertConditionKeyword       AlertCondition = "keyword"
 	AlertConditionPattern       AlertCondition = "pattern"
+	AlertConditionDNSSECExpiry  AlertCondition = "dnssec_expiry"
 )
 
 // AlertThreshold represents the threshold configuration for an alert
@@ -43,7 +44,7 @@ type AlertThreshold struct {
 	Max           *float64 `json:"max,omitempty"`
 	ExactMatch    *string  `json:"exact_match,omitempty"`
 	Pattern       *string  `json:"pattern,omitempty"`
-	DaysInAdvance *int     `json:"days_in_advance,omitempty"` // For SSL expiry alerts
+	DaysInAdvance *int     `json:"days_in_advance,omitempty"` // For SSL and DNSSEC expiry alerts
 }
 
 // CreateAlertConfigRequest represents the request body for creating a new alert configuration
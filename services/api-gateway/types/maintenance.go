@@ -0,0 +1,85 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaintenanceAction controls what happens to checks inside a maintenance window
+type MaintenanceAction string
+
+const (
+	MaintenanceActionSkip MaintenanceAction = "skip"
+	MaintenanceActionFlag MaintenanceAction = "flag"
+)
+
+// CreateMaintenanceWindowRequest represents the request body for creating a maintenance window.
+// One-off windows need ends_at; recurring windows need recurrence (an RRULE such as
+// "FREQ=WEEKLY;BYDAY=SU;BYHOUR=2" or a cron expression) and duration_seconds.
+type CreateMaintenanceWindowRequest struct {
+	Name            string            `json:"name" binding:"required"`
+	Description     *string           `json:"description,omitempty"`
+	MonitorIDs      []uuid.UUID       `json:"monitor_ids,omitempty"`
+	GroupIDs        []uuid.UUID       `json:"group_ids,omitempty"`
+	Tags            []string          `json:"tags,omitempty"`
+	StartsAt        time.Time         `json:"starts_at" binding:"required"`
+	EndsAt          *time.Time        `json:"ends_at,omitempty"`
+	DurationSeconds *int              `json:"duration_seconds,omitempty" binding:"omitempty,min=60"`
+	Recurrence      *string           `json:"recurrence,omitempty"`
+	Timezone        *string           `json:"timezone,omitempty"`
+	Action          MaintenanceAction `json:"action,omitempty" binding:"omitempty,oneof=skip flag"`
+}
+
+// UpdateMaintenanceWindowRequest represents the request body for updating a maintenance window.
+// Setting recurrence to an empty string turns a recurring window into a one-off window.
+type UpdateMaintenanceWindowRequest struct {
+	Name            *string            `json:"name,omitempty"`
+	Description     *string            `json:"description,omitempty"`
+	MonitorIDs      *[]uuid.UUID       `json:"monitor_ids,omitempty"`
+	GroupIDs        *[]uuid.UUID       `json:"group_ids,omitempty"`
+	Tags            *[]string          `json:"tags,omitempty"`
+	StartsAt        *time.Time         `json:"starts_at,omitempty"`
+	EndsAt          *time.Time         `json:"ends_at,omitempty"`
+	DurationSeconds *int               `json:"duration_seconds,omitempty" binding:"omitempty,min=60"`
+	Recurrence      *string            `json:"recurrence,omitempty"`
+	Timezone        *string            `json:"timezone,omitempty"`
+	Action          *MaintenanceAction `json:"action,omitempty" binding:"omitempty,oneof=skip flag"`
+}
+
+// MaintenanceOccurrence represents a single period of maintenance
+type MaintenanceOccurrence struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// MaintenanceWindow represents a maintenance window entity
+type MaintenanceWindow struct {
+	ID              uuid.UUID               `json:"id"`
+	Name            string                  `json:"name"`
+	Description     *string                 `json:"description,omitempty"`
+	MonitorIDs      []uuid.UUID             `json:"monitor_ids"`
+	GroupIDs        []uuid.UUID             `json:"group_ids"`
+	Tags            []string                `json:"tags"`
+	StartsAt        time.Time               `json:"starts_at"`
+	EndsAt          *time.Time              `json:"ends_at,omitempty"`
+	DurationSeconds *int                    `json:"duration_seconds,omitempty"`
+	Recurrence      *string                 `json:"recurrence,omitempty"`
+	Timezone        string                  `json:"timezone"`
+	Action          MaintenanceAction       `json:"action"`
+	Active          bool                    `json:"active"`
+	NextOccurrences []MaintenanceOccurrence `json:"next_occurrences"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// ListMaintenanceWindowsResponse represents the response for listing maintenance windows
+type ListMaintenanceWindowsResponse struct {
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows"`
+	Total              int                 `json:"total"`
+}
+
+// GetMaintenanceWindowResponse represents the response for getting a single maintenance window
+type GetMaintenanceWindowResponse struct {
+	MaintenanceWindow MaintenanceWindow `json:"maintenance_window"`
+}
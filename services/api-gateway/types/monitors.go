@@ -25,12 +25,19 @@ const (
 	MonitorStatusError  MonitorStatus = "error"
 )
 
-// CreateMonitorRequest represents the request body for creating a new monitor
+// CreateMonitorRequest represents the request body for creating a new monitor.
+// A monitor runs either every interval seconds or, when schedule is set, at the
+// times matched by the cron expression (e.g. "*/5 9-17 * * MON-FRI") evaluated
+// in schedule_timezone. Interval may be omitted for scheduled monitors. HTTP
+// monitors check the response body for expected_response as
+// http.content_match_mode says. TCP monitors whose target starts with tls://
+// complete a TLS handshake and report the server's certificate, as HTTPS
+// monitors do.
 type CreateMonitorRequest struct {
 	Name             string       `json:"name" binding:"required"`
 	Type             MonitorType  `json:"type" binding:"required"`
 	Target           string       `json:"target" binding:"required"`
-	Interval         int          `json:"interval" binding:"required,min=30"`
+	Interval         int          `json:"interval" binding:"required_without=Schedule,omitempty,min=30"`
 	Timeout          int          `json:"timeout" binding:"required,min=5"`
 	Status           MonitorStatus `json:"status"`
 	Locations        []string     `json:"locations" binding:"required,min=1"`
@@ -38,11 +45,23 @@ type CreateMonitorRequest struct {
 	FollowRedirects  *bool        `json:"follow_redirects,omitempty"`
 	VerifySSL        *bool        `json:"verify_ssl,omitempty"`
 	Port             *int         `json:"port,omitempty"`
-	DNSRecordType    *string      `json:"dns_record_type,omitempty"`
+	DNSRecordType    *string      `json:"dns_record_type,omitempty" binding:"omitempty,oneof=A AAAA CNAME MX TXT NS SOA SRV"`
 	ExpectedResponse *string      `json:"expected_response,omitempty"`
+	Schedule         *string      `json:"schedule,omitempty"`
+	ScheduleTimezone *string      `json:"schedule_timezone,omitempty"`
+	// ProbeSelector restricts which probe workers run the checks, e.g.
+	// {"provider": "aws", "ip_family": "ipv6"}
+	ProbeSelector map[string]string `json:"probe_selector,omitempty"`
+	// HTTP sets the request HTTP monitors send
+	HTTP *HTTPOptions `json:"http,omitempty"`
+	// DNS sets the nameserver and expected answers of DNS monitors
+	DNS *DNSOptions `json:"dns,omitempty"`
 }
 
-// UpdateMonitorRequest represents the request body for updating a monitor
+// UpdateMonitorRequest represents the request body for updating a monitor.
+// Setting schedule to an empty string returns the monitor to its interval, and
+// setting probe_selector to {} lets any worker in its locations run it. A new
+// schedule without an interval derives one from its runs, as on creation.
 type UpdateMonitorRequest struct {
 	Name             *string      `json:"name,omitempty"`
 	Type             *MonitorType `json:"type,omitempty"`
@@ -55,8 +74,78 @@ type UpdateMonitorRequest struct {
 	FollowRedirects  *bool        `json:"follow_redirects,omitempty"`
 	VerifySSL        *bool        `json:"verify_ssl,omitempty"`
 	Port             *int         `json:"port,omitempty"`
-	DNSRecordType    *string      `json:"dns_record_type,omitempty"`
+	DNSRecordType    *string      `json:"dns_record_type,omitempty" binding:"omitempty,oneof=A AAAA CNAME MX TXT NS SOA SRV"`
 	ExpectedResponse *string      `json:"expected_response,omitempty"`
+	Schedule         *string      `json:"schedule,omitempty"`
+	ScheduleTimezone *string      `json:"schedule_timezone,omitempty"`
+	// ProbeSelector restricts which probe workers run the checks, e.g.
+	// {"provider": "aws", "ip_family": "ipv6"}
+	ProbeSelector map[string]string `json:"probe_selector,omitempty"`
+	// HTTP sets the request HTTP monitors send
+	HTTP *HTTPOptions `json:"http,omitempty"`
+	// DNS sets the nameserver and expected answers of DNS monitors
+	DNS *DNSOptions `json:"dns,omitempty"`
+}
+
+// HTTPOptions describes the request an HTTP monitor sends, on top of
+// expected_status_codes, follow_redirects and verify_ssl. Sending http on
+// update replaces the stored options; a basic_auth with the same username and
+// no password keeps the stored password, which is never returned.
+type HTTPOptions struct {
+	Method       string            `json:"method,omitempty" binding:"omitempty,oneof=GET HEAD POST PUT PATCH DELETE OPTIONS"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	MaxRedirects *int              `json:"max_redirects,omitempty" binding:"omitempty,min=0,max=20"`
+	BasicAuth    *HTTPBasicAuth    `json:"basic_auth,omitempty"`
+	UserAgent    string            `json:"user_agent,omitempty"`
+	Cookies      map[string]string `json:"cookies,omitempty"`
+	// ContentMatchMode says how expected_response is matched against the
+	// response body, contains by default
+	ContentMatchMode string `json:"content_match_mode,omitempty" binding:"omitempty,oneof=contains not_contains regex not_regex"`
+	// Assertions must all pass for the check to succeed
+	Assertions []HTTPAssertion `json:"assertions,omitempty" binding:"omitempty,max=50,dive"`
+	// MaxResponseTimeMs fails checks whose response takes longer
+	MaxResponseTimeMs int `json:"max_response_time_ms,omitempty" binding:"omitempty,min=1"`
+}
+
+// HTTPAssertion checks a value in the JSON response body or a response
+// header. Path is a gjson-style path such as "data.items.0.status" for json
+// and the header name for header. Value is compared with what Path selects,
+// and is a number for greater_than and less_than and one of string, number,
+// boolean, object, array or null for type.
+type HTTPAssertion struct {
+	Source   string `json:"source" binding:"required,oneof=json header"`
+	Path     string `json:"path" binding:"required"`
+	Operator string `json:"operator" binding:"required,oneof=equals not_equals contains not_contains greater_than less_than exists not_exists type"`
+	Value    string `json:"value,omitempty"`
+}
+
+// HTTPBasicAuth holds the credentials an HTTP monitor sends
+type HTTPBasicAuth struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password,omitempty"`
+}
+
+// DNSOptions describes how a DNS monitor queries and what it expects, on top
+// of dns_record_type. Nameserver is a host or host:port, port 53 by default;
+// without one the probe's own nameserver is asked. Every expected value must
+// be among the answers, written as the record data, e.g. "192.0.2.10" for A
+// or "10 mail.example.com" for MX, whose target alone also matches; record
+// types other than A and AAAA take one. Without expected_values,
+// expected_response is the single expected value.
+// check_propagation also asks every authoritative nameserver of the zone and
+// each of resolvers, e.g. ["1.1.1.1", "8.8.8.8"], and fails the check when
+// their answers or the zone's SOA serials differ. dnssec validates the
+// answers' chain of trust from trust_anchor, a DS record such as
+// "example.com. IN DS 12345 13 2 <digest>", or from the root zone's key, and
+// reports when the first signature expires.
+type DNSOptions struct {
+	Nameserver       string   `json:"nameserver,omitempty"`
+	ExpectedValues   []string `json:"expected_values,omitempty" binding:"omitempty,max=50"`
+	CheckPropagation bool     `json:"check_propagation,omitempty"`
+	Resolvers        []string `json:"resolvers,omitempty" binding:"omitempty,max=20"`
+	DNSSEC           bool     `json:"dnssec,omitempty"`
+	TrustAnchor      string   `json:"trust_anchor,omitempty"`
 }
 
 // Monitor represents a monitor entity
@@ -76,6 +165,12 @@ type Monitor struct {
 	Port              *int         `json:"port,omitempty"`
 	DNSRecordType     *string      `json:"dns_record_type,omitempty"`
 	ExpectedResponse  *string      `json:"expected_response,omitempty"`
+	Schedule          *string      `json:"schedule,omitempty"`
+	ScheduleTimezone  *string      `json:"schedule_timezone,omitempty"`
+	NextRuns          []time.Time  `json:"next_runs,omitempty"`
+	ProbeSelector     map[string]string `json:"probe_selector,omitempty"`
+	HTTP              *HTTPOptions `json:"http,omitempty"`
+	DNS               *DNSOptions  `json:"dns,omitempty"`
 	CreatedAt         time.Time    `json:"created_at"`
 	UpdatedAt         time.Time    `json:"updated_at"`
 }
@@ -109,3 +204,16 @@ type ListMonitorsResponse struct {
 type GetMonitorResponse struct {
 	Monitor Monitor `json:"monitor"`
 }
+
+// SchedulePreviewRequest represents the request body for previewing a schedule
+type SchedulePreviewRequest struct {
+	Schedule string `json:"schedule" binding:"required"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// SchedulePreviewResponse lists the next times a schedule would run
+type SchedulePreviewResponse struct {
+	Schedule string      `json:"schedule"`
+	Timezone string      `json:"timezone"`
+	NextRuns []time.Time `json:"next_runs"`
+}
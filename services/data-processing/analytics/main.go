@@ -4,35 +4,41 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
 	_ "github.com/lib/pq"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.uber.org/zap"
 )
 
 type AnalyticsService struct {
 	logger   *zap.Logger
 	natsConn *nats.Conn
+	js       jetstream.JetStream
 	db       *sql.DB
 }
 
 type CheckResultData struct {
-	Type      string    `json:"type"`
-	MonitorID string    `json:"monitor_id"`
-	Timestamp time.Time `json:"timestamp"`
-	Success   bool      `json:"success"`
-	Duration  int64     `json:"duration"`
+	Type        string    `json:"type"`
+	MonitorID   string    `json:"monitor_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Success     bool      `json:"success"`
+	Duration    int64     `json:"duration"`
+	Maintenance bool      `json:"maintenance"`
 }
 
-func NewAnalyticsService(logger *zap.Logger, natsConn *nats.Conn, db *sql.DB) *AnalyticsService {
+func NewAnalyticsService(logger *zap.Logger, natsConn *nats.Conn, js jetstream.JetStream, db *sql.DB) *AnalyticsService {
 	return &AnalyticsService{
 		logger:   logger,
 		natsConn: natsConn,
+		js:       js,
 		db:       db,
 	}
 }
@@ -43,8 +49,13 @@ func (s *AnalyticsService) Start() error {
 		return err
 	}
 
-	// Subscribe to analytics data
-	if _, err := s.natsConn.Subscribe("analytics.ingest", s.handleAnalyticsData); err != nil {
+	// Consume analytics data
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := pipeline.Consume(ctx, s.js, s.logger, pipeline.ConsumerConfig{
+		Stream:  pipeline.StreamAnalytics,
+		Durable: "analytics",
+	}, s.handleAnalyticsData); err != nil {
 		return err
 	}
 
@@ -105,19 +116,19 @@ func (s *AnalyticsService) initDatabase() error {
 	return err
 }
 
-func (s *AnalyticsService) handleAnalyticsData(msg *nats.Msg) {
+func (s *AnalyticsService) handleAnalyticsData(payload []byte) error {
 	var data CheckResultData
-	if err := json.Unmarshal(msg.Data, &data); err != nil {
-		s.logger.Error("failed to unmarshal analytics data", zap.Error(err))
-		return
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return pipeline.Permanent(fmt.Errorf("failed to unmarshal analytics data: %w", err))
 	}
 
 	if data.Type != "check_result" {
-		return // Only process check results for now
+		return nil // Only process check results for now
 	}
 
 	// Store raw data for future analysis if needed
 	// This could be stored in a separate table or sent to another system
+	return nil
 }
 
 func (s *AnalyticsService) runHourlyAggregation() {
@@ -164,7 +175,9 @@ func (s *AnalyticsService) aggregateHourlyStats() error {
 		FROM check_results
 		WHERE
 			timestamp >= date_trunc('hour', NOW() - INTERVAL '1 hour') AND
-			timestamp < date_trunc('hour', NOW())
+			timestamp < date_trunc('hour', NOW()) AND
+			-- Checks taken during maintenance windows do not count towards uptime
+			NOT maintenance
 		GROUP BY monitor_id, date_trunc('hour', timestamp)
 		ON CONFLICT (monitor_id, hour) DO UPDATE SET
 			total_checks = EXCLUDED.total_checks,
@@ -254,8 +267,20 @@ func main() {
 	}
 	defer nc.Close()
 
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Fatal("failed to create JetStream context", zap.Error(err))
+	}
+
+	streamCtx, streamCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pipeline.EnsureStreams(streamCtx, js)
+	streamCancel()
+	if err != nil {
+		logger.Fatal("failed to create pipeline streams", zap.Error(err))
+	}
+
 	// Create and start analytics service
-	service := NewAnalyticsService(logger, nc, db)
+	service := NewAnalyticsService(logger, nc, js, db)
 	if err := service.Start(); err != nil {
 		logger.Fatal("failed to start analytics service", zap.Error(err))
 	}
@@ -4,38 +4,64 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jjkirkpatrick/monitoring/internal/database"
+	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	"github.com/jjkirkpatrick/monitoring/pkg/certcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
+	"github.com/jjkirkpatrick/monitoring/pkg/probeauth"
 	_ "github.com/lib/pq" // Import postgres driver
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.uber.org/zap"
 )
 
 type CheckResult struct {
-	MonitorID string            `json:"monitor_id"`
-	WorkerID  string            `json:"worker_id"`
-	Timestamp time.Time         `json:"timestamp"`
-	Duration  int64             `json:"duration"`
-	Success   bool              `json:"success"`
-	Error     string            `json:"error,omitempty"`
-	Details   map[string]string `json:"details,omitempty"`
+	MonitorID           string            `json:"monitor_id"`
+	WorkerID            string            `json:"worker_id"`
+	Location            string            `json:"location,omitempty"`
+	RetriedBy           string            `json:"retried_by,omitempty"`
+	Timestamp           time.Time         `json:"timestamp"`
+	Duration            int64             `json:"duration"`
+	Success             bool              `json:"success"`
+	Error               string            `json:"error,omitempty"`
+	Details             map[string]string `json:"details,omitempty"`
+	Maintenance         bool              `json:"maintenance,omitempty"`
+	MaintenanceWindowID string            `json:"maintenance_window_id,omitempty"`
 }
 
 type IngestionService struct {
 	logger   *zap.Logger
 	natsConn *nats.Conn
+	js       jetstream.JetStream
 	db       *sql.DB
+	// monitorDB holds monitor_results, which the API reads per-location
+	// stats from
+	monitorDB *database.DB
+	// verifier rejects results not signed by a registered worker
+	verifier *probeauth.Verifier
 }
 
-func NewIngestionService(logger *zap.Logger, natsConn *nats.Conn, db *sql.DB) *IngestionService {
+func NewIngestionService(logger *zap.Logger, natsConn *nats.Conn, js jetstream.JetStream, db *sql.DB, monitorDB *database.DB, verifier *probeauth.Verifier) *IngestionService {
 	return &IngestionService{
-		logger:   logger,
-		natsConn: natsConn,
-		db:       db,
+		logger:    logger,
+		natsConn:  natsConn,
+		js:        js,
+		db:        db,
+		monitorDB: monitorDB,
+		verifier:  verifier,
 	}
 }
 
@@ -45,8 +71,14 @@ func (s *IngestionService) Start() error {
 		return err
 	}
 
-	// Subscribe to check results
-	if _, err := s.natsConn.Subscribe("probes.check.result", s.handleCheckResult); err != nil {
+	// Consume check results durably, so results published while the service
+	// is down are stored once it is back
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := pipeline.ConsumeMsg(ctx, s.js, s.logger, pipeline.ConsumerConfig{
+		Stream:  pipeline.StreamCheckResults,
+		Durable: "ingestion",
+	}, s.handleCheckResult); err != nil {
 		return err
 	}
 
@@ -63,17 +95,28 @@ func (s *IngestionService) initDatabase() error {
 			duration BIGINT NOT NULL,
 			success BOOLEAN NOT NULL,
 			error TEXT,
-			details JSONB,
-			PRIMARY KEY (monitor_id, timestamp)
+			details JSONB
 		);
 
+		ALTER TABLE check_results ADD COLUMN IF NOT EXISTS maintenance BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE check_results ADD COLUMN IF NOT EXISTS location TEXT;
+		ALTER TABLE check_results ADD COLUMN IF NOT EXISTS retried_by TEXT;
+
 		SELECT create_hypertable('check_results', 'timestamp', 
 			chunk_time_interval => INTERVAL '1 day',
 			if_not_exists => TRUE
 		);
 
+		-- Locations check independently, so several may report the same
+		-- monitor at the same time. Redeliveries of one location's result
+		-- still collide, which is what lets the insert skip them.
+		CREATE UNIQUE INDEX IF NOT EXISTS check_results_monitor_location_time_key
+			ON check_results (monitor_id, location, timestamp) NULLS NOT DISTINCT;
+		ALTER TABLE check_results DROP CONSTRAINT IF EXISTS check_results_pkey;
+
 		CREATE INDEX IF NOT EXISTS idx_check_results_monitor_success ON check_results (monitor_id, success, timestamp DESC);
 		CREATE INDEX IF NOT EXISTS idx_check_results_timestamp ON check_results (timestamp DESC);
+		CREATE INDEX IF NOT EXISTS idx_check_results_monitor_location ON check_results (monitor_id, location, timestamp DESC);
 	`)
 	if err != nil {
 		return err
@@ -82,24 +125,39 @@ func (s *IngestionService) initDatabase() error {
 	return nil
 }
 
-func (s *IngestionService) handleCheckResult(msg *nats.Msg) {
+// handleCheckResult stores a result and forwards it to analytics. Results
+// that fail verification go to the dead-letter stream. Any other failure
+// returns an error so the result is redelivered; both inserts ignore rows
+// already stored by an earlier delivery, so only the failed steps take effect.
+func (s *IngestionService) handleCheckResult(msg jetstream.Msg) error {
+	worker, err := s.verifier.Verify(msg)
+	if err != nil {
+		return verifyError(err)
+	}
+
 	var result CheckResult
-	if err := json.Unmarshal(msg.Data, &result); err != nil {
-		s.logger.Error("failed to unmarshal check result", zap.Error(err))
-		return
+	if err := json.Unmarshal(msg.Data(), &result); err != nil {
+		return pipeline.Permanent(fmt.Errorf("failed to unmarshal check result: %w", err))
+	}
+
+	// Private agents may only report on their own account's monitors
+	if worker.AccountID != "" {
+		if err := s.checkOwner(result.MonitorID, worker.AccountID); err != nil {
+			return verifyError(err)
+		}
 	}
 
 	// Store result in TimescaleDB
 	details, err := json.Marshal(result.Details)
 	if err != nil {
-		s.logger.Error("failed to marshal details", zap.Error(err))
-		return
+		return pipeline.Permanent(fmt.Errorf("failed to marshal details: %w", err))
 	}
 
 	_, err = s.db.Exec(`
 		INSERT INTO check_results (
-			monitor_id, worker_id, timestamp, duration, success, error, details
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			monitor_id, worker_id, timestamp, duration, success, error, details, maintenance, location, retried_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''))
+		ON CONFLICT DO NOTHING`,
 		result.MonitorID,
 		result.WorkerID,
 		result.Timestamp,
@@ -107,25 +165,119 @@ func (s *IngestionService) handleCheckResult(msg *nats.Msg) {
 		result.Success,
 		result.Error,
 		details,
+		result.Maintenance,
+		result.Location,
+		result.RetriedBy,
 	)
 	if err != nil {
-		s.logger.Error("failed to insert check result",
-			zap.Error(err),
-			zap.String("monitor_id", result.MonitorID))
-		return
+		return fmt.Errorf("failed to insert check result for monitor %s: %w", result.MonitorID, err)
+	}
+
+	// monitor_results feeds uptime and the other stats users see, which
+	// checks flagged by a maintenance window must not count against. They
+	// are kept in check_results with the flag set.
+	if !result.Maintenance {
+		if err := s.recordMonitorResult(result); err != nil {
+			return fmt.Errorf("failed to record monitor result for monitor %s: %w", result.MonitorID, err)
+		}
 	}
 
 	// Forward to analytics service
 	analyticsData, _ := json.Marshal(map[string]interface{}{
-		"type":       "check_result",
-		"monitor_id": result.MonitorID,
-		"timestamp":  result.Timestamp,
-		"success":    result.Success,
-		"duration":   result.Duration,
+		"type":        "check_result",
+		"monitor_id":  result.MonitorID,
+		"location":    result.Location,
+		"timestamp":   result.Timestamp,
+		"success":     result.Success,
+		"duration":    result.Duration,
+		"maintenance": result.Maintenance,
 	})
-	if err := s.natsConn.Publish("analytics.ingest", analyticsData); err != nil {
-		s.logger.Error("failed to forward to analytics", zap.Error(err))
+	if err := pipeline.Publish(s.js, pipeline.SubjectAnalyticsIngest, analyticsData); err != nil {
+		return fmt.Errorf("failed to forward to analytics: %w", err)
 	}
+
+	return nil
+}
+
+// checkOwner rejects the result unless the monitor belongs to the account
+func (s *IngestionService) checkOwner(monitorID, accountID string) error {
+	monitor, err := uuid.Parse(monitorID)
+	if err != nil {
+		return probeauth.Reject(probeauth.ReasonAccountMismatch, fmt.Errorf("invalid monitor id %q", monitorID))
+	}
+	account, err := uuid.Parse(accountID)
+	if err != nil {
+		return probeauth.Reject(probeauth.ReasonAccountMismatch, fmt.Errorf("invalid account id %q", accountID))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = s.monitorDB.Queries.GetMonitor(ctx, sqlc.GetMonitorParams{ID: monitor, UserID: account})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return probeauth.Reject(probeauth.ReasonAccountMismatch,
+			fmt.Errorf("monitor %s does not belong to account %s", monitorID, accountID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up monitor %s: %w", monitorID, err)
+	}
+	return nil
+}
+
+// verifyError sends rejected results to the dead-letter stream and retries
+// failures to verify them
+func verifyError(err error) error {
+	if probeauth.IsRejected(err) {
+		return pipeline.Permanent(err)
+	}
+	return err
+}
+
+// recordMonitorResult stores the result in monitor_results, keyed by the
+// location it was taken from and the time the check ran
+func (s *IngestionService) recordMonitorResult(result CheckResult) error {
+	monitorID, err := uuid.Parse(result.MonitorID)
+	if err != nil {
+		return pipeline.Permanent(err)
+	}
+
+	params := sqlc.CreateMonitorResultParams{
+		MonitorID:         monitorID,
+		Time:              pgtype.Timestamptz{Time: result.Timestamp, Valid: true},
+		Location:          result.Location,
+		Success:           result.Success,
+		Latency:           pgtype.Int4{Int32: int32(result.Duration), Valid: true},
+		TotalTime:         pgtype.Int4{Int32: int32(result.Duration), Valid: true},
+		StatusCode:        detailInt(result.Details, "status_code"),
+		ResponseSize:      detailInt(result.Details, httpcheck.DetailResponseSize),
+		DnsResolutionTime: detailInt(result.Details, httpcheck.DetailDNSTime),
+		ConnectTime:       detailInt(result.Details, httpcheck.DetailConnectTime),
+		TlsHandshakeTime:  detailInt(result.Details, httpcheck.DetailTLSTime),
+		FirstByteTime:     detailInt(result.Details, httpcheck.DetailFirstByteTime),
+	}
+	if result.Error != "" {
+		params.ErrorMessage = pgtype.Text{String: result.Error, Valid: true}
+	}
+	if expiry, ok := certcheck.Expiry(result.Details); ok {
+		params.CertificateExpiry = pgtype.Timestamptz{Time: expiry, Valid: true}
+	}
+	// HTTP checks time the request itself, without the worker's overhead
+	if total := detailInt(result.Details, httpcheck.DetailResponseTime); total.Valid {
+		params.TotalTime = total
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.monitorDB.Queries.CreateMonitorResult(ctx, params)
+}
+
+// detailInt reads a numeric detail of a check result, which is NULL when the
+// check did not report it
+func detailInt(details map[string]string, key string) pgtype.Int4 {
+	n, err := strconv.ParseInt(details[key], 10, 32)
+	if err != nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(n), Valid: true}
 }
 
 func main() {
@@ -166,8 +318,43 @@ func main() {
 	}
 	defer nc.Close()
 
+	// Connect to the monitors database
+	monitorDB, err := database.New(context.Background())
+	if err != nil {
+		logger.Fatal("failed to initialize database", zap.Error(err))
+	}
+	defer monitorDB.Close()
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Fatal("failed to create JetStream context", zap.Error(err))
+	}
+
+	streamCtx, streamCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pipeline.EnsureStreams(streamCtx, js)
+	streamCancel()
+	if err != nil {
+		logger.Fatal("failed to create pipeline streams", zap.Error(err))
+	}
+
+	// Only results signed by registered workers are stored
+	maxAge := probeauth.DefaultMaxAge
+	if v := os.Getenv("RESULT_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid RESULT_MAX_AGE", zap.Error(err))
+		}
+		maxAge = d
+	}
+	verifyCtx, verifyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	verifier, err := probeauth.NewVerifier(verifyCtx, js, logger, maxAge)
+	verifyCancel()
+	if err != nil {
+		logger.Fatal("failed to load probe worker registry", zap.Error(err))
+	}
+
 	// Create and start ingestion service
-	service := NewIngestionService(logger, nc, db)
+	service := NewIngestionService(logger, nc, js, db, monitorDB, verifier)
 	if err := service.Start(); err != nil {
 		logger.Fatal("failed to start ingestion service", zap.Error(err))
 	}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jjkirkpatrick/monitoring/pkg/auth"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// workerRegistration is the body of probes.register. Private agents send the
+// token they were issued, which is checked on every registration and never
+// kept in the registry. Shared workers send a proof that they hold the
+// operator's registration secret instead.
+type workerRegistration struct {
+	ProbeWorker
+	Token string `json:"token,omitempty"`
+	Proof string `json:"proof,omitempty"`
+}
+
+// errRejected wraps the reasons a registration is refused
+var errRejected = errors.New("registration rejected")
+
+// authenticate checks a private agent's token and ties the worker to the
+// token's account. Shared workers carry no token, may not claim an account
+// and must prove they hold the registration secret for their ID and key.
+func (pm *ProbeManager) authenticate(registration *workerRegistration) error {
+	worker := &registration.ProbeWorker
+	if registration.Token == "" {
+		if worker.AccountID != "" {
+			return fmt.Errorf("%w: account_id given without an agent token", errRejected)
+		}
+		if pm.registrationSecret == "" {
+			return fmt.Errorf("%w: shared workers are not accepted without a registration secret", errRejected)
+		}
+		if !auth.VerifyRegistrationProof(pm.registrationSecret, worker.ID, worker.PublicKey, registration.Proof) {
+			return fmt.Errorf("%w: missing or invalid registration proof", errRejected)
+		}
+		worker.TokenID = ""
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token, err := pm.db.Queries.GetActiveProbeAgentToken(ctx, auth.HashAgentToken(registration.Token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: invalid or revoked agent token", errRejected)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up agent token: %w", err)
+	}
+
+	accountID := token.UserID.String()
+	if worker.AccountID != "" && worker.AccountID != accountID {
+		return fmt.Errorf("%w: agent token belongs to a different account", errRejected)
+	}
+	worker.AccountID = accountID
+	worker.TokenID = token.ID.String()
+
+	if err := pm.db.Queries.UpdateProbeAgentTokenLastUsed(ctx, token.ID); err != nil {
+		pm.logger.Warn("failed to update agent token last used",
+			zap.String("token_id", worker.TokenID),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// rejectRegistration tells a worker why it was not registered, so that a
+// misconfigured agent says so in its own logs
+func (pm *ProbeManager) rejectRegistration(workerID string, err error) {
+	pm.logger.Warn("worker registration rejected",
+		zap.String("worker_id", workerID),
+		zap.Error(err))
+	registrationsRejected.Inc()
+
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	if err := pm.natsConn.Publish("probes.rejected."+workerID, data); err != nil {
+		pm.logger.Error("failed to notify rejected worker",
+			zap.String("worker_id", workerID),
+			zap.Error(err))
+	}
+}
+
+// AgentTokenRevoked is published by the API gateway on probes.agents.revoked
+type AgentTokenRevoked struct {
+	TokenID string `json:"token_id"`
+}
+
+// handleTokenRevoked drops the agents registered with a revoked token and
+// moves the checks they have not started elsewhere. An agent that keeps
+// sending heartbeats is asked to register again, which then fails.
+func (pm *ProbeManager) handleTokenRevoked(msg *nats.Msg) {
+	var event AgentTokenRevoked
+	if err := json.Unmarshal(msg.Data, &event); err != nil || event.TokenID == "" {
+		pm.logger.Error("invalid agent token revocation", zap.ByteString("data", msg.Data))
+		return
+	}
+
+	var removed []string
+	pm.mu.Lock()
+	for id, worker := range pm.workers {
+		if worker.TokenID == event.TokenID {
+			delete(pm.workers, id)
+			forgetWorker(worker)
+			removed = append(removed, id)
+		}
+	}
+	pm.mu.Unlock()
+
+	for _, id := range removed {
+		pm.removeWorker(id)
+		pm.reassignQueued(id)
+		pm.logger.Info("removed agent with revoked token",
+			zap.String("worker_id", id),
+			zap.String("token_id", event.TokenID))
+	}
+}
+
+// AgentListRequest is the body of probes.agents.list
+type AgentListRequest struct {
+	AccountID string `json:"account_id"`
+}
+
+// handleListAgents replies with the private agents of one account, ordered
+// by ID
+func (pm *ProbeManager) handleListAgents(msg *nats.Msg) {
+	var request AgentListRequest
+	if err := json.Unmarshal(msg.Data, &request); err != nil || request.AccountID == "" {
+		data, _ := json.Marshal(WorkerList{Workers: []ProbeWorker{}})
+		msg.Respond(data)
+		return
+	}
+
+	pm.mu.RLock()
+	list := WorkerList{Workers: []ProbeWorker{}}
+	for _, worker := range pm.workers {
+		if worker.AccountID == request.AccountID {
+			list.Workers = append(list.Workers, *worker)
+		}
+	}
+	pm.mu.RUnlock()
+
+	sort.Slice(list.Workers, func(i, j int) bool {
+		return list.Workers[i].ID < list.Workers[j].ID
+	})
+
+	data, _ := json.Marshal(list)
+	if err := msg.Respond(data); err != nil {
+		pm.logger.Error("failed to reply with agent list", zap.Error(err))
+	}
+}
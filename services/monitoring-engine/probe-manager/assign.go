@@ -0,0 +1,341 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+const (
+	// ackTimeout is how long a worker has to start a check before it is
+	// given to another worker. Workers ack when a slot frees up, so this
+	// also bounds how long a check may queue.
+	ackTimeout = 15 * time.Second
+	// deadlineSlack is added to the check's own timeout to allow for
+	// publishing the result
+	deadlineSlack = 10 * time.Second
+	// maxAttempts caps how many workers a check is tried on
+	maxAttempts = 3
+	// defaultCheckTimeout applies when the request's timeout cannot be parsed
+	defaultCheckTimeout = 10 * time.Second
+	// requestAckWait is how long a check request may go without progress
+	// before it is redelivered. Requests held back by target limits report
+	// progress every requestProgressInterval.
+	requestAckWait          = 30 * time.Second
+	requestProgressInterval = 10 * time.Second
+	// requestRetryDelay is how long a request that could not be handed to
+	// any worker waits before it is redelivered
+	requestRetryDelay = 30 * time.Second
+)
+
+// checkRequestConsumer is the durable consumer of check requests
+var checkRequestConsumer = pipeline.ConsumerConfig{
+	Stream:  pipeline.StreamCheckRequests,
+	Durable: "probe-manager",
+	AckWait: requestAckWait,
+}
+
+// assignment is a check sent to a worker and awaiting its result
+type assignment struct {
+	id       string
+	request  CheckRequest
+	selector map[string]string
+
+	workerID string
+	attempt  int
+	tried    map[string]bool
+	// lostBy is the worker the previous attempt was lost on
+	lostBy string
+
+	acked    bool
+	deadline time.Time
+
+	// target is the host the check runs against, which politeness limits
+	// are kept per
+	target   string
+	queuedAt time.Time
+	heldBack bool
+
+	// msg is the check request, left unacked until the check has first
+	// been handed to a worker
+	msg jetstream.Msg
+}
+
+// checkAssignment is the message published to the chosen worker
+type checkAssignment struct {
+	CheckRequest
+	WorkerID     string `json:"worker_id"`
+	AssignmentID string `json:"assignment_id"`
+	Attempt      int    `json:"attempt"`
+	// RetriedBy is set on reassigned checks to the worker now running them;
+	// workers echo it on the result
+	RetriedBy string `json:"retried_by,omitempty"`
+}
+
+func newAssignment(request CheckRequest) *assignment {
+	// The location is matched against the worker's region like any other
+	// selector term
+	selector := make(map[string]string, len(request.Selector)+1)
+	for key, value := range request.Selector {
+		selector[key] = value
+	}
+	if request.Location != "" {
+		selector["region"] = request.Location
+	}
+
+	return &assignment{
+		id:       uuid.New().String(),
+		request:  request,
+		selector: selector,
+		tried:    make(map[string]bool),
+	}
+}
+
+// timeout returns the check's own timeout
+func (a *assignment) timeout() time.Duration {
+	timeout, err := time.ParseDuration(a.request.Timeout)
+	if err != nil || timeout <= 0 {
+		return defaultCheckTimeout
+	}
+	return timeout
+}
+
+// assign sends the check to the best available worker that has not already
+// been tried and tracks it until its result arrives
+func (pm *ProbeManager) assign(a *assignment) {
+	worker, reason := pm.selectWorker(a.request.Type, a.request.AccountID, a.selector, a.tried)
+	if worker == nil {
+		if a.attempt == 0 {
+			pm.publishUnroutable(UnroutableCheck{
+				MonitorID: a.request.MonitorID,
+				Type:      a.request.Type,
+				Location:  a.request.Location,
+				Selector:  a.request.Selector,
+				Reason:    reason,
+				Timestamp: time.Now(),
+			})
+			pm.ackRequest(a)
+			pm.finish(a)
+			return
+		}
+		pm.loseAssignment(a, reason)
+		return
+	}
+
+	a.workerID = worker.ID
+	a.attempt++
+	a.tried[worker.ID] = true
+	a.acked = false
+	a.deadline = time.Now().Add(ackTimeout)
+
+	message := checkAssignment{
+		CheckRequest: a.request,
+		WorkerID:     worker.ID,
+		AssignmentID: a.id,
+		Attempt:      a.attempt,
+	}
+	if a.attempt > 1 {
+		message.RetriedBy = worker.ID
+	}
+
+	pm.assignMu.Lock()
+	pm.assignments[a.id] = a
+	assignmentsInFlight.Set(float64(len(pm.assignments)))
+	pm.assignMu.Unlock()
+
+	data, _ := json.Marshal(message)
+	subject := pipeline.SubjectCheckAssignPrefix + worker.ID
+	if err := pipeline.Publish(pm.js, subject, data); err != nil {
+		// Left in place; the ack timeout will move it to another worker
+		pm.logger.Error("failed to publish check assignment",
+			zap.String("worker_id", worker.ID),
+			zap.String("assignment_id", a.id),
+			zap.Error(err))
+		return
+	}
+	// The assignments stream now holds the check
+	pm.ackRequest(a)
+}
+
+// ackRequest acks the check request an assignment came from, once
+func (pm *ProbeManager) ackRequest(a *assignment) {
+	if a.msg == nil {
+		return
+	}
+	if err := a.msg.Ack(); err != nil {
+		pm.logger.Error("failed to ack check request",
+			zap.String("monitor_id", a.request.MonitorID),
+			zap.Error(err))
+	}
+	a.msg = nil
+}
+
+// handleCheckAck extends the deadline of an assignment its worker has started
+func (pm *ProbeManager) handleCheckAck(msg *nats.Msg) {
+	var ack struct {
+		AssignmentID string `json:"assignment_id"`
+		WorkerID     string `json:"worker_id"`
+	}
+	if err := json.Unmarshal(msg.Data, &ack); err != nil {
+		pm.logger.Error("failed to unmarshal check ack", zap.Error(err))
+		return
+	}
+
+	pm.assignMu.Lock()
+	defer pm.assignMu.Unlock()
+
+	a, exists := pm.assignments[ack.AssignmentID]
+	if !exists || a.workerID != ack.WorkerID {
+		// Already completed, or an ack from a worker it was taken away from
+		return
+	}
+	a.acked = true
+	a.deadline = time.Now().Add(a.timeout() + deadlineSlack)
+}
+
+// handleCheckResult completes the assignment a result belongs to and releases
+// it from the worker's load. Only a result signed by the worker the check is
+// assigned to completes it, so nobody else can cancel a check.
+func (pm *ProbeManager) handleCheckResult(msg *nats.Msg) {
+	worker, err := pm.verifier.VerifyMsg(msg)
+	if err != nil {
+		pm.logger.Warn("ignoring unverified check result", zap.Error(err))
+		return
+	}
+
+	var result struct {
+		WorkerID     string `json:"worker_id"`
+		AssignmentID string `json:"assignment_id"`
+	}
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		return
+	}
+
+	if result.AssignmentID != "" {
+		pm.assignMu.Lock()
+		a, exists := pm.assignments[result.AssignmentID]
+		if exists && a.workerID != worker.ID {
+			// A late result from a worker the check was taken away from
+			exists = false
+		} else {
+			delete(pm.assignments, result.AssignmentID)
+		}
+		assignmentsInFlight.Set(float64(len(pm.assignments)))
+		pm.assignMu.Unlock()
+
+		if exists {
+			pm.finish(a)
+		}
+	}
+
+	pm.releaseWorker(worker.ID)
+}
+
+// releaseWorker takes one assignment off the worker's outstanding count
+func (pm *ProbeManager) releaseWorker(workerID string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if worker, exists := pm.workers[workerID]; exists && worker.outstanding > 0 {
+		worker.outstanding--
+		workerLoad.WithLabelValues(worker.ID, worker.Region).Set(worker.load())
+	}
+}
+
+// reassignExpired periodically moves assignments that missed their deadline
+// to another worker. A late result from the original worker is still
+// published, so a reassigned check can occasionally report twice.
+func (pm *ProbeManager) reassignExpired() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+
+		var expired []*assignment
+		pm.assignMu.Lock()
+		for id, a := range pm.assignments {
+			if now.After(a.deadline) {
+				expired = append(expired, a)
+				delete(pm.assignments, id)
+			}
+		}
+		assignmentsInFlight.Set(float64(len(pm.assignments)))
+		pm.assignMu.Unlock()
+
+		for _, a := range expired {
+			reason := "ack_timeout"
+			if a.acked {
+				reason = "deadline"
+			}
+			assignmentsExpired.WithLabelValues(reason).Inc()
+			pm.logger.Warn("check assignment expired",
+				zap.String("assignment_id", a.id),
+				zap.String("monitor_id", a.request.MonitorID),
+				zap.String("worker_id", a.workerID),
+				zap.Int("attempt", a.attempt),
+				zap.String("reason", reason))
+
+			pm.releaseWorker(a.workerID)
+			pm.retry(a)
+		}
+	}
+}
+
+// reassignQueued moves the checks a worker has not started yet to other
+// workers. Checks it is already running are left to finish.
+func (pm *ProbeManager) reassignQueued(workerID string) {
+	var queued []*assignment
+	pm.assignMu.Lock()
+	for id, a := range pm.assignments {
+		if a.workerID == workerID && !a.acked {
+			queued = append(queued, a)
+			delete(pm.assignments, id)
+		}
+	}
+	assignmentsInFlight.Set(float64(len(pm.assignments)))
+	pm.assignMu.Unlock()
+
+	for _, a := range queued {
+		pm.releaseWorker(a.workerID)
+		pm.retry(a)
+	}
+}
+
+// retry gives an assignment taken from its worker to another one, unless it
+// has run out of attempts
+func (pm *ProbeManager) retry(a *assignment) {
+	a.lostBy = a.workerID
+	if a.attempt >= maxAttempts {
+		pm.loseAssignment(a, "max_attempts")
+		return
+	}
+	assignmentsRetried.Inc()
+	pm.assign(a)
+}
+
+// loseAssignment gives up on a check that no worker completed
+func (pm *ProbeManager) loseAssignment(a *assignment, reason string) {
+	assignmentsLost.WithLabelValues(reason).Inc()
+	pm.logger.Error("check assignment lost",
+		zap.String("assignment_id", a.id),
+		zap.String("monitor_id", a.request.MonitorID),
+		zap.String("last_worker_id", a.lostBy),
+		zap.Int("attempts", a.attempt),
+		zap.String("reason", reason))
+	// A check never handed to any worker is tried again later
+	if a.msg != nil {
+		if err := a.msg.NakWithDelay(requestRetryDelay); err != nil {
+			pm.logger.Error("failed to nak check request",
+				zap.String("monitor_id", a.request.MonitorID),
+				zap.Error(err))
+		}
+		a.msg = nil
+	}
+	pm.finish(a)
+}
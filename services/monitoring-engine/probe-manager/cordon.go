@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// WorkerCommand is the request body for probes.workers.cordon, uncordon and
+// drain
+type WorkerCommand struct {
+	WorkerID string `json:"worker_id"`
+}
+
+// WorkerCommandReply carries the worker as it is after the command, or an
+// error if there is no such worker
+type WorkerCommandReply struct {
+	Worker *ProbeWorker `json:"worker,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// handleCordon stops new checks going to a worker. Checks it already has are
+// left alone.
+func (pm *ProbeManager) handleCordon(msg *nats.Msg) {
+	pm.handleWorkerCommand(msg, "cordon", func(worker *ProbeWorker) {
+		worker.Cordoned = true
+	})
+}
+
+// handleUncordon returns a cordoned or drained worker to rotation
+func (pm *ProbeManager) handleUncordon(msg *nats.Msg) {
+	worker := pm.handleWorkerCommand(msg, "uncordon", func(worker *ProbeWorker) {
+		worker.Cordoned = false
+		worker.Draining = false
+	})
+	if worker != nil {
+		pm.notifyWorker("probes.resume.", worker.ID)
+	}
+}
+
+// handleDrain cordons a worker, tells it to stop pulling checks and moves the
+// checks it has not started to other workers. Once its in-flight count
+// reaches zero the worker can be stopped without losing anything.
+func (pm *ProbeManager) handleDrain(msg *nats.Msg) {
+	worker := pm.handleWorkerCommand(msg, "drain", func(worker *ProbeWorker) {
+		worker.Cordoned = true
+		worker.Draining = true
+	})
+	if worker != nil {
+		pm.notifyWorker("probes.drain.", worker.ID)
+		pm.reassignQueued(worker.ID)
+	}
+}
+
+// handleWorkerCommand applies update to the worker named in the request,
+// saves it and replies with the result. It returns the updated worker, or nil
+// if there is none.
+func (pm *ProbeManager) handleWorkerCommand(msg *nats.Msg, command string, update func(*ProbeWorker)) *ProbeWorker {
+	var request WorkerCommand
+	if err := json.Unmarshal(msg.Data, &request); err != nil {
+		pm.reply(msg, WorkerCommandReply{Error: "invalid request"})
+		return nil
+	}
+
+	pm.mu.Lock()
+	worker, exists := pm.workers[request.WorkerID]
+	if !exists {
+		pm.mu.Unlock()
+		pm.reply(msg, WorkerCommandReply{Error: "worker not found"})
+		return nil
+	}
+	update(worker)
+	snapshot := *worker
+	pm.mu.Unlock()
+
+	pm.saveWorker(snapshot)
+	pm.logger.Info("worker "+command,
+		zap.String("worker_id", snapshot.ID),
+		zap.Bool("cordoned", snapshot.Cordoned),
+		zap.Bool("draining", snapshot.Draining))
+
+	pm.reply(msg, WorkerCommandReply{Worker: &snapshot})
+	return &snapshot
+}
+
+// handleWorkerDeregistration retires a worker that is shutting down and moves
+// the checks it has not started to other workers. Workers deregister once
+// their last results are published.
+func (pm *ProbeManager) handleWorkerDeregistration(msg *nats.Msg) {
+	var request WorkerCommand
+	if err := json.Unmarshal(msg.Data, &request); err != nil {
+		pm.logger.Error("failed to unmarshal worker deregistration", zap.Error(err))
+		return
+	}
+
+	pm.mu.Lock()
+	worker, exists := pm.workers[request.WorkerID]
+	if exists {
+		delete(pm.workers, worker.ID)
+		forgetWorker(worker)
+	}
+	pm.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	pm.retireWorker(*worker)
+	pm.reassignQueued(worker.ID)
+	pm.logger.Info("worker deregistered", zap.String("worker_id", worker.ID))
+}
+
+// notifyWorker sends a worker a command on prefix+workerID
+func (pm *ProbeManager) notifyWorker(prefix, workerID string) {
+	if err := pm.natsConn.Publish(prefix+workerID, nil); err != nil {
+		pm.logger.Error("failed to notify worker",
+			zap.String("subject", prefix+workerID),
+			zap.Error(err))
+	}
+}
+
+func (pm *ProbeManager) reply(msg *nats.Msg, reply WorkerCommandReply) {
+	data, _ := json.Marshal(reply)
+	if err := msg.Respond(data); err != nil {
+		pm.logger.Error("failed to reply to worker command", zap.Error(err))
+	}
+}
@@ -1,41 +1,119 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/jjkirkpatrick/monitoring/internal/database"
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
+	"github.com/jjkirkpatrick/monitoring/pkg/pingcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
+	"github.com/jjkirkpatrick/monitoring/pkg/probeauth"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.uber.org/zap"
 )
 
 type ProbeWorker struct {
-	ID        string    `json:"id"`
-	LastSeen  time.Time `json:"last_seen"`
-	Status    string    `json:"status"`
-	CheckType []string  `json:"check_types"` // Supported check types (HTTP, HTTPS, TCP, UDP, DNS)
+	ID        string            `json:"id"`
+	LastSeen  time.Time         `json:"last_seen"`
+	Status    string            `json:"status"`
+	CheckType []string          `json:"check_types"`         // Supported check types (HTTP, HTTPS, TCP, UDP, DNS, PING)
+	Region    string            `json:"region"`              // Location the worker probes from
+	Provider  string            `json:"provider,omitempty"`  // Hosting provider, e.g. aws or hetzner
+	IPFamily  string            `json:"ip_family,omitempty"` // ipv4, ipv6 or dual
+	Capacity  int               `json:"capacity,omitempty"`  // Checks the worker runs concurrently
+	Labels    map[string]string `json:"labels,omitempty"`
+
+	// AccountID is set for private agents, which only run checks for
+	// monitors of that account. TokenID is the agent token they registered
+	// with.
+	AccountID string `json:"account_id,omitempty"`
+	TokenID   string `json:"token_id,omitempty"`
+	// PublicKey verifies the worker's signed results, see pkg/probeauth
+	PublicKey string `json:"public_key"`
+
+	// Cordoned workers receive no new checks; draining workers have also
+	// been told to stop pulling the checks queued for them
+	Cordoned bool `json:"cordoned"`
+	Draining bool `json:"draining"`
+	// RetiredAt is set once the worker has deregistered or been removed for
+	// inactivity, see retireWorker
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+
+	// Load as of the last heartbeat
+	InFlight   int     `json:"in_flight"`
+	QueueDepth int     `json:"queue_depth"`
+	LatencyMs  float64 `json:"latency_ms"`
+	// outstanding counts checks assigned but not yet reported back, so that
+	// selection accounts for assignments made since the last heartbeat
+	outstanding int
 }
 
 type ProbeManager struct {
 	logger   *zap.Logger
 	natsConn *nats.Conn
+	js       jetstream.JetStream
+	db       *database.DB
+	registry jetstream.KeyValue
 	workers  map[string]*ProbeWorker
-	mu       sync.RWMutex
+	// retired holds when each retired worker left, until its registry
+	// entry is deleted
+	retired map[string]time.Time
+	mu      sync.RWMutex
+	// nextWorker rotates check assignments across matching workers
+	nextWorker int
+
+	// assignments holds checks sent to a worker whose result has not yet
+	// come back, keyed by assignment ID
+	assignments map[string]*assignment
+	assignMu    sync.Mutex
+
+	// politeness holds checks back from hosts that are already busy
+	politeness *politeness
+
+	// verifier rejects results not signed by a registered worker
+	verifier *probeauth.Verifier
+
+	// registrationSecret is the operator's secret shared workers prove they
+	// hold when registering. Without it only private agents are accepted.
+	registrationSecret string
 }
 
-func NewProbeManager(logger *zap.Logger, natsConn *nats.Conn) *ProbeManager {
+func NewProbeManager(logger *zap.Logger, natsConn *nats.Conn, js jetstream.JetStream, db *database.DB, politeness PolitenessPolicy, verifier *probeauth.Verifier, registrationSecret string) *ProbeManager {
 	return &ProbeManager{
-		logger:   logger,
-		natsConn: natsConn,
-		workers:  make(map[string]*ProbeWorker),
+		logger:      logger,
+		natsConn:    natsConn,
+		js:          js,
+		db:          db,
+		workers:     make(map[string]*ProbeWorker),
+		retired:     make(map[string]time.Time),
+		assignments: make(map[string]*assignment),
+		politeness:  newPoliteness(politeness),
+
+		verifier:           verifier,
+		registrationSecret: registrationSecret,
 	}
 }
 
 func (pm *ProbeManager) Start() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Restore the workers known before a restart
+	if err := pm.openRegistry(ctx); err != nil {
+		return err
+	}
+
 	// Subscribe to probe worker registrations
 	if _, err := pm.natsConn.Subscribe("probes.register", pm.handleWorkerRegistration); err != nil {
 		return err
@@ -46,36 +124,138 @@ func (pm *ProbeManager) Start() error {
 		return err
 	}
 
-	// Subscribe to check requests from scheduler
-	if _, err := pm.natsConn.Subscribe("probes.check.request", pm.handleCheckRequest); err != nil {
+	// Track results so outstanding assignments are released as they finish
+	if _, err := pm.natsConn.Subscribe("probes.check.result", pm.handleCheckResult); err != nil {
+		return err
+	}
+
+	// Subscribe to workers acknowledging that they started a check
+	if _, err := pm.natsConn.Subscribe("probes.check.ack", pm.handleCheckAck); err != nil {
+		return err
+	}
+
+	// Subscribe to workers leaving on shutdown
+	if _, err := pm.natsConn.Subscribe("probes.deregister", pm.handleWorkerDeregistration); err != nil {
+		return err
+	}
+
+	// Answer requests for the worker registry
+	if _, err := pm.natsConn.Subscribe("probes.workers.list", pm.handleListWorkers); err != nil {
+		return err
+	}
+
+	// Answer requests for an account's private agents
+	if _, err := pm.natsConn.Subscribe("probes.agents.list", pm.handleListAgents); err != nil {
+		return err
+	}
+
+	// Drop private agents whose token has been revoked
+	if _, err := pm.natsConn.Subscribe("probes.agents.revoked", pm.handleTokenRevoked); err != nil {
+		return err
+	}
+
+	// Answer operator commands for taking workers out of rotation
+	for subject, handler := range map[string]nats.MsgHandler{
+		"probes.workers.cordon":   pm.handleCordon,
+		"probes.workers.uncordon": pm.handleUncordon,
+		"probes.workers.drain":    pm.handleDrain,
+	} {
+		if _, err := pm.natsConn.Subscribe(subject, handler); err != nil {
+			return err
+		}
+	}
+
+	// Consume check requests from the scheduler. Replicas share the durable
+	// consumer, so each request is assigned once.
+	if _, err := pipeline.ConsumeMsg(ctx, pm.js, pm.logger, checkRequestConsumer, pm.handleCheckRequest); err != nil {
 		return err
 	}
 
 	// Start worker cleanup routine
 	go pm.cleanupInactiveWorkers()
 
+	// Reassign checks whose worker went quiet
+	go pm.reassignExpired()
+
+	// Forget hosts that no longer have checks running
+	go pm.pruneTargets()
+
+	// Keep check requests held back by target limits from being redelivered
+	go pm.keepWaiting()
+
 	return nil
 }
 
 func (pm *ProbeManager) handleWorkerRegistration(msg *nats.Msg) {
-	var worker ProbeWorker
-	if err := json.Unmarshal(msg.Data, &worker); err != nil {
+	var registration workerRegistration
+	if err := json.Unmarshal(msg.Data, &registration); err != nil {
 		pm.logger.Error("failed to unmarshal worker registration", zap.Error(err))
 		return
 	}
+	if registration.PublicKey == "" {
+		pm.rejectRegistration(registration.ID, fmt.Errorf("%w: no public key to verify results with", errRejected))
+		return
+	}
+	if err := pm.authenticate(&registration); err != nil {
+		pm.rejectRegistration(registration.ID, err)
+		return
+	}
+	worker := registration.ProbeWorker
+
+	// The registry also holds retired workers, whose ID stays bound to
+	// their key for as long as their results may be verified
+	stored, err := pm.storedWorker(worker.ID)
+	if err != nil {
+		pm.rejectRegistration(worker.ID, err)
+		return
+	}
 
 	pm.mu.Lock()
+	// A worker registering again keeps its cordon and outstanding checks.
+	// Its account and key cannot change.
+	existing, exists := pm.workers[worker.ID]
+	if !exists {
+		existing = stored
+	}
+	if existing != nil {
+		if err := checkBinding(existing, &worker); err != nil {
+			pm.mu.Unlock()
+			pm.rejectRegistration(worker.ID, err)
+			return
+		}
+	}
+	if exists {
+		worker.Cordoned = existing.Cordoned
+		worker.Draining = existing.Draining
+		worker.outstanding = existing.outstanding
+	}
 	worker.LastSeen = time.Now()
 	worker.Status = "active"
+	worker.RetiredAt = nil
 	pm.workers[worker.ID] = &worker
+	delete(pm.retired, worker.ID)
+	observeWorker(&worker)
+	snapshot := worker
 	pm.mu.Unlock()
 
-	pm.logger.Info("worker registered", zap.String("worker_id", worker.ID))
+	pm.saveWorker(snapshot)
+
+	pm.logger.Info("worker registered",
+		zap.String("worker_id", worker.ID),
+		zap.String("region", worker.Region),
+		zap.String("provider", worker.Provider),
+		zap.String("ip_family", worker.IPFamily),
+		zap.Int("capacity", worker.Capacity),
+		zap.Any("labels", worker.Labels),
+		zap.String("account_id", worker.AccountID))
 }
 
 func (pm *ProbeManager) handleWorkerHeartbeat(msg *nats.Msg) {
 	var heartbeat struct {
-		WorkerID string `json:"worker_id"`
+		WorkerID   string  `json:"worker_id"`
+		InFlight   int     `json:"in_flight"`
+		QueueDepth int     `json:"queue_depth"`
+		LatencyMs  float64 `json:"latency_ms"`
 	}
 	if err := json.Unmarshal(msg.Data, &heartbeat); err != nil {
 		pm.logger.Error("failed to unmarshal worker heartbeat", zap.Error(err))
@@ -83,84 +263,106 @@ func (pm *ProbeManager) handleWorkerHeartbeat(msg *nats.Msg) {
 	}
 
 	pm.mu.Lock()
-	if worker, exists := pm.workers[heartbeat.WorkerID]; exists {
-		worker.LastSeen = time.Now()
-		worker.Status = "active"
+	worker, exists := pm.workers[heartbeat.WorkerID]
+	if !exists {
+		pm.mu.Unlock()
+		pm.requestRegistration(heartbeat.WorkerID)
+		return
 	}
+	worker.LastSeen = time.Now()
+	worker.Status = "active"
+	worker.InFlight = heartbeat.InFlight
+	worker.QueueDepth = heartbeat.QueueDepth
+	worker.LatencyMs = heartbeat.LatencyMs
+	// The worker's own count is authoritative; resyncing drops any
+	// assignments whose results were lost
+	worker.outstanding = heartbeat.InFlight + heartbeat.QueueDepth
+	observeWorker(worker)
+	snapshot := *worker
 	pm.mu.Unlock()
-}
 
-func (pm *ProbeManager) handleCheckRequest(msg *nats.Msg) {
-	var request struct {
-		MonitorID string `json:"monitor_id"`
-		URL       string `json:"url"`
-		Type      string `json:"type"`
-		Timeout   string `json:"timeout"`
-	}
-	if err := json.Unmarshal(msg.Data, &request); err != nil {
-		pm.logger.Error("failed to unmarshal check request", zap.Error(err))
-		return
-	}
+	pm.saveWorker(snapshot)
+}
 
-	// Find available worker that supports the check type
-	var selectedWorker *ProbeWorker
-	pm.mu.RLock()
-	for _, worker := range pm.workers {
-		if worker.Status == "active" {
-			for _, checkType := range worker.CheckType {
-				if checkType == request.Type {
-					selectedWorker = worker
-					break
-				}
-			}
-		}
-		if selectedWorker != nil {
-			break
-		}
-	}
-	pm.mu.RUnlock()
+// CheckRequest is a check the scheduler wants run
+type CheckRequest struct {
+	MonitorID string `json:"monitor_id"`
+	URL       string `json:"url"`
+	Type      string `json:"type"`
+	Timeout   string `json:"timeout"`
+	// AccountID owns the monitor; private agents only run their account's
+	// checks
+	AccountID string `json:"account_id,omitempty"`
+	// Location the check must run from; empty means any worker will do
+	Location string `json:"location,omitempty"`
+	// Labels the worker must carry, see ProbeWorker.matches
+	Selector map[string]string `json:"selector,omitempty"`
+	// HTTP, DNS and Ping are passed to the worker as they are
+	HTTP *httpcheck.Config `json:"http,omitempty"`
+	DNS  *dnscheck.Config  `json:"dns,omitempty"`
+	Ping *pingcheck.Config `json:"ping,omitempty"`
+	// Set by the scheduler for checks inside a flagging maintenance window
+	Maintenance         bool   `json:"maintenance"`
+	MaintenanceWindowID string `json:"maintenance_window_id"`
+}
 
-	if selectedWorker == nil {
-		pm.logger.Error("no available workers for check type",
-			zap.String("monitor_id", request.MonitorID),
-			zap.String("type", request.Type))
-		return
+// handleCheckRequest queues a check behind the others for its host. The
+// request is settled once the check has been handed to a worker or given up
+// on, so that checks still waiting are redelivered if the manager stops.
+func (pm *ProbeManager) handleCheckRequest(msg jetstream.Msg) error {
+	var request CheckRequest
+	if err := json.Unmarshal(msg.Data(), &request); err != nil {
+		return pipeline.Permanent(fmt.Errorf("failed to unmarshal check request: %w", err))
 	}
 
-	// Forward check request to selected worker
-	checkAssignment, _ := json.Marshal(map[string]interface{}{
-		"monitor_id": request.MonitorID,
-		"url":       request.URL,
-		"type":      request.Type,
-		"timeout":   request.Timeout,
-		"worker_id": selectedWorker.ID,
-	})
-
-	subject := "probes.check.assign." + selectedWorker.ID
-	if err := pm.natsConn.Publish(subject, checkAssignment); err != nil {
-		pm.logger.Error("failed to publish check assignment",
-			zap.String("worker_id", selectedWorker.ID),
-			zap.Error(err))
-	}
+	a := newAssignment(request)
+	a.msg = msg
+	pm.admit(a)
+	return pipeline.ErrDeferred
 }
 
+// cleanupInactiveWorkers marks workers that have missed their heartbeats
+// inactive, retires those that stay quiet, and deletes the registry entries
+// of workers retired for longer than results are kept
 func (pm *ProbeManager) cleanupInactiveWorkers() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
 		now := time.Now()
+		var changed, removed []ProbeWorker
+		var expired []string
 		pm.mu.Lock()
 		for id, worker := range pm.workers {
 			if now.Sub(worker.LastSeen) > 1*time.Minute {
-				worker.Status = "inactive"
 				if now.Sub(worker.LastSeen) > 5*time.Minute {
 					delete(pm.workers, id)
+					forgetWorker(worker)
+					removed = append(removed, *worker)
 					pm.logger.Info("removed inactive worker", zap.String("worker_id", id))
+				} else if worker.Status != "inactive" {
+					worker.Status = "inactive"
+					changed = append(changed, *worker)
 				}
 			}
 		}
+		for id, retiredAt := range pm.retired {
+			if now.Sub(retiredAt) > pipeline.ResultRetention {
+				delete(pm.retired, id)
+				expired = append(expired, id)
+			}
+		}
 		pm.mu.Unlock()
+
+		for _, worker := range changed {
+			pm.saveWorker(worker)
+		}
+		for _, worker := range removed {
+			pm.retireWorker(worker)
+		}
+		for _, id := range expired {
+			pm.removeWorker(id)
+		}
 	}
 }
 
@@ -188,8 +390,76 @@ func main() {
 	}
 	defer nc.Close()
 
+	// Expose probe manager metrics
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":8080"
+	}
+	observability.ServeMetrics(metricsAddr, logger)
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Fatal("failed to create JetStream context", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pipeline.EnsureStreams(ctx, js)
+	cancel()
+	if err != nil {
+		logger.Fatal("failed to create pipeline streams", zap.Error(err))
+	}
+
+	// Private agent tokens are checked against the database
+	db, err := database.New(context.Background())
+	if err != nil {
+		logger.Fatal("failed to initialize database", zap.Error(err))
+	}
+	defer db.Close()
+
+	// Limits on how hard checks hit any one host
+	politeness := PolitenessPolicy{
+		MaxConcurrent: 10,
+		MinSpacing:    100 * time.Millisecond,
+		MaxDelay:      2 * time.Minute,
+	}
+	if v := os.Getenv("PROBE_TARGET_MAX_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logger.Fatal("invalid PROBE_TARGET_MAX_CONCURRENCY", zap.String("value", v))
+		}
+		politeness.MaxConcurrent = n
+	}
+	if v := os.Getenv("PROBE_TARGET_MIN_SPACING"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid PROBE_TARGET_MIN_SPACING", zap.Error(err))
+		}
+		politeness.MinSpacing = d
+	}
+	if v := os.Getenv("PROBE_TARGET_MAX_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid PROBE_TARGET_MAX_DELAY", zap.Error(err))
+		}
+		politeness.MaxDelay = d
+	}
+
+	// Shared workers register with a proof of this secret
+	registrationSecret := os.Getenv("PROBE_REGISTRATION_SECRET")
+	if registrationSecret == "" {
+		logger.Warn("PROBE_REGISTRATION_SECRET not set, only private agents can register")
+	}
+
+	// Results only complete assignments once verified against the registry
+	verifyCtx, verifyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	verifier, err := probeauth.NewVerifier(verifyCtx, js, logger, 0)
+	verifyCancel()
+	if err != nil {
+		logger.Fatal("failed to load probe worker registry", zap.Error(err))
+	}
+
 	// Create and start probe manager
-	manager := NewProbeManager(logger, nc)
+	manager := NewProbeManager(logger, nc, js, db, politeness, verifier, registrationSecret)
 	if err := manager.Start(); err != nil {
 		logger.Fatal("failed to start probe manager", zap.Error(err))
 	}
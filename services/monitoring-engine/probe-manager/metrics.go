@@ -0,0 +1,147 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	workerInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_manager_worker_in_flight",
+			Help: "Checks a worker reported running at its last heartbeat",
+		},
+		[]string{"worker_id", "region"},
+	)
+
+	workerQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_manager_worker_queue_depth",
+			Help: "Checks a worker reported waiting for a free slot at its last heartbeat",
+		},
+		[]string{"worker_id", "region"},
+	)
+
+	workerLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_manager_worker_latency_seconds",
+			Help: "Moving average of a worker's recent check durations",
+		},
+		[]string{"worker_id", "region"},
+	)
+
+	workerLoad = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_manager_worker_load",
+			Help: "Outstanding checks per unit of capacity, as used for worker selection",
+		},
+		[]string{"worker_id", "region"},
+	)
+
+	checksAssigned = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "probe_manager_checks_assigned_total",
+			Help: "Total number of checks assigned to each worker",
+		},
+		[]string{"worker_id", "region"},
+	)
+
+	assignmentsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "probe_manager_assignments_in_flight",
+			Help: "Checks assigned to a worker whose result has not arrived",
+		},
+	)
+
+	assignmentsExpired = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "probe_manager_assignments_expired_total",
+			Help: "Total number of assignments whose worker missed the ack or result deadline",
+		},
+		[]string{"reason"},
+	)
+
+	assignmentsRetried = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "probe_manager_assignments_retried_total",
+			Help: "Total number of expired assignments reassigned to another worker",
+		},
+	)
+
+	assignmentsLost = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "probe_manager_assignments_lost_total",
+			Help: "Total number of checks given up on without a result",
+		},
+		[]string{"reason"},
+	)
+
+	checksUnroutable = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "probe_manager_checks_unroutable_total",
+			Help: "Total number of checks no worker could run",
+		},
+		[]string{"reason"},
+	)
+
+	politenessLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "probe_manager_politeness_limited_total",
+			Help: "Total number of checks held back because their target host hit its concurrency or spacing limit",
+		},
+		[]string{"reason"},
+	)
+
+	politenessWaiting = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "probe_manager_politeness_waiting",
+			Help: "Checks currently held back by per-host limits",
+		},
+	)
+
+	politenessDelay = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "probe_manager_politeness_delay_seconds",
+			Help:    "Time checks spent held back by per-host limits before being assigned",
+			Buckets: []float64{0, 0.1, 0.5, 1, 5, 15, 30, 60, 120},
+		},
+	)
+
+	registrationsRejected = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "probe_manager_registrations_rejected_total",
+			Help: "Total number of worker registrations refused, mostly private agents with an invalid or revoked token",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(workerInFlight)
+	prometheus.MustRegister(workerQueueDepth)
+	prometheus.MustRegister(workerLatency)
+	prometheus.MustRegister(workerLoad)
+	prometheus.MustRegister(checksAssigned)
+	prometheus.MustRegister(assignmentsInFlight)
+	prometheus.MustRegister(assignmentsExpired)
+	prometheus.MustRegister(assignmentsRetried)
+	prometheus.MustRegister(assignmentsLost)
+	prometheus.MustRegister(checksUnroutable)
+	prometheus.MustRegister(politenessLimited)
+	prometheus.MustRegister(politenessWaiting)
+	prometheus.MustRegister(politenessDelay)
+	prometheus.MustRegister(registrationsRejected)
+}
+
+// observeWorker publishes the worker's last reported load
+func observeWorker(w *ProbeWorker) {
+	workerInFlight.WithLabelValues(w.ID, w.Region).Set(float64(w.InFlight))
+	workerQueueDepth.WithLabelValues(w.ID, w.Region).Set(float64(w.QueueDepth))
+	workerLatency.WithLabelValues(w.ID, w.Region).Set(w.LatencyMs / 1000)
+	workerLoad.WithLabelValues(w.ID, w.Region).Set(w.load())
+}
+
+// forgetWorker drops the series of a worker that has been removed
+func forgetWorker(w *ProbeWorker) {
+	workerInFlight.DeleteLabelValues(w.ID, w.Region)
+	workerQueueDepth.DeleteLabelValues(w.ID, w.Region)
+	workerLatency.DeleteLabelValues(w.ID, w.Region)
+	workerLoad.DeleteLabelValues(w.ID, w.Region)
+	checksAssigned.DeleteLabelValues(w.ID, w.Region)
+}
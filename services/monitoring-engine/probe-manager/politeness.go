@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// PolitenessPolicy limits how hard checks hit any one target host, however
+// many monitors and locations point at it. Limits are kept per host name as
+// given in the monitor, so two names for the same address are counted apart.
+type PolitenessPolicy struct {
+	// MaxConcurrent caps the checks running against a host across all
+	// workers; zero means no cap
+	MaxConcurrent int
+	// MinSpacing is the least time between the starts of two checks
+	// against a host
+	MinSpacing time.Duration
+	// MaxDelay is how long a check may be held back before its request is
+	// moved to the dead-letter stream
+	MaxDelay time.Duration
+}
+
+// targetState tracks the checks against one host
+type targetState struct {
+	inFlight  int
+	nextStart time.Time
+	// waiting holds checks that have been held back, oldest first
+	waiting []*assignment
+	// timer wakes the queue once the spacing allows the next check
+	timer *time.Timer
+}
+
+// politeness holds the per-host state for a PolitenessPolicy
+type politeness struct {
+	policy  PolitenessPolicy
+	targets map[string]*targetState
+	waiting int
+	mu      sync.Mutex
+}
+
+func newPoliteness(policy PolitenessPolicy) *politeness {
+	return &politeness{
+		policy:  policy,
+		targets: make(map[string]*targetState),
+	}
+}
+
+// targetHost returns the host a check connects to. Targets are URLs for HTTP
+// checks, host:port for TCP and UDP, and bare names for DNS and ping.
+func targetHost(target string) string {
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+			return strings.ToLower(u.Hostname())
+		}
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return strings.ToLower(host)
+	}
+	return strings.ToLower(target)
+}
+
+// admit queues a new check behind the others for its host and assigns
+// whatever the host's limits allow
+func (pm *ProbeManager) admit(a *assignment) {
+	a.target = targetHost(a.request.URL)
+	a.queuedAt = time.Now()
+
+	p := pm.politeness
+	p.mu.Lock()
+	state, exists := p.targets[a.target]
+	if !exists {
+		state = &targetState{}
+		p.targets[a.target] = state
+	}
+	state.waiting = append(state.waiting, a)
+	p.waiting++
+	ready, dropped := pm.releaseTarget(a.target, state)
+	p.mu.Unlock()
+
+	pm.dispatchReady(ready, dropped)
+}
+
+// finish frees the slot a completed or abandoned check held against its host
+// and assigns the next checks waiting for it
+func (pm *ProbeManager) finish(a *assignment) {
+	if a.target == "" {
+		return
+	}
+
+	p := pm.politeness
+	p.mu.Lock()
+	state, exists := p.targets[a.target]
+	if !exists {
+		p.mu.Unlock()
+		return
+	}
+	if state.inFlight > 0 {
+		state.inFlight--
+	}
+	ready, dropped := pm.releaseTarget(a.target, state)
+	p.mu.Unlock()
+
+	pm.dispatchReady(ready, dropped)
+}
+
+// releaseTarget takes as many checks off the host's queue as its limits allow,
+// along with any that have waited too long. It must be called with
+// politeness.mu held.
+func (pm *ProbeManager) releaseTarget(target string, state *targetState) (ready, dropped []*assignment) {
+	p := pm.politeness
+	now := time.Now()
+
+	for len(state.waiting) > 0 {
+		a := state.waiting[0]
+		if p.policy.MaxDelay > 0 && now.Sub(a.queuedAt) > p.policy.MaxDelay {
+			state.waiting = state.waiting[1:]
+			p.waiting--
+			dropped = append(dropped, a)
+			continue
+		}
+
+		if p.policy.MaxConcurrent > 0 && state.inFlight >= p.policy.MaxConcurrent {
+			// A finishing check releases the queue again
+			pm.holdBack(a, "concurrency")
+			break
+		}
+		if now.Before(state.nextStart) {
+			pm.holdBack(a, "spacing")
+			if state.timer == nil {
+				state.timer = time.AfterFunc(state.nextStart.Sub(now), func() {
+					pm.wake(target)
+				})
+			}
+			break
+		}
+
+		state.waiting = state.waiting[1:]
+		p.waiting--
+		state.inFlight++
+		state.nextStart = now.Add(p.policy.MinSpacing)
+		politenessDelay.Observe(now.Sub(a.queuedAt).Seconds())
+		ready = append(ready, a)
+	}
+
+	if state.inFlight == 0 && len(state.waiting) == 0 && state.timer == nil && !now.Before(state.nextStart) {
+		delete(p.targets, target)
+	}
+	politenessWaiting.Set(float64(p.waiting))
+	return ready, dropped
+}
+
+// wake releases a host's queue once its spacing has passed
+func (pm *ProbeManager) wake(target string) {
+	p := pm.politeness
+	p.mu.Lock()
+	state, exists := p.targets[target]
+	if !exists {
+		p.mu.Unlock()
+		return
+	}
+	state.timer = nil
+	ready, dropped := pm.releaseTarget(target, state)
+	p.mu.Unlock()
+
+	pm.dispatchReady(ready, dropped)
+}
+
+// pruneTargets periodically forgets hosts with nothing running or waiting.
+// Hosts are otherwise only dropped once their spacing has passed, so that a
+// quick check does not reset it.
+func (pm *ProbeManager) pruneTargets() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		p := pm.politeness
+		now := time.Now()
+		p.mu.Lock()
+		for target, state := range p.targets {
+			if state.inFlight == 0 && len(state.waiting) == 0 && state.timer == nil && !now.Before(state.nextStart) {
+				delete(p.targets, target)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// keepWaiting periodically reports progress on the check requests held back
+// by target limits, so that they are not redelivered while they wait
+func (pm *ProbeManager) keepWaiting() {
+	ticker := time.NewTicker(requestProgressInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var waiting []jetstream.Msg
+		p := pm.politeness
+		p.mu.Lock()
+		for _, state := range p.targets {
+			for _, a := range state.waiting {
+				if a.msg != nil {
+					waiting = append(waiting, a.msg)
+				}
+			}
+		}
+		p.mu.Unlock()
+
+		for _, msg := range waiting {
+			if err := msg.InProgress(); err != nil {
+				pm.logger.Warn("failed to extend held back check request", zap.Error(err))
+			}
+		}
+	}
+}
+
+// holdBack records that a check had to wait for its host, counting each check
+// once
+func (pm *ProbeManager) holdBack(a *assignment, reason string) {
+	if a.heldBack {
+		return
+	}
+	a.heldBack = true
+	politenessLimited.WithLabelValues(reason).Inc()
+	pm.logger.Debug("holding back check for busy target",
+		zap.String("monitor_id", a.request.MonitorID),
+		zap.String("target", a.target),
+		zap.String("reason", reason))
+}
+
+// dispatchReady assigns released checks and gives up on dropped ones. It must
+// be called without politeness.mu held.
+func (pm *ProbeManager) dispatchReady(ready, dropped []*assignment) {
+	for _, a := range dropped {
+		waited := time.Since(a.queuedAt)
+		pm.logger.Warn("dropping check held back too long by target limits",
+			zap.String("monitor_id", a.request.MonitorID),
+			zap.String("target", a.target),
+			zap.Duration("waited", waited))
+		assignmentsLost.WithLabelValues("politeness_timeout").Inc()
+
+		if a.msg != nil {
+			cause := fmt.Errorf("held back %s by limits on %s", waited.Round(time.Second), a.target)
+			if err := pipeline.DeadLetter(pm.js, checkRequestConsumer, a.msg, "politeness_timeout", cause); err != nil {
+				// Left unacked, so it is redelivered rather than lost
+				pm.logger.Error("failed to dead-letter check request",
+					zap.String("monitor_id", a.request.MonitorID),
+					zap.Error(err))
+			}
+			a.msg = nil
+		}
+	}
+	for _, a := range ready {
+		pm.assign(a)
+	}
+}
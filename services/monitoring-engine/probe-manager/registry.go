@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/probeauth"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// openRegistry opens the registry bucket and loads the workers it holds. The
+// bucket lets a restarted probe manager route checks straight away instead of
+// waiting for every worker to register again, and gives the services that
+// verify results the keys workers sign with.
+func (pm *ProbeManager) openRegistry(ctx context.Context) error {
+	kv, err := probeauth.OpenRegistry(ctx, pm.js)
+	if err != nil {
+		return err
+	}
+	pm.registry = kv
+
+	lister, err := kv.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list workers: %w", err)
+	}
+
+	var agents []ProbeWorker
+	pm.mu.Lock()
+	for key := range lister.Keys() {
+		entry, err := kv.Get(ctx, key)
+		if err != nil {
+			pm.logger.Warn("failed to load worker", zap.String("worker_id", key), zap.Error(err))
+			continue
+		}
+		var worker ProbeWorker
+		if err := json.Unmarshal(entry.Value(), &worker); err != nil {
+			pm.logger.Warn("failed to unmarshal worker", zap.String("worker_id", key), zap.Error(err))
+			continue
+		}
+		if worker.RetiredAt != nil {
+			pm.retired[worker.ID] = *worker.RetiredAt
+			continue
+		}
+		// Private agents are not restored, as their token may have been
+		// revoked meanwhile. Their results are refused until their next
+		// heartbeat asks them to register again, which checks it. Their
+		// entry is retired rather than deleted so their key stays bound to
+		// their ID.
+		if worker.AccountID != "" {
+			agents = append(agents, worker)
+			continue
+		}
+		pm.workers[worker.ID] = &worker
+		observeWorker(&worker)
+	}
+	loaded := len(pm.workers)
+	pm.mu.Unlock()
+
+	for _, agent := range agents {
+		pm.retireWorker(agent)
+	}
+
+	pm.logger.Info("loaded worker registry", zap.Int("workers", loaded))
+	return nil
+}
+
+// storedWorker returns the registry entry for a worker, retired or not, or
+// nil when the registry has none
+func (pm *ProbeManager) storedWorker(id string) (*ProbeWorker, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry, err := pm.registry.Get(ctx, id)
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up worker: %w", err)
+	}
+	var worker ProbeWorker
+	if err := json.Unmarshal(entry.Value(), &worker); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal worker: %w", err)
+	}
+	return &worker, nil
+}
+
+// checkBinding refuses a registration that would move a known worker ID to
+// another account or key, or anyone could take over its results by
+// registering under its ID
+func checkBinding(existing, worker *ProbeWorker) error {
+	if existing.AccountID != worker.AccountID {
+		return fmt.Errorf("%w: worker ID is registered to another account", errRejected)
+	}
+	if existing.PublicKey != "" && existing.PublicKey != worker.PublicKey {
+		return fmt.Errorf("%w: worker ID is registered with another key", errRejected)
+	}
+	return nil
+}
+
+// saveWorker writes a copy of the worker to the registry. Callers take the
+// copy under pm.mu and save it after releasing the lock.
+func (pm *ProbeManager) saveWorker(worker ProbeWorker) {
+	data, err := json.Marshal(worker)
+	if err != nil {
+		pm.logger.Error("failed to marshal worker", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := pm.registry.Put(ctx, worker.ID, data); err != nil {
+		pm.logger.Error("failed to save worker",
+			zap.String("worker_id", worker.ID),
+			zap.Error(err))
+	}
+}
+
+// retireWorker marks the worker as gone in the registry instead of deleting
+// it. Its results may still be waiting to be verified, by consumers that are
+// behind or that see them redelivered, so the entry is kept for as long as
+// the results stream keeps results. Callers have already dropped the worker
+// from pm.workers.
+func (pm *ProbeManager) retireWorker(worker ProbeWorker) {
+	now := time.Now()
+	worker.Status = "retired"
+	worker.RetiredAt = &now
+
+	pm.mu.Lock()
+	pm.retired[worker.ID] = now
+	pm.mu.Unlock()
+
+	pm.saveWorker(worker)
+}
+
+// removeWorker deletes the worker from the registry
+func (pm *ProbeManager) removeWorker(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pm.registry.Delete(ctx, id); err != nil {
+		pm.logger.Error("failed to remove worker",
+			zap.String("worker_id", id),
+			zap.Error(err))
+	}
+}
+
+// requestRegistration asks a worker the manager does not know to register
+// again, which happens when it was removed while unreachable
+func (pm *ProbeManager) requestRegistration(workerID string) {
+	pm.logger.Info("asking unknown worker to re-register", zap.String("worker_id", workerID))
+	if err := pm.natsConn.Publish("probes.reregister."+workerID, nil); err != nil {
+		pm.logger.Error("failed to request worker registration",
+			zap.String("worker_id", workerID),
+			zap.Error(err))
+	}
+}
+
+// WorkerList is the reply to probes.workers.list
+type WorkerList struct {
+	Workers []ProbeWorker `json:"workers"`
+}
+
+// handleListWorkers replies with every known worker, ordered by ID
+func (pm *ProbeManager) handleListWorkers(msg *nats.Msg) {
+	pm.mu.RLock()
+	list := WorkerList{Workers: make([]ProbeWorker, 0, len(pm.workers))}
+	for _, worker := range pm.workers {
+		list.Workers = append(list.Workers, *worker)
+	}
+	pm.mu.RUnlock()
+
+	sort.Slice(list.Workers, func(i, j int) bool {
+		return list.Workers[i].ID < list.Workers[j].ID
+	})
+
+	data, _ := json.Marshal(list)
+	if err := msg.Respond(data); err != nil {
+		pm.logger.Error("failed to reply with worker list", zap.Error(err))
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reasons a check could not be routed to a worker
+const (
+	unroutableNoWorkers       = "no_active_workers"
+	unroutableUnsupportedType = "unsupported_check_type"
+	unroutableNoMatch         = "no_matching_workers"
+)
+
+// UnroutableCheck is published on check.unroutable when no registered worker
+// can run a check request
+type UnroutableCheck struct {
+	MonitorID string            `json:"monitor_id"`
+	Type      string            `json:"type"`
+	Location  string            `json:"location,omitempty"`
+	Selector  map[string]string `json:"selector,omitempty"`
+	Reason    string            `json:"reason"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// label returns the value of a selector key for the worker. The attributes
+// workers register with are exposed as labels so that selectors can match
+// them like any other.
+func (w *ProbeWorker) label(key string) (string, bool) {
+	switch key {
+	case "region":
+		return w.Region, w.Region != ""
+	case "provider":
+		return w.Provider, w.Provider != ""
+	case "ip_family":
+		return w.IPFamily, w.IPFamily != ""
+	}
+	value, ok := w.Labels[key]
+	return value, ok
+}
+
+// matches reports whether the worker satisfies every term of the selector
+func (w *ProbeWorker) matches(selector map[string]string) bool {
+	for key, want := range selector {
+		have, ok := w.label(key)
+		if !ok {
+			return false
+		}
+		// Dual-stack workers can probe over either family
+		if key == "ip_family" && have == "dual" && (want == "ipv4" || want == "ipv6") {
+			continue
+		}
+		if have != want {
+			return false
+		}
+	}
+	return true
+}
+
+// supports reports whether the worker can run checks of the given type
+func (w *ProbeWorker) supports(checkType string) bool {
+	for _, t := range w.CheckType {
+		if t == checkType {
+			return true
+		}
+	}
+	return false
+}
+
+// load returns the worker's outstanding checks per unit of capacity
+func (w *ProbeWorker) load() float64 {
+	outstanding := max(w.outstanding, w.InFlight+w.QueueDepth)
+	return float64(outstanding) / float64(max(w.Capacity, 1))
+}
+
+// serves reports whether the worker may run checks for the account. Shared
+// workers run every account's checks; private agents only their own.
+func (w *ProbeWorker) serves(accountID string) bool {
+	return w.AccountID == "" || w.AccountID == accountID
+}
+
+// selectWorker picks the least loaded active, uncordoned worker that supports
+// the check type and satisfies the selector, and counts the assignment against
+// it. Private agents of other accounts are never considered. Ties are broken
+// by rotating through the candidates so that idle workers share checks
+// evenly. Workers in exclude are skipped. When none qualifies it returns the
+// reason.
+func (pm *ProbeManager) selectWorker(checkType, accountID string, selector map[string]string, exclude map[string]bool) (*ProbeWorker, string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var candidates []*ProbeWorker
+	active, supported := false, false
+	for _, worker := range pm.workers {
+		if worker.Status != "active" || worker.Cordoned || !worker.serves(accountID) {
+			continue
+		}
+		active = true
+		if !worker.supports(checkType) {
+			continue
+		}
+		supported = true
+		if worker.matches(selector) && !exclude[worker.ID] {
+			candidates = append(candidates, worker)
+		}
+	}
+
+	switch {
+	case !active:
+		return nil, unroutableNoWorkers
+	case !supported:
+		return nil, unroutableUnsupportedType
+	case len(candidates) == 0:
+		return nil, unroutableNoMatch
+	}
+
+	// Map iteration order is random; sort so the rotation is stable
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+	start := pm.nextWorker % len(candidates)
+	pm.nextWorker++
+
+	worker := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		candidate := candidates[(start+i)%len(candidates)]
+		if candidate.load() < worker.load() {
+			worker = candidate
+		}
+	}
+
+	worker.outstanding++
+	checksAssigned.WithLabelValues(worker.ID, worker.Region).Inc()
+	workerLoad.WithLabelValues(worker.ID, worker.Region).Set(worker.load())
+	return worker, ""
+}
+
+// publishUnroutable reports a check that no worker can run
+func (pm *ProbeManager) publishUnroutable(event UnroutableCheck) {
+	pm.logger.Error("no available workers for check",
+		zap.String("monitor_id", event.MonitorID),
+		zap.String("type", event.Type),
+		zap.String("location", event.Location),
+		zap.Any("selector", event.Selector),
+		zap.String("reason", event.Reason))
+
+	checksUnroutable.WithLabelValues(event.Reason).Inc()
+
+	data, _ := json.Marshal(event)
+	if err := pm.natsConn.Publish("check.unroutable", data); err != nil {
+		pm.logger.Error("failed to publish unroutable check", zap.Error(err))
+	}
+}
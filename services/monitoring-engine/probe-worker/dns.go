@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+)
+
+// dnsCheck queries the nameserver for the configured record type at target
+// and compares the answers with the expected values. Checks that follow
+// propagation then compare the answers of every server for the zone, and
+// DNSSEC checks validate the answers' chain of trust.
+func (w *ProbeWorker) dnsCheck(ctx context.Context, target string, config *dnscheck.Config) CheckResult {
+	result, err := w.resolver.Query(ctx, target, config)
+	if err != nil {
+		return CheckResult{Success: false, Error: err.Error()}
+	}
+
+	details := result.Details(config.Type())
+	failure := config.Check(target, result)
+
+	if config != nil && config.CheckPropagation {
+		propagation, err := w.resolver.Propagation(ctx, target, config)
+		if err == nil {
+			maps.Copy(details, propagation.Details())
+			err = propagation.Check()
+		}
+		if failure == nil {
+			failure = err
+		}
+	}
+
+	if config != nil && config.DNSSEC {
+		now := time.Now()
+		validation := w.resolver.ValidateDNSSEC(ctx, target, config, now)
+		maps.Copy(details, validation.Details(now))
+		if failure == nil {
+			failure = validation.Err
+		}
+	}
+
+	if failure != nil {
+		return CheckResult{Success: false, Error: failure.Error(), Details: details}
+	}
+	return CheckResult{Success: true, Details: details}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// drain stops the worker taking new checks while letting those it has
+// started finish
+func (w *ProbeWorker) drain() {
+	if w.draining.Swap(true) {
+		return
+	}
+	w.stopConsuming()
+	w.logger.Info("draining worker", zap.Int64("in_flight", w.inFlight.Load()))
+}
+
+// resume puts a drained worker back into service
+func (w *ProbeWorker) resume() error {
+	if !w.draining.Swap(false) {
+		return nil
+	}
+	w.logger.Info("resuming worker")
+	return w.startConsuming()
+}
+
+// Shutdown drains the worker and waits up to timeout for the checks it is
+// running to publish their results. The probe manager is asked to drain it
+// first, so the checks it has not started move elsewhere straight away, and
+// told it is leaving only once its results are in: results from a worker
+// that has left the registry are refused.
+func (w *ProbeWorker) Shutdown(timeout time.Duration) {
+	close(w.shutdownCh)
+	w.drain()
+
+	request, _ := json.Marshal(map[string]string{"worker_id": w.ID})
+	if _, err := w.natsConn.Request("probes.workers.drain", request, 5*time.Second); err != nil {
+		w.logger.Warn("failed to ask probe manager to drain worker", zap.Error(err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.checks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.logger.Info("finished in-flight checks")
+	case <-time.After(timeout):
+		w.logger.Warn("timed out waiting for in-flight checks",
+			zap.Int64("in_flight", w.inFlight.Load()))
+	}
+
+	if err := w.natsConn.Publish("probes.deregister", request); err != nil {
+		w.logger.Error("failed to deregister worker", zap.Error(err))
+	}
+	if err := w.natsConn.Flush(); err != nil {
+		w.logger.Error("failed to flush connection", zap.Error(err))
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/certcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
+)
+
+// maxResponseBytes bounds how much of a response body is downloaded. The
+// reported size of larger bodies stops there.
+const maxResponseBytes = 10 << 20
+
+// newTransport returns the transport HTTP checks are sent over. Keep-alives
+// are off so that every check opens its own connection, as a visitor would,
+// rather than riding on one left open by an earlier check.
+func newTransport(verifyTLS bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: !verifyTLS}
+	return transport
+}
+
+// httpCheck sends the request described by config and judges the response
+// status, body, headers and time as the check asks. The client gives up after
+// the check's own timeout.
+func (w *ProbeWorker) httpCheck(ctx context.Context, target string, config *httpcheck.Config, timeout time.Duration) CheckResult {
+	var body io.Reader
+	if config != nil && config.Body != "" {
+		body = strings.NewReader(config.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, config.RequestMethod(), target, body)
+	if err != nil {
+		return CheckResult{Success: false, Error: err.Error()}
+	}
+	req.Header.Set("User-Agent", config.Agent())
+	if config != nil {
+		for key, value := range config.Headers {
+			// The Host header is taken from the request, not its headers
+			if strings.EqualFold(key, "Host") {
+				req.Host = value
+				continue
+			}
+			req.Header.Set(key, value)
+		}
+		if config.BasicAuth != nil {
+			req.SetBasicAuth(config.BasicAuth.Username, config.BasicAuth.Password)
+		}
+	}
+
+	timing := &requestTiming{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), timing.trace()))
+
+	client, err := w.httpClient(req.URL, config, timeout)
+	if err != nil {
+		return CheckResult{Success: false, Error: err.Error()}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		// Report the phases that completed before the request failed
+		details := make(map[string]string)
+		timing.details(details)
+		return CheckResult{Success: false, Error: err.Error(), Details: details}
+	}
+	defer resp.Body.Close()
+
+	details := map[string]string{
+		"status_code": strconv.Itoa(resp.StatusCode),
+		"status":      resp.Status,
+	}
+	if final := resp.Request.URL.String(); final != req.URL.String() {
+		details["final_url"] = final
+	}
+	if resp.TLS != nil {
+		maps.Copy(details, certcheck.Inspect(*resp.TLS, resp.Request.URL.Hostname(), time.Now()))
+	}
+
+	// Assertions see the start of the body; the rest is downloaded only to
+	// time and size the response, whatever its status
+	var data []byte
+	var read int64
+	if config.ChecksBody() {
+		var truncated bool
+		data, truncated, err = httpcheck.ReadBody(resp.Body)
+		read = int64(len(data))
+		if truncated {
+			read++
+			details[httpcheck.DetailBodyTruncated] = "true"
+		}
+	}
+	var rest int64
+	if err == nil {
+		rest, err = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes-read))
+	}
+	elapsed := time.Since(start)
+
+	timing.details(details)
+	details[httpcheck.DetailResponseTime] = strconv.FormatInt(elapsed.Milliseconds(), 10)
+	details[httpcheck.DetailResponseSize] = strconv.FormatInt(read+rest, 10)
+
+	if !config.StatusOK(resp.StatusCode) {
+		return CheckResult{
+			Success: false,
+			Error:   fmt.Sprintf("unexpected status code %d", resp.StatusCode),
+			Details: details,
+		}
+	}
+	if err != nil {
+		return CheckResult{
+			Success: false,
+			Error:   fmt.Sprintf("failed to read response body: %v", err),
+			Details: details,
+		}
+	}
+
+	// Every assertion is evaluated so that each outcome is reported, but the
+	// first failure decides the error
+	failure := config.CheckBody(data)
+	outcomes, err := config.CheckAssertions(resp.Header, data)
+	maps.Copy(details, outcomes)
+	if failure == nil {
+		failure = err
+	}
+	if failure == nil {
+		failure = config.CheckResponseTime(elapsed)
+	}
+
+	if failure != nil {
+		var assertion *httpcheck.AssertionError
+		if errors.As(failure, &assertion) {
+			maps.Copy(details, assertion.Details())
+		}
+		return CheckResult{Success: false, Error: failure.Error(), Details: details}
+	}
+	return CheckResult{Success: true, Details: details}
+}
+
+// httpClient returns a client that follows the check's redirect, certificate
+// and cookie settings
+func (w *ProbeWorker) httpClient(target *url.URL, config *httpcheck.Config, timeout time.Duration) (*http.Client, error) {
+	transport := w.transport
+	if !config.VerifiesTLS() {
+		transport = w.insecureTransport
+	}
+
+	// Cookies go in a jar so that they follow redirects within the site
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil && len(config.Cookies) > 0 {
+		cookies := make([]*http.Cookie, 0, len(config.Cookies))
+		for name, value := range config.Cookies {
+			cookies = append(cookies, &http.Cookie{Name: name, Value: value})
+		}
+		jar.SetCookies(target, cookies)
+	}
+
+	limit := config.RedirectLimit()
+	return &http.Client{
+		Transport: transport,
+		Jar:       jar,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > limit {
+				if limit == 0 {
+					// Judge the redirect response itself
+					return http.ErrUseLastResponse
+				}
+				return fmt.Errorf("stopped after %d redirects", limit)
+			}
+			return nil
+		},
+	}, nil
+}
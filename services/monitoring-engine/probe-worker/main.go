@@ -8,83 +8,338 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jjkirkpatrick/monitoring/pkg/auth"
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
+	"github.com/jjkirkpatrick/monitoring/pkg/pingcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
+	"github.com/jjkirkpatrick/monitoring/pkg/probeauth"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.uber.org/zap"
 )
 
+// WorkerConfig describes where a worker probes from. The probe manager
+// routes checks to workers by matching these attributes and labels against
+// the check's location and selector.
+type WorkerConfig struct {
+	Region   string
+	Provider string
+	IPFamily string // ipv4, ipv6 or dual
+	Capacity int    // Checks run concurrently
+	Labels   map[string]string
+
+	// AgentToken switches the worker to private agent mode, in which it
+	// only receives checks for the account the token was issued to.
+	// AccountID is optional and only guards against using another
+	// account's token.
+	AgentToken string
+	AccountID  string
+
+	// RegistrationSecret is issued by the operator to shared workers, which
+	// prove they hold it when registering
+	RegistrationSecret string
+}
+
 type ProbeWorker struct {
 	ID        string
+	config    WorkerConfig
 	logger    *zap.Logger
 	natsConn  *nats.Conn
-	client    *http.Client
+	js        jetstream.JetStream
 	supported []string
+	slots     chan struct{}
+	// HTTP checks go over one transport or the other depending on whether
+	// the monitor verifies certificates
+	transport         *http.Transport
+	insecureTransport *http.Transport
+	// resolver sends DNS checks to the monitor's nameserver, or the
+	// worker's own
+	resolver *dnscheck.Resolver
+	// signer signs results with the key registered with the probe manager
+	signer *probeauth.Signer
+
+	// consumer delivers check assignments; it is stopped while the worker
+	// drains
+	consumer   jetstream.ConsumeContext
+	consumerMu sync.Mutex
+	draining   atomic.Bool
+	checks     sync.WaitGroup
+	shutdownCh chan struct{}
+
+	// Load reported to the probe manager with each heartbeat
+	inFlight  atomic.Int64
+	waiting   atomic.Int64
+	latencyMu sync.Mutex
+	latency   time.Duration // moving average of recent check durations
 }
 
-func NewProbeWorker(logger *zap.Logger, natsConn *nats.Conn) *ProbeWorker {
+func NewProbeWorker(logger *zap.Logger, natsConn *nats.Conn, js jetstream.JetStream, config WorkerConfig) *ProbeWorker {
+	// Ping is only offered where an ICMP socket can be opened, which needs
+	// the worker's group in net.ipv4.ping_group_range or CAP_NET_RAW
+	supported := []string{"HTTP", "HTTPS", "TCP", "UDP", "DNS"}
+	if err := pingcheck.Available(); err != nil {
+		logger.Warn("ICMP sockets unavailable, not offering ping checks", zap.Error(err))
+	} else {
+		supported = append(supported, "PING")
+	}
+
 	return &ProbeWorker{
-		ID:     uuid.New().String(),
-		logger: logger,
-		natsConn: natsConn,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		supported: []string{"HTTP", "HTTPS", "TCP", "UDP", "DNS"},
+		ID:                uuid.New().String(),
+		config:            config,
+		logger:            logger,
+		natsConn:          natsConn,
+		js:                js,
+		transport:         newTransport(true),
+		insecureTransport: newTransport(false),
+		resolver:          dnscheck.NewResolver(),
+		supported:         supported,
+		slots:             make(chan struct{}, config.Capacity),
+		shutdownCh:        make(chan struct{}),
 	}
 }
 
 func (w *ProbeWorker) Start() error {
+	// Results are signed with a key generated for this run of the worker
+	signer, err := probeauth.NewSigner(w.ID)
+	if err != nil {
+		return err
+	}
+	w.signer = signer
+
 	// Register with probe manager
-	registration, _ := json.Marshal(map[string]interface{}{
-		"id":          w.ID,
-		"check_types": w.supported,
-	})
-	if err := w.natsConn.Publish("probes.register", registration); err != nil {
+	if err := w.register(); err != nil {
 		return fmt.Errorf("failed to register worker: %v", err)
 	}
 
+	// Register again whenever the probe manager no longer knows this worker
+	if _, err := w.natsConn.Subscribe("probes.reregister."+w.ID, func(*nats.Msg) {
+		if err := w.register(); err != nil {
+			w.logger.Error("failed to re-register worker", zap.Error(err))
+		}
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to registration requests: %v", err)
+	}
+
+	// Report registrations the probe manager refuses, e.g. for a revoked
+	// agent token
+	if _, err := w.natsConn.Subscribe("probes.rejected."+w.ID, func(msg *nats.Msg) {
+		var rejection struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(msg.Data, &rejection)
+		w.logger.Error("probe manager rejected registration", zap.String("reason", rejection.Error))
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to registration rejections: %v", err)
+	}
+
+	// Follow drain and resume commands from the probe manager
+	if _, err := w.natsConn.Subscribe("probes.drain."+w.ID, func(*nats.Msg) {
+		w.drain()
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to drain requests: %v", err)
+	}
+	if _, err := w.natsConn.Subscribe("probes.resume."+w.ID, func(*nats.Msg) {
+		if err := w.resume(); err != nil {
+			w.logger.Error("failed to resume worker", zap.Error(err))
+		}
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to resume requests: %v", err)
+	}
+
 	// Start heartbeat routine
 	go w.sendHeartbeats()
 
-	// Subscribe to check assignments
-	subject := fmt.Sprintf("probes.check.assign.%s", w.ID)
-	if _, err := w.natsConn.Subscribe(subject, w.handleCheckAssignment); err != nil {
-		return fmt.Errorf("failed to subscribe to check assignments: %v", err)
+	return w.startConsuming()
+}
+
+// startConsuming starts pulling check assignments. Worker IDs do not survive
+// a restart, so the consumer is removed once the worker has gone away; the
+// probe manager will already have moved its checks elsewhere. Pulling no more
+// than the worker can run keeps queued checks where the manager can see them.
+func (w *ProbeWorker) startConsuming() error {
+	w.consumerMu.Lock()
+	defer w.consumerMu.Unlock()
+
+	if w.consumer != nil {
+		return nil
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	consumer, err := pipeline.Consume(ctx, w.js, w.logger, pipeline.ConsumerConfig{
+		Stream:            pipeline.StreamCheckAssignments,
+		Durable:           "probe-worker-" + w.ID,
+		FilterSubject:     pipeline.SubjectCheckAssignPrefix + w.ID,
+		AckWait:           2 * time.Minute,
+		MaxBuffered:       w.config.Capacity,
+		InactiveThreshold: 5 * time.Minute,
+	}, w.handleCheckAssignment)
+	if err != nil {
+		return fmt.Errorf("failed to consume check assignments: %v", err)
+	}
+	w.consumer = consumer
 	return nil
 }
 
+// stopConsuming stops pulling check assignments
+func (w *ProbeWorker) stopConsuming() {
+	w.consumerMu.Lock()
+	defer w.consumerMu.Unlock()
+
+	if w.consumer != nil {
+		w.consumer.Stop()
+		w.consumer = nil
+	}
+}
+
+// register announces the worker and what it can run to the probe manager
+func (w *ProbeWorker) register() error {
+	var proof string
+	if w.config.RegistrationSecret != "" {
+		proof = auth.RegistrationProof(w.config.RegistrationSecret, w.ID, w.signer.PublicKey())
+	}
+	registration, _ := json.Marshal(map[string]interface{}{
+		"id":          w.ID,
+		"check_types": w.supported,
+		"region":      w.config.Region,
+		"provider":    w.config.Provider,
+		"ip_family":   w.config.IPFamily,
+		"capacity":    w.config.Capacity,
+		"labels":      w.config.Labels,
+		"token":       w.config.AgentToken,
+		"account_id":  w.config.AccountID,
+		"public_key":  w.signer.PublicKey(),
+		"proof":       proof,
+	})
+	return w.natsConn.Publish("probes.register", registration)
+}
+
 func (w *ProbeWorker) sendHeartbeats() {
 	ticker := time.NewTicker(15 * time.Second)
 	defer ticker.Stop()
 
-	heartbeat, _ := json.Marshal(map[string]string{
-		"worker_id": w.ID,
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.natsConn.Publish("probes.heartbeat", w.heartbeat()); err != nil {
+				w.logger.Error("failed to send heartbeat", zap.Error(err))
+			}
+		case <-w.shutdownCh:
+			return
+		}
+	}
+}
+
+// heartbeat reports the worker's current load so the probe manager can send
+// new checks to the least busy workers
+func (w *ProbeWorker) heartbeat() []byte {
+	// Assignments not yet running are blocked waiting for a slot
+	queued := w.waiting.Load()
+
+	w.latencyMu.Lock()
+	latency := w.latency
+	w.latencyMu.Unlock()
+
+	heartbeat, _ := json.Marshal(map[string]interface{}{
+		"worker_id":   w.ID,
+		"in_flight":   w.inFlight.Load(),
+		"queue_depth": queued,
+		"latency_ms":  float64(latency) / float64(time.Millisecond),
 	})
+	return heartbeat
+}
 
-	for range ticker.C {
-		if err := w.natsConn.Publish("probes.heartbeat", heartbeat); err != nil {
-			w.logger.Error("failed to send heartbeat", zap.Error(err))
-		}
+// recordLatency folds a check's duration into the moving average
+func (w *ProbeWorker) recordLatency(d time.Duration) {
+	w.latencyMu.Lock()
+	defer w.latencyMu.Unlock()
+
+	if w.latency == 0 {
+		w.latency = d
+		return
 	}
+	w.latency += (d - w.latency) / 5
 }
 
-func (w *ProbeWorker) handleCheckAssignment(msg *nats.Msg) {
-	var assignment struct {
-		MonitorID string `json:"monitor_id"`
-		URL       string `json:"url"`
-		Type      string `json:"type"`
-		Timeout   string `json:"timeout"`
+// handleCheckAssignment runs each assignment in its own goroutine, up to the
+// worker's capacity. Once every slot is taken it blocks, which holds back
+// further deliveries until a check finishes. The assignment is acked as soon
+// as it has a slot; from then on the probe manager's deadlines cover it.
+func (w *ProbeWorker) handleCheckAssignment(data []byte) error {
+	var assignment checkAssignment
+	if err := json.Unmarshal(data, &assignment); err != nil {
+		return pipeline.Permanent(fmt.Errorf("failed to unmarshal check assignment: %w", err))
 	}
-	if err := json.Unmarshal(msg.Data, &assignment); err != nil {
-		w.logger.Error("failed to unmarshal check assignment", zap.Error(err))
-		return
+
+	w.waiting.Add(1)
+	w.slots <- struct{}{}
+	w.waiting.Add(-1)
+
+	// The probe manager reassigns checks a draining worker has not started
+	if w.draining.Load() {
+		<-w.slots
+		w.logger.Debug("dropping check assignment while draining",
+			zap.String("assignment_id", assignment.AssignmentID))
+		return nil
+	}
+
+	w.inFlight.Add(1)
+	w.checks.Add(1)
+	go func() {
+		defer func() {
+			w.inFlight.Add(-1)
+			<-w.slots
+			w.checks.Done()
+		}()
+		w.runCheck(assignment)
+	}()
+	return nil
+}
+
+// checkAssignment is a check the probe manager has given this worker
+type checkAssignment struct {
+	MonitorID string `json:"monitor_id"`
+	URL       string `json:"url"`
+	Type      string `json:"type"`
+	Timeout   string `json:"timeout"`
+	Location  string `json:"location"`
+	// Identify the assignment to the probe manager, which reassigns it
+	// if it is not acked and completed in time
+	AssignmentID string `json:"assignment_id"`
+	Attempt      int    `json:"attempt"`
+	RetriedBy    string `json:"retried_by"`
+	// Echoed back so downstream services can tell maintenance results apart
+	Maintenance         bool   `json:"maintenance"`
+	MaintenanceWindowID string `json:"maintenance_window_id"`
+	// HTTP configures HTTP checks; nil sends a plain GET
+	HTTP *httpcheck.Config `json:"http,omitempty"`
+	// DNS configures DNS checks; nil asks the worker's nameserver for A
+	// records
+	DNS *dnscheck.Config `json:"dns,omitempty"`
+	// Ping configures ping checks; nil sends the default echoes
+	Ping *pingcheck.Config `json:"ping,omitempty"`
+}
+
+func (w *ProbeWorker) runCheck(assignment checkAssignment) {
+	// Tell the probe manager the check has started
+	if assignment.AssignmentID != "" {
+		ack, _ := json.Marshal(map[string]string{
+			"assignment_id": assignment.AssignmentID,
+			"worker_id":     w.ID,
+		})
+		if err := w.natsConn.Publish("probes.check.ack", ack); err != nil {
+			w.logger.Error("failed to ack check assignment", zap.Error(err))
+		}
 	}
 
 	// Parse timeout
@@ -95,44 +350,60 @@ func (w *ProbeWorker) handleCheckAssignment(msg *nats.Msg) {
 
 	// Execute check based on type
 	start := time.Now()
-	result := w.executeCheck(assignment.Type, assignment.URL, timeout)
+	result := w.executeCheck(assignment, timeout)
 	duration := time.Since(start)
+	w.recordLatency(duration)
+
+	// Results from location-less checks are attributed to this worker's region
+	location := assignment.Location
+	if location == "" {
+		location = w.config.Region
+	}
 
 	// Publish check result
 	checkResult, _ := json.Marshal(map[string]interface{}{
-		"monitor_id": assignment.MonitorID,
-		"worker_id":  w.ID,
-		"timestamp":  time.Now(),
-		"duration":   duration.Milliseconds(),
-		"success":    result.Success,
-		"error":      result.Error,
-		"details":    result.Details,
+		"monitor_id":            assignment.MonitorID,
+		"worker_id":             w.ID,
+		"location":              location,
+		"assignment_id":         assignment.AssignmentID,
+		"attempt":               assignment.Attempt,
+		"retried_by":            assignment.RetriedBy,
+		"timestamp":             time.Now(),
+		"duration":              duration.Milliseconds(),
+		"success":               result.Success,
+		"error":                 result.Error,
+		"details":               result.Details,
+		"maintenance":           assignment.Maintenance,
+		"maintenance_window_id": assignment.MaintenanceWindowID,
 	})
 
-	if err := w.natsConn.Publish("probes.check.result", checkResult); err != nil {
+	if err := pipeline.PublishMsg(w.js, w.signer.Sign(pipeline.SubjectCheckResult, checkResult)); err != nil {
 		w.logger.Error("failed to publish check result", zap.Error(err))
 	}
 }
 
 type CheckResult struct {
 	Success bool              `json:"success"`
-	Error   string           `json:"error,omitempty"`
+	Error   string            `json:"error,omitempty"`
 	Details map[string]string `json:"details,omitempty"`
 }
 
-func (w *ProbeWorker) executeCheck(checkType, target string, timeout time.Duration) CheckResult {
+func (w *ProbeWorker) executeCheck(assignment checkAssignment, timeout time.Duration) CheckResult {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	checkType, target := assignment.Type, assignment.URL
 	switch strings.ToUpper(checkType) {
 	case "HTTP", "HTTPS":
-		return w.httpCheck(ctx, target)
+		return w.httpCheck(ctx, target, assignment.HTTP, timeout)
 	case "TCP":
 		return w.tcpCheck(ctx, target)
 	case "UDP":
 		return w.udpCheck(ctx, target)
 	case "DNS":
-		return w.dnsCheck(ctx, target)
+		return w.dnsCheck(ctx, target, assignment.DNS)
+	case "PING":
+		return w.pingCheck(ctx, target, assignment.Ping)
 	default:
 		return CheckResult{
 			Success: false,
@@ -141,28 +412,11 @@ func (w *ProbeWorker) executeCheck(checkType, target string, timeout time.Durati
 	}
 }
 
-func (w *ProbeWorker) httpCheck(ctx context.Context, url string) CheckResult {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return CheckResult{Success: false, Error: err.Error()}
-	}
-
-	resp, err := w.client.Do(req)
-	if err != nil {
-		return CheckResult{Success: false, Error: err.Error()}
-	}
-	defer resp.Body.Close()
-
-	return CheckResult{
-		Success: resp.StatusCode < 400,
-		Details: map[string]string{
-			"status_code": fmt.Sprintf("%d", resp.StatusCode),
-			"status":      resp.Status,
-		},
+func (w *ProbeWorker) tcpCheck(ctx context.Context, target string) CheckResult {
+	if address, secure := strings.CutPrefix(target, tlsScheme); secure {
+		return w.tlsCheck(ctx, address)
 	}
-}
 
-func (w *ProbeWorker) tcpCheck(ctx context.Context, target string) CheckResult {
 	var d net.Dialer
 	conn, err := d.DialContext(ctx, "tcp", target)
 	if err != nil {
@@ -184,22 +438,21 @@ func (w *ProbeWorker) udpCheck(ctx context.Context, target string) CheckResult {
 	return CheckResult{Success: true}
 }
 
-func (w *ProbeWorker) dnsCheck(ctx context.Context, target string) CheckResult {
-	resolver := &net.Resolver{}
-	ips, err := resolver.LookupIPAddr(ctx, target)
-	if err != nil {
-		return CheckResult{Success: false, Error: err.Error()}
-	}
-
-	details := make(map[string]string)
-	for i, ip := range ips {
-		details[fmt.Sprintf("ip_%d", i+1)] = ip.String()
-	}
-
-	return CheckResult{
-		Success: true,
-		Details: details,
+// parseLabels parses labels in the form "key=value,key2=value2"
+func parseLabels(spec string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("label %q is not in key=value form", pair)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
 	}
+	return labels, nil
 }
 
 func main() {
@@ -226,16 +479,90 @@ func main() {
 	}
 	defer nc.Close()
 
+	// Describe where this worker probes from; checks for a location are only
+	// routed to workers in that region whose labels match the check's selector
+	config := WorkerConfig{
+		Region:   os.Getenv("PROBE_REGION"),
+		Provider: os.Getenv("PROBE_PROVIDER"),
+		IPFamily: os.Getenv("PROBE_IP_FAMILY"),
+		Capacity: 10,
+	}
+	// Private agents run inside a customer's network and authenticate with
+	// a token issued to that account
+	config.AgentToken = os.Getenv("PROBE_AGENT_TOKEN")
+	config.AccountID = os.Getenv("PROBE_ACCOUNT_ID")
+	if config.AgentToken != "" {
+		if config.Region == "" {
+			logger.Fatal("PROBE_REGION must name the agent's location in private agent mode")
+		}
+		if config.Provider == "" {
+			config.Provider = "private"
+		}
+		logger.Info("running as private probe agent",
+			zap.String("region", config.Region),
+			zap.String("account_id", config.AccountID))
+	} else if config.AccountID != "" {
+		logger.Fatal("PROBE_ACCOUNT_ID requires PROBE_AGENT_TOKEN")
+	} else {
+		// Shared workers authenticate with the operator's registration secret
+		config.RegistrationSecret = os.Getenv("PROBE_REGISTRATION_SECRET")
+		if config.RegistrationSecret == "" {
+			logger.Fatal("PROBE_REGISTRATION_SECRET is required for shared workers")
+		}
+	}
+	if config.Region == "" {
+		logger.Warn("PROBE_REGION not set, worker will only receive checks without a location")
+	}
+	if config.IPFamily == "" {
+		config.IPFamily = "ipv4"
+	}
+	if v := os.Getenv("PROBE_CAPACITY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatal("invalid PROBE_CAPACITY", zap.String("value", v))
+		}
+		config.Capacity = n
+	}
+	labels, err := parseLabels(os.Getenv("PROBE_LABELS"))
+	if err != nil {
+		logger.Fatal("invalid PROBE_LABELS", zap.Error(err))
+	}
+	config.Labels = labels
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Fatal("failed to create JetStream context", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pipeline.EnsureStreams(ctx, js)
+	cancel()
+	if err != nil {
+		logger.Fatal("failed to create pipeline streams", zap.Error(err))
+	}
+
 	// Create and start probe worker
-	worker := NewProbeWorker(logger, nc)
+	worker := NewProbeWorker(logger, nc, js, config)
 	if err := worker.Start(); err != nil {
 		logger.Fatal("failed to start probe worker", zap.Error(err))
 	}
 
+	// How long in-flight checks may take to finish on shutdown
+	shutdownTimeout := 30 * time.Second
+	if v := os.Getenv("PROBE_SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid PROBE_SHUTDOWN_TIMEOUT", zap.Error(err))
+		}
+		shutdownTimeout = d
+	}
+
 	// Wait for shutdown signal
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
 
+	// Graceful shutdown
+	worker.Shutdown(shutdownTimeout)
 	logger.Info("probe worker stopped")
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/pingcheck"
+)
+
+// pingCheck sends ICMP echo requests to target and judges the round trips and
+// packet loss against the monitor's thresholds
+func (w *ProbeWorker) pingCheck(ctx context.Context, target string, config *pingcheck.Config) CheckResult {
+	stats, err := pingcheck.Ping(ctx, target, config)
+	if err != nil {
+		return CheckResult{Success: false, Error: err.Error()}
+	}
+
+	details := stats.Details()
+	if err := config.Check(target, stats); err != nil {
+		return CheckResult{Success: false, Error: err.Error(), Details: details}
+	}
+	return CheckResult{Success: true, Details: details}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/certcheck"
+)
+
+// tlsScheme marks TCP targets that speak TLS from the first byte, such as
+// tls://mail.example.com:465
+const tlsScheme = "tls://"
+
+// tlsCheck connects to address and completes a TLS handshake. The handshake
+// accepts any certificate so that a broken one can still be described; the
+// check then fails if the chain or hostname did not verify.
+func (w *ProbeWorker) tlsCheck(ctx context.Context, address string) CheckResult {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return CheckResult{Success: false, Error: err.Error()}
+	}
+
+	dialer := &tls.Dialer{
+		Config: &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return CheckResult{Success: false, Error: err.Error()}
+	}
+	defer conn.Close()
+
+	details := certcheck.Inspect(conn.(*tls.Conn).ConnectionState(), host, time.Now())
+	if details[certcheck.DetailChainValid] == "false" {
+		return CheckResult{Success: false, Error: details[certcheck.DetailChainError], Details: details}
+	}
+	if details[certcheck.DetailHostnameMatch] == "false" {
+		return CheckResult{Success: false, Error: details[certcheck.DetailHostnameError], Details: details}
+	}
+	return CheckResult{Success: true, Details: details}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
+)
+
+// requestTiming records when each phase of an HTTP check's request happened.
+// A redirect starts the record again, so it describes the last request.
+type requestTiming struct {
+	phases phaseTimes
+	// mu guards phases, as dual-stack dialing can connect from several
+	// goroutines at once
+	mu sync.Mutex
+}
+
+type phaseTimes struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+}
+
+// trace returns the hooks that fill in the timing
+func (t *requestTiming) trace() *httptrace.ClientTrace {
+	record := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			t.phases = phaseTimes{start: time.Now()}
+			t.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { record(&t.phases.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { record(&t.phases.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			// Keep the first attempt when several addresses are dialed
+			if t.phases.connectStart.IsZero() {
+				t.phases.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				record(&t.phases.connectDone)
+			}
+		},
+		TLSHandshakeStart:    func() { record(&t.phases.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { record(&t.phases.tlsDone) },
+		GotFirstResponseByte: func() { record(&t.phases.firstByte) },
+	}
+}
+
+// details adds the duration of each phase that happened to a check's details
+func (t *requestTiming) details(details map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phase := func(key string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() {
+			details[key] = strconv.FormatInt(end.Sub(start).Milliseconds(), 10)
+		}
+	}
+	p := t.phases
+	phase(httpcheck.DetailDNSTime, p.dnsStart, p.dnsDone)
+	phase(httpcheck.DetailConnectTime, p.connectStart, p.connectDone)
+	phase(httpcheck.DetailTLSTime, p.tlsStart, p.tlsDone)
+	phase(httpcheck.DetailFirstByteTime, p.start, p.firstByte)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// membersBucket is the NATS KV bucket holding scheduler replica leases
+const membersBucket = "scheduler_members"
+
+// Cluster coordinates scheduler replicas. Each replica holds a lease in a
+// NATS KV bucket whose keys expire unless refreshed, and monitors are
+// assigned to the live replicas by rendezvous (highest random weight)
+// hashing so that each monitor has exactly one owner and only the monitors of
+// a departed replica move when membership changes.
+//
+// Replicas see a membership change at their own next renewal, so for a short
+// while after one the old and new owner of a moved monitor could both claim
+// it. To avoid dispatching it twice, a replica gives up monitors that moved
+// away at once but only takes on monitors that moved to it once the handoff
+// period has passed. A replica stalled for longer than its lease can still
+// overlap with its successor until it next renews.
+type Cluster struct {
+	logger      *zap.Logger
+	js          jetstream.JetStream
+	kv          jetstream.KeyValue
+	replicaID   string
+	heartbeat   time.Duration
+	members     []string
+	lastRenewed time.Time
+	// previous is the member list before the last change, whose owners keep
+	// their monitors until handoffUntil
+	previous     []string
+	handoffUntil time.Time
+	mu           sync.RWMutex
+	shutdownCh   chan struct{}
+}
+
+func NewCluster(logger *zap.Logger, js jetstream.JetStream, replicaID string, heartbeat time.Duration) *Cluster {
+	return &Cluster{
+		logger:     logger,
+		js:         js,
+		replicaID:  replicaID,
+		heartbeat:  heartbeat,
+		shutdownCh: make(chan struct{}),
+	}
+}
+
+// handoffPeriod is how long a replica waits before taking on monitors that
+// moved to it, long enough for every other replica to have renewed and seen
+// the same change
+func (c *Cluster) handoffPeriod() time.Duration {
+	return c.heartbeat
+}
+
+// leaseTTL is how long a lease survives without renewal. Renewals happen
+// every heartbeat/3, so a dead replica's lease lapses within 2/3 of a
+// heartbeat and the survivors notice at their next renewal, keeping takeover
+// within one heartbeat period.
+func (c *Cluster) leaseTTL() time.Duration {
+	return c.heartbeat * 2 / 3
+}
+
+func (c *Cluster) Start(ctx context.Context) error {
+	kv, err := c.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      membersBucket,
+		Description: "Scheduler replica leases",
+		TTL:         c.leaseTTL(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create members bucket: %w", err)
+	}
+	c.kv = kv
+
+	if err := c.renew(ctx); err != nil {
+		return err
+	}
+
+	go c.run()
+
+	return nil
+}
+
+func (c *Cluster) run() {
+	ticker := time.NewTicker(c.heartbeat / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), c.heartbeat/3)
+			if err := c.renew(ctx); err != nil {
+				c.logger.Error("failed to renew scheduler lease", zap.Error(err))
+			}
+			cancel()
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// renew refreshes this replica's lease and reloads the member list
+func (c *Cluster) renew(ctx context.Context) error {
+	if _, err := c.kv.Put(ctx, c.replicaID, []byte(time.Now().UTC().Format(time.RFC3339))); err != nil {
+		return fmt.Errorf("failed to put lease: %w", err)
+	}
+	renewedAt := time.Now()
+
+	lister, err := c.kv.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list members: %w", err)
+	}
+	var members []string
+	for key := range lister.Keys() {
+		members = append(members, key)
+	}
+	sort.Strings(members)
+
+	c.mu.Lock()
+	changed := !equalMembers(c.members, members)
+	if changed {
+		c.previous = c.members
+		c.handoffUntil = renewedAt.Add(c.handoffPeriod())
+	}
+	c.members = members
+	c.lastRenewed = renewedAt
+	c.mu.Unlock()
+
+	clusterMembers.Set(float64(len(members)))
+	if changed {
+		c.logger.Info("scheduler membership changed",
+			zap.String("replica_id", c.replicaID),
+			zap.Strings("members", members))
+	}
+
+	return nil
+}
+
+// Owns reports whether this replica is responsible for dispatching the
+// monitor. A replica whose own lease may have lapsed owns nothing, since the
+// other replicas will already have taken over its monitors. During a handoff
+// it only owns monitors it also owned before the change, so a moved monitor
+// goes undispatched for up to one handoff period rather than twice.
+func (c *Cluster) Owns(monitorID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if time.Since(c.lastRenewed) > c.leaseTTL() {
+		return false
+	}
+	if ownerOf(monitorID, c.members) != c.replicaID {
+		return false
+	}
+	return time.Now().After(c.handoffUntil) || ownerOf(monitorID, c.previous) == c.replicaID
+}
+
+// Stop releases the lease so that the remaining replicas take over this
+// replica's monitors immediately rather than waiting for it to expire
+func (c *Cluster) Stop() {
+	close(c.shutdownCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.kv.Delete(ctx, c.replicaID); err != nil {
+		c.logger.Error("failed to release scheduler lease", zap.Error(err))
+	}
+}
+
+// ownerOf returns the member with the highest hash weight for the monitor
+func ownerOf(monitorID string, members []string) string {
+	var owner string
+	var best uint64
+	for _, member := range members {
+		h := fnv.New64a()
+		h.Write([]byte(member))
+		h.Write([]byte{0})
+		h.Write([]byte(monitorID))
+		if weight := mix64(h.Sum64()); owner == "" || weight > best {
+			owner, best = member, weight
+		}
+	}
+	return owner
+}
+
+// mix64 is the splitmix64 finalizer, used to spread FNV hashes of similar
+// keys evenly across replicas
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+func equalMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// ConfirmationPolicy controls the accelerated cadence a monitor switches to
+// after a failed check, so that an outage is confirmed or cleared without
+// waiting a full interval
+type ConfirmationPolicy struct {
+	// Interval is the delay between a failed result and the re-check
+	Interval time.Duration
+	// MaxAttempts caps the re-checks per failure; zero disables confirmation
+	MaxAttempts int
+	// DefaultMaxChecksPerDay is the daily check limit of users without a
+	// user_limits row
+	DefaultMaxChecksPerDay int64
+}
+
+// handleCheckResult watches probe results and brings the next check of a
+// failing monitor forward. Every replica tracks results so that the dispatch
+// queues stay identical; only the owner dispatches the re-check.
+func (s *Scheduler) handleCheckResult(msg *nats.Msg) {
+	// Only results signed by a registered worker may bring checks forward,
+	// or anyone could spend a user's budget on re-checks
+	worker, err := s.verifier.VerifyMsg(msg)
+	if err != nil {
+		s.logger.Warn("ignoring unverified check result", zap.Error(err))
+		return
+	}
+
+	var result struct {
+		MonitorID   string `json:"monitor_id"`
+		Success     bool   `json:"success"`
+		Maintenance bool   `json:"maintenance"`
+	}
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		s.logger.Error("failed to unmarshal check result", zap.Error(err))
+		return
+	}
+
+	// Failures during maintenance are expected and not worth confirming
+	if result.Maintenance {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.schedules[result.MonitorID]
+	if !exists {
+		return
+	}
+	// Private agents only report on their own account's monitors
+	if worker.AccountID != "" && worker.AccountID != entry.monitor.UserID {
+		s.logger.Warn("ignoring check result from another account's agent",
+			zap.String("monitor_id", result.MonitorID),
+			zap.String("worker_id", worker.ID))
+		return
+	}
+
+	if result.Success {
+		// Recovered; fall back to the normal interval
+		if entry.failing {
+			entry.failing = false
+			entry.confirmations = 0
+			if entry.confirming {
+				entry.confirming = false
+				entry.next = nextSlot(entry.monitor, time.Now())
+				heap.Fix(&s.queue, entry.index)
+			}
+		}
+		return
+	}
+
+	if !entry.failing {
+		entry.failing = true
+		entry.confirmations = 0
+	}
+	if entry.confirming || entry.confirmations >= s.confirm.MaxAttempts {
+		return
+	}
+
+	next := time.Now().Add(s.confirm.Interval)
+	if !next.Before(entry.next) {
+		// The regular check comes sooner anyway
+		return
+	}
+	entry.next = next
+	entry.confirming = true
+	heap.Fix(&s.queue, entry.index)
+	s.wake()
+}
+
+// checkBudgets tracks how many checks beyond their regular schedule each
+// user's plan still allows today
+type checkBudgets struct {
+	mu    sync.Mutex
+	users map[string]int64
+}
+
+// allow reports whether the user can afford another n checks and, if so,
+// charges them to their budget
+func (b *checkBudgets) allow(userID string, n int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.users[userID] < int64(n) {
+		return false
+	}
+	b.users[userID] -= int64(n)
+	return true
+}
+
+// checksPerRun returns how many checks a single run of the monitor
+// dispatches, one per location
+func checksPerRun(monitor *Monitor) int {
+	return max(len(monitor.Locations), 1)
+}
+
+// refreshCheckBudgets recomputes each user's spare checks for the day as
+// max_checks_per_day minus the checks already run and the regular checks
+// still scheduled before midnight, so confirmations never eat into them.
+// Users without a user_limits row are held to DefaultMaxChecksPerDay.
+func (s *Scheduler) refreshCheckBudgets(ctx context.Context) error {
+	usage, err := s.db.Queries.ListDailyCheckUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	remaining := now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+
+	planned := make(map[string]int64)
+	s.mu.Lock()
+	for _, entry := range s.schedules {
+		if entry.monitor.Interval > 0 {
+			checks := int64(remaining / entry.monitor.Interval)
+			planned[entry.monitor.UserID] += checks * int64(checksPerRun(entry.monitor))
+		}
+	}
+	s.mu.Unlock()
+
+	users := make(map[string]int64, len(usage))
+	for _, u := range usage {
+		id := u.UserID.String()
+		limit := s.confirm.DefaultMaxChecksPerDay
+		if u.MaxChecksPerDay.Valid {
+			limit = int64(u.MaxChecksPerDay.Int32)
+		}
+		users[id] = limit - u.CurrentCheckCount - planned[id]
+	}
+
+	s.budgets.mu.Lock()
+	s.budgets.users = users
+	s.budgets.mu.Unlock()
+
+	return nil
+}
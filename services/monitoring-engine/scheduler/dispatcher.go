@@ -0,0 +1,231 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/jjkirkpatrick/monitoring/pkg/cron"
+	"github.com/jjkirkpatrick/monitoring/pkg/maintenance"
+	"go.uber.org/zap"
+)
+
+// idleWait bounds how long the dispatcher sleeps when nothing is scheduled
+const idleWait = time.Minute
+
+// cronJitter bounds the spread applied to cron-scheduled checks so that
+// monitors sharing an expression do not all fire at the top of the minute
+const cronJitter = 30 * time.Second
+
+// scheduleEntry is a monitor's position in the dispatch queue
+type scheduleEntry struct {
+	monitor *Monitor
+	next    time.Time
+	index   int
+
+	// confirming is set while next is an accelerated re-check rather than
+	// a regular slot; failing and confirmations track the current run of
+	// failed results
+	confirming    bool
+	failing       bool
+	confirmations int
+}
+
+// scheduleQueue is a min-heap of schedule entries ordered by next check time.
+// A single dispatcher goroutine drains it, so memory stays at one entry per
+// monitor regardless of how many monitors are scheduled.
+type scheduleQueue []*scheduleEntry
+
+func (q scheduleQueue) Len() int           { return len(q) }
+func (q scheduleQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x interface{}) {
+	entry := x.(*scheduleEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*q = old[:n-1]
+	return entry
+}
+
+// slotOffset returns the deterministic offset of a monitor's checks within
+// its interval. Hashing the monitor ID spreads monitors created together
+// across the interval instead of firing them in lockstep.
+func slotOffset(monitorID string, interval time.Duration) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(monitorID))
+	return time.Duration(h.Sum64() % uint64(interval))
+}
+
+// nextSlot returns the first check time for the monitor strictly after t
+func nextSlot(monitor *Monitor, t time.Time) time.Time {
+	if monitor.cron != nil {
+		offset := slotOffset(monitor.ID, cronJitter)
+		next := monitor.cron.Next(t.Add(-offset))
+		if next.IsZero() {
+			// Expressions that never fire are refused by scheduleMonitor, so
+			// this only happens for a schedule whose last run is years away
+			return t.AddDate(5, 0, 0)
+		}
+		return next.Add(offset)
+	}
+
+	offset := slotOffset(monitor.ID, monitor.Interval)
+	next := t.Add(-offset).Truncate(monitor.Interval).Add(offset)
+	for !next.After(t) {
+		next = next.Add(monitor.Interval)
+	}
+	return next
+}
+
+// scheduleMonitor adds or replaces the monitor in the dispatch queue. When
+// immediate is set the monitor is dispatched on the next loop iteration,
+// otherwise it waits for its next slot. Cron-scheduled monitors always wait,
+// since catching up could run them outside their schedule. The caller must
+// hold s.mu.
+func (s *Scheduler) scheduleMonitor(monitor *Monitor, immediate bool) {
+	now := time.Now()
+
+	switch {
+	case monitor.Schedule != "":
+		schedule, err := cron.ParseInTimezone(monitor.Schedule, monitor.Timezone)
+		if err == nil && schedule.Next(now).IsZero() {
+			err = fmt.Errorf("schedule never fires")
+		}
+		if err != nil {
+			s.logger.Error("refusing to schedule monitor with invalid schedule",
+				zap.String("monitor_id", monitor.ID),
+				zap.String("schedule", monitor.Schedule),
+				zap.String("timezone", monitor.Timezone),
+				zap.Error(err))
+			return
+		}
+		monitor.cron = schedule
+		immediate = false
+	case monitor.Interval <= 0:
+		s.logger.Error("refusing to schedule monitor with invalid interval",
+			zap.String("monitor_id", monitor.ID),
+			zap.Duration("interval", monitor.Interval))
+		return
+	}
+
+	next := nextSlot(monitor, now)
+	if immediate {
+		next = now
+	}
+
+	if entry, exists := s.schedules[monitor.ID]; exists {
+		entry.monitor = monitor
+		entry.next = next
+		entry.confirming = false
+		heap.Fix(&s.queue, entry.index)
+	} else {
+		entry := &scheduleEntry{monitor: monitor, next: next}
+		heap.Push(&s.queue, entry)
+		s.schedules[monitor.ID] = entry
+	}
+
+	monitorsScheduled.Set(float64(len(s.schedules)))
+	s.wake()
+}
+
+// unscheduleMonitor removes a monitor from the dispatch queue. The caller
+// must hold s.mu.
+func (s *Scheduler) unscheduleMonitor(monitorID string) {
+	entry, exists := s.schedules[monitorID]
+	if !exists {
+		return
+	}
+
+	heap.Remove(&s.queue, entry.index)
+	delete(s.schedules, monitorID)
+	monitorsScheduled.Set(float64(len(s.schedules)))
+}
+
+// wake interrupts the dispatcher's sleep so it picks up a changed queue head
+func (s *Scheduler) wake() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// runDispatcher is the single loop that publishes every due check request
+func (s *Scheduler) runDispatcher() {
+	timer := time.NewTimer(idleWait)
+	defer timer.Stop()
+
+	type dueCheck struct {
+		monitor      *Monitor
+		due          time.Time
+		confirmation bool
+	}
+
+	for {
+		var due []dueCheck
+
+		s.mu.Lock()
+		now := time.Now()
+		for s.queue.Len() > 0 && !s.queue[0].next.After(now) {
+			entry := s.queue[0]
+			due = append(due, dueCheck{monitor: entry.monitor, due: entry.next, confirmation: entry.confirming})
+			if entry.confirming {
+				entry.confirming = false
+				entry.confirmations++
+			}
+			entry.monitor.LastChecked = now
+			entry.next = nextSlot(entry.monitor, now)
+			heap.Fix(&s.queue, 0)
+		}
+		wait := idleWait
+		if s.queue.Len() > 0 {
+			wait = s.queue[0].next.Sub(now)
+		}
+		s.mu.Unlock()
+
+		for _, check := range due {
+			// Every replica keeps the full queue so that a takeover needs no
+			// state transfer; only the owner actually dispatches
+			if !s.cluster.Owns(check.monitor.ID) {
+				checksSkipped.WithLabelValues("not_owner").Inc()
+				continue
+			}
+			window := s.activeWindow(check.monitor.ID, now)
+			if window != nil && window.Action == maintenance.ActionSkip {
+				checksSkipped.WithLabelValues("maintenance").Inc()
+				continue
+			}
+			if check.confirmation {
+				if !s.budgets.allow(check.monitor.UserID, checksPerRun(check.monitor)) {
+					checksSkipped.WithLabelValues("check_budget").Inc()
+					continue
+				}
+				confirmationChecks.Inc()
+			}
+			dispatchLag.Observe(now.Sub(check.due).Seconds())
+			s.dispatch(check.monitor, window)
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-s.wakeCh:
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
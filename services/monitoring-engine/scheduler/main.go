@@ -1,48 +1,109 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/jjkirkpatrick/monitoring/internal/database"
+	"github.com/jjkirkpatrick/monitoring/pkg/cron"
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/maintenance"
 	"github.com/jjkirkpatrick/monitoring/pkg/observability"
+	"github.com/jjkirkpatrick/monitoring/pkg/pingcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/pipeline"
+	"github.com/jjkirkpatrick/monitoring/pkg/probeauth"
+	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"go.uber.org/zap"
 )
 
 type Monitor struct {
 	ID          string        `json:"id"`
+	UserID      string        `json:"user_id"`
 	Name        string        `json:"name"`
 	URL         string        `json:"url"`
-	Type        string        `json:"type"` // HTTP, HTTPS, TCP, UDP, DNS
+	Type        string        `json:"type"` // HTTP, HTTPS, TCP, UDP, DNS, PING
 	Interval    time.Duration `json:"interval"`
 	Timeout     time.Duration `json:"timeout"`
 	RetryCount  int          `json:"retry_count"`
 	LastChecked time.Time    `json:"last_checked"`
 	Status      string       `json:"status"`
+	Locations   []string     `json:"locations"`
+	// Selector restricts which probe workers may run the checks
+	Selector map[string]string `json:"selector,omitempty"`
+	// HTTP configures the request and response checks of HTTP monitors
+	HTTP *httpcheck.Config `json:"http,omitempty"`
+	// DNS configures the query and expected answers of DNS monitors
+	DNS *dnscheck.Config `json:"dns,omitempty"`
+	// Ping sets the echoes ping monitors send and the thresholds they are
+	// judged by
+	Ping *pingcheck.Config `json:"ping,omitempty"`
+	// Schedule is an optional cron expression, evaluated in Timezone, that
+	// replaces Interval for monitors which should only run at certain times
+	Schedule string `json:"schedule,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+
+	cron *cron.Schedule
 }
 
 type Scheduler struct {
-	logger     *zap.Logger
-	natsConn   *nats.Conn
-	monitors   map[string]*Monitor
-	schedules  map[string]*time.Ticker
-	shutdownCh chan struct{}
+	logger            *zap.Logger
+	natsConn          *nats.Conn
+	js                jetstream.JetStream
+	db                *database.DB
+	cluster           *Cluster
+	reconcileInterval time.Duration
+	schedules         map[string]*scheduleEntry
+	queue             scheduleQueue
+	wakeCh            chan struct{}
+	mu                sync.Mutex
+	shutdownCh        chan struct{}
+	confirm           ConfirmationPolicy
+	budgets           checkBudgets
+	verifier          *probeauth.Verifier
+
+	windows            *maintenance.Set
+	maintenanceTargets map[string]maintenance.Target
+	maintenanceMu      sync.RWMutex
+	// maintenanceState holds what was last reported about each owned monitor
+	// in maintenance; it is only touched by the maintenance tracker
+	maintenanceState map[string]maintenanceReport
 }
 
-func NewScheduler(logger *zap.Logger, natsConn *nats.Conn) *Scheduler {
+func NewScheduler(logger *zap.Logger, natsConn *nats.Conn, js jetstream.JetStream, db *database.DB, cluster *Cluster, reconcileInterval time.Duration, confirm ConfirmationPolicy, verifier *probeauth.Verifier) *Scheduler {
 	return &Scheduler{
-		logger:     logger,
-		natsConn:   natsConn,
-		monitors:   make(map[string]*Monitor),
-		schedules:  make(map[string]*time.Ticker),
-		shutdownCh: make(chan struct{}),
+		logger:            logger,
+		natsConn:          natsConn,
+		js:                js,
+		db:                db,
+		cluster:           cluster,
+		reconcileInterval: reconcileInterval,
+		schedules:         make(map[string]*scheduleEntry),
+		wakeCh:            make(chan struct{}, 1),
+		shutdownCh:        make(chan struct{}),
+		confirm:           confirm,
+		verifier:          verifier,
+		maintenanceState:  make(map[string]maintenanceReport),
 	}
 }
 
 func (s *Scheduler) Start() error {
+	// Load all active monitors before listening for updates so that a restart
+	// does not leave monitors unscheduled until they are next edited
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.reconcile(ctx); err != nil {
+		return err
+	}
+
 	// Subscribe to monitor updates
 	if _, err := s.natsConn.Subscribe("monitors.updates", s.handleMonitorUpdate); err != nil {
 		return err
@@ -53,6 +114,19 @@ func (s *Scheduler) Start() error {
 		return err
 	}
 
+	// Watch results so failing monitors are re-checked sooner
+	if s.confirm.MaxAttempts > 0 {
+		if _, err := s.natsConn.Subscribe("probes.check.result", s.handleCheckResult); err != nil {
+			return err
+		}
+	}
+
+	// Start the dispatch loop and keep the in-memory schedules in line with
+	// the database
+	go s.runDispatcher()
+	go s.runReconciliation()
+	go s.runMaintenanceTracker()
+
 	return nil
 }
 
@@ -63,36 +137,9 @@ func (s *Scheduler) handleMonitorUpdate(msg *nats.Msg) {
 		return
 	}
 
-	// Stop existing schedule if any
-	if ticker, exists := s.schedules[monitor.ID]; exists {
-		ticker.Stop()
-		delete(s.schedules, monitor.ID)
-	}
-
-	// Create new schedule
-	ticker := time.NewTicker(monitor.Interval)
-	s.schedules[monitor.ID] = ticker
-	s.monitors[monitor.ID] = &monitor
-
-	// Start monitoring routine
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				// Publish check request to probe manager
-				checkRequest, _ := json.Marshal(map[string]interface{}{
-					"monitor_id": monitor.ID,
-					"url":       monitor.URL,
-					"type":      monitor.Type,
-					"timeout":   monitor.Timeout,
-				})
-				s.natsConn.Publish("probes.check.request", checkRequest)
-			case <-s.shutdownCh:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduleMonitor(&monitor, false)
 }
 
 func (s *Scheduler) handleMonitorDeletion(msg *nats.Msg) {
@@ -102,18 +149,65 @@ func (s *Scheduler) handleMonitorDeletion(msg *nats.Msg) {
 		return
 	}
 
-	if ticker, exists := s.schedules[monitorID]; exists {
-		ticker.Stop()
-		delete(s.schedules, monitorID)
-		delete(s.monitors, monitorID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unscheduleMonitor(monitorID)
+}
+
+// dispatch publishes one check request per configured location to the probe
+// manager, which routes each to a worker in that region. Monitors without
+// locations get a single request that any worker may run. Checks inside a
+// flagging maintenance window carry the window so that their results can be
+// excluded from alerting and uptime.
+func (s *Scheduler) dispatch(monitor *Monitor, window *maintenance.Window) {
+	locations := monitor.Locations
+	if len(locations) == 0 {
+		locations = []string{""}
+	}
+
+	for _, location := range locations {
+		request := map[string]interface{}{
+			"monitor_id": monitor.ID,
+			"url":        monitor.URL,
+			"type":       monitor.Type,
+			"timeout":    monitor.Timeout.String(),
+			"account_id": monitor.UserID,
+		}
+		if location != "" {
+			request["location"] = location
+		}
+		if len(monitor.Selector) > 0 {
+			request["selector"] = monitor.Selector
+		}
+		if monitor.HTTP != nil {
+			request["http"] = monitor.HTTP
+		}
+		if monitor.DNS != nil {
+			request["dns"] = monitor.DNS
+		}
+		if monitor.Ping != nil {
+			request["ping"] = monitor.Ping
+		}
+		if window != nil {
+			request["maintenance"] = true
+			request["maintenance_window_id"] = window.ID
+		}
+
+		checkRequest, _ := json.Marshal(request)
+		if err := pipeline.Publish(s.js, pipeline.SubjectCheckRequest, checkRequest); err != nil {
+			dispatchErrors.Inc()
+			s.logger.Error("failed to publish check request",
+				zap.String("monitor_id", monitor.ID),
+				zap.String("location", location),
+				zap.Error(err))
+			continue
+		}
+		checksDispatched.Inc()
 	}
 }
 
 func (s *Scheduler) Stop() {
 	close(s.shutdownCh)
-	for _, ticker := range s.schedules {
-		ticker.Stop()
-	}
 }
 
 func main() {
@@ -128,6 +222,13 @@ func main() {
 	}
 	defer shutdown()
 
+	// Initialize Database
+	db, err := database.New(context.Background())
+	if err != nil {
+		logger.Fatal("failed to initialize database", zap.Error(err))
+	}
+	defer db.Close()
+
 	// Connect to NATS
 	natsURL := os.Getenv("NATS_URL")
 	if natsURL == "" {
@@ -140,8 +241,95 @@ func main() {
 	}
 	defer nc.Close()
 
+	// How often schedules are reconciled against the database
+	reconcileInterval := time.Minute
+	if v := os.Getenv("SCHEDULER_RECONCILE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid SCHEDULER_RECONCILE_INTERVAL", zap.Error(err))
+		}
+		reconcileInterval = d
+	}
+
+	// Expose scheduler metrics
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":8080"
+	}
+	observability.ServeMetrics(metricsAddr, logger)
+
+	// JetStream carries the check pipeline and the scheduler cluster's state
+	js, err := jetstream.New(nc)
+	if err != nil {
+		logger.Fatal("failed to create JetStream context", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = pipeline.EnsureStreams(ctx, js)
+	cancel()
+	if err != nil {
+		logger.Fatal("failed to create pipeline streams", zap.Error(err))
+	}
+
+	replicaID := os.Getenv("SCHEDULER_REPLICA_ID")
+	if replicaID == "" {
+		replicaID = uuid.New().String()
+	}
+
+	heartbeat := 15 * time.Second
+	if v := os.Getenv("SCHEDULER_HEARTBEAT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid SCHEDULER_HEARTBEAT", zap.Error(err))
+		}
+		heartbeat = d
+	}
+
+	// Accelerated re-checks after a failed result
+	confirm := ConfirmationPolicy{Interval: 20 * time.Second, MaxAttempts: 3, DefaultMaxChecksPerDay: 10000}
+	if v := os.Getenv("SCHEDULER_CONFIRM_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal("invalid SCHEDULER_CONFIRM_INTERVAL", zap.Error(err))
+		}
+		confirm.Interval = d
+	}
+	if v := os.Getenv("SCHEDULER_CONFIRM_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			logger.Fatal("invalid SCHEDULER_CONFIRM_ATTEMPTS", zap.Error(err))
+		}
+		confirm.MaxAttempts = n
+	}
+	if v := os.Getenv("SCHEDULER_DEFAULT_MAX_CHECKS_PER_DAY"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			logger.Fatal("invalid SCHEDULER_DEFAULT_MAX_CHECKS_PER_DAY", zap.String("value", v))
+		}
+		confirm.DefaultMaxChecksPerDay = n
+	}
+
+	// Results that bring re-checks forward must be signed by a registered
+	// probe worker
+	verifyCtx, verifyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	verifier, err := probeauth.NewVerifier(verifyCtx, js, logger, 0)
+	verifyCancel()
+	if err != nil {
+		logger.Fatal("failed to load probe worker registry", zap.Error(err))
+	}
+
+	// Join the scheduler cluster so that each monitor is dispatched by exactly
+	// one replica
+	cluster := NewCluster(logger, js, replicaID, heartbeat)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	err = cluster.Start(ctx)
+	cancel()
+	if err != nil {
+		logger.Fatal("failed to join scheduler cluster", zap.Error(err))
+	}
+
 	// Create and start scheduler
-	scheduler := NewScheduler(logger, nc)
+	scheduler := NewScheduler(logger, nc, js, db, cluster, reconcileInterval, confirm, verifier)
 	if err := scheduler.Start(); err != nil {
 		logger.Fatal("failed to start scheduler", zap.Error(err))
 	}
@@ -153,5 +341,6 @@ func main() {
 
 	// Graceful shutdown
 	scheduler.Stop()
+	cluster.Stop()
 	logger.Info("scheduler stopped")
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	"github.com/jjkirkpatrick/monitoring/pkg/maintenance"
+	"go.uber.org/zap"
+)
+
+// maintenanceCheckInterval is how often monitors are checked for entering or
+// leaving a maintenance window
+const maintenanceCheckInterval = 15 * time.Second
+
+// MaintenanceEvent is published on monitors.maintenance when a monitor enters
+// or leaves maintenance, so that the alert manager can suppress alerts for
+// checks that are skipped and therefore never produce results. ExpiresAt is
+// when the alert manager stops suppressing alerts unless it hears again: the
+// end of the current occurrence, or two reconcile intervals away if that is
+// sooner, so that a lost leave event cannot silence a monitor for good.
+type MaintenanceEvent struct {
+	MonitorID     string             `json:"monitor_id"`
+	InMaintenance bool               `json:"in_maintenance"`
+	WindowID      string             `json:"window_id,omitempty"`
+	Action        maintenance.Action `json:"action,omitempty"`
+	ExpiresAt     time.Time          `json:"expires_at,omitempty"`
+	Timestamp     time.Time          `json:"timestamp"`
+}
+
+// maintenanceReport is the last maintenance event published for a monitor
+type maintenanceReport struct {
+	windowID  string
+	expiresAt time.Time
+}
+
+// refreshMaintenance reloads maintenance windows and the per-monitor
+// maintenance mode flags. Windows are evaluated against the clock at dispatch
+// time, so a window takes effect at its start even though definitions are
+// only reloaded on each reconcile.
+func (s *Scheduler) refreshMaintenance(ctx context.Context) error {
+	rows, err := s.db.Queries.ListCurrentMaintenanceWindows(ctx)
+	if err != nil {
+		return err
+	}
+
+	states, err := s.db.Queries.ListMonitorMaintenanceStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	windows := make([]*maintenance.Window, 0, len(rows)+len(states))
+	for _, row := range rows {
+		w := windowFromDB(row)
+		if err := w.Validate(); err != nil {
+			s.logger.Warn("ignoring invalid maintenance window",
+				zap.String("window_id", w.ID),
+				zap.Error(err))
+			continue
+		}
+		windows = append(windows, w)
+	}
+
+	targets := make(map[string]maintenance.Target, len(states))
+	for _, state := range states {
+		id := state.ID.String()
+		target := maintenance.Target{
+			MonitorID: id,
+			UserID:    state.UserID.String(),
+			Tags:      state.Tags,
+		}
+		if state.GroupID.Valid {
+			target.GroupID = uuid.UUID(state.GroupID.Bytes).String()
+		}
+		targets[id] = target
+
+		// A monitor switched into maintenance mode behaves like an open
+		// one-off window that ends at maintenance_until, if set
+		if state.MaintenanceMode.Valid && state.MaintenanceMode.Bool {
+			w := &maintenance.Window{
+				UserID:     target.UserID,
+				Name:       "maintenance mode",
+				MonitorIDs: []string{id},
+				Action:     maintenance.ActionSkip,
+			}
+			if state.MaintenanceUntil.Valid {
+				w.EndsAt = state.MaintenanceUntil.Time
+			}
+			windows = append(windows, w)
+		}
+	}
+
+	s.maintenanceMu.Lock()
+	s.windows = maintenance.NewSet(windows)
+	s.maintenanceTargets = targets
+	s.maintenanceMu.Unlock()
+
+	return nil
+}
+
+// activeWindow returns the maintenance window covering the monitor at t, if any
+func (s *Scheduler) activeWindow(monitorID string, t time.Time) *maintenance.Window {
+	s.maintenanceMu.RLock()
+	defer s.maintenanceMu.RUnlock()
+
+	target, ok := s.maintenanceTargets[monitorID]
+	if !ok {
+		target = maintenance.Target{MonitorID: monitorID}
+	}
+	return s.windows.Active(target, t)
+}
+
+func (s *Scheduler) maintenanceWindowCount() int {
+	s.maintenanceMu.RLock()
+	defer s.maintenanceMu.RUnlock()
+	return s.windows.Len()
+}
+
+// runMaintenanceTracker publishes maintenance transitions for the monitors
+// this replica owns. The current state is republished once per reconcile
+// interval so that a restarted alert manager catches up.
+func (s *Scheduler) runMaintenanceTracker() {
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	var lastRepublish time.Time
+	for {
+		select {
+		case now := <-ticker.C:
+			republish := now.Sub(lastRepublish) >= s.reconcileInterval
+			if republish {
+				lastRepublish = now
+			}
+			s.trackMaintenance(now, republish)
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) trackMaintenance(now time.Time, republish bool) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.schedules))
+	for id := range s.schedules {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	seen := make(map[string]bool, len(ids))
+	inMaintenance := 0
+	for _, id := range ids {
+		seen[id] = true
+
+		// Monitors that moved to another replica are forgotten without an
+		// event, since their new owner reports their state
+		if !s.cluster.Owns(id) {
+			delete(s.maintenanceState, id)
+			continue
+		}
+
+		window := s.activeWindow(id, now)
+		previous, wasInMaintenance := s.maintenanceState[id]
+		switch {
+		case window != nil:
+			inMaintenance++
+			// Reports are renewed before the alert manager would expire them
+			expiring := !previous.expiresAt.After(now.Add(maintenanceCheckInterval))
+			if !wasInMaintenance || previous.windowID != window.ID || republish || expiring {
+				report := maintenanceReport{
+					windowID:  window.ID,
+					expiresAt: s.maintenanceExpiry(window, now),
+				}
+				s.maintenanceState[id] = report
+				s.publishMaintenanceEvent(MaintenanceEvent{
+					MonitorID:     id,
+					InMaintenance: true,
+					WindowID:      window.ID,
+					Action:        window.Action,
+					ExpiresAt:     report.expiresAt,
+					Timestamp:     now,
+				})
+			}
+		case wasInMaintenance:
+			delete(s.maintenanceState, id)
+			s.publishMaintenanceEvent(MaintenanceEvent{
+				MonitorID: id,
+				Timestamp: now,
+			})
+		}
+	}
+
+	for id := range s.maintenanceState {
+		if !seen[id] {
+			delete(s.maintenanceState, id)
+		}
+	}
+
+	monitorsInMaintenance.Set(float64(inMaintenance))
+}
+
+// maintenanceExpiry returns when a report that the monitor is in the window
+// at now lapses: at the end of the current occurrence, if the window has one,
+// and otherwise after two reconcile intervals, by which time it has been
+// republished
+func (s *Scheduler) maintenanceExpiry(window *maintenance.Window, now time.Time) time.Time {
+	expiry := now.Add(2 * s.reconcileInterval)
+	if occurrences := window.NextOccurrences(now, 1); len(occurrences) > 0 && occurrences[0].End.Before(expiry) {
+		expiry = occurrences[0].End
+	}
+	return expiry
+}
+
+func (s *Scheduler) publishMaintenanceEvent(event MaintenanceEvent) {
+	data, _ := json.Marshal(event)
+	if err := s.natsConn.Publish("monitors.maintenance", data); err != nil {
+		s.logger.Error("failed to publish maintenance event",
+			zap.String("monitor_id", event.MonitorID),
+			zap.Error(err))
+	}
+}
+
+// windowFromDB converts a maintenance_windows row into a window ready for
+// validation
+func windowFromDB(row sqlc.MaintenanceWindow) *maintenance.Window {
+	w := &maintenance.Window{
+		ID:         row.ID.String(),
+		UserID:     row.UserID.String(),
+		Name:       row.Name,
+		Tags:       row.Tags,
+		StartsAt:   row.StartsAt.Time,
+		Recurrence: row.Recurrence.String,
+		Timezone:   row.Timezone,
+		Action:     maintenance.Action(row.Action),
+	}
+	for _, id := range row.MonitorIds {
+		w.MonitorIDs = append(w.MonitorIDs, id.String())
+	}
+	for _, id := range row.GroupIds {
+		w.GroupIDs = append(w.GroupIDs, id.String())
+	}
+	if row.EndsAt.Valid {
+		w.EndsAt = row.EndsAt.Time
+	}
+	if row.DurationSeconds.Valid {
+		w.Duration = time.Duration(row.DurationSeconds.Int32) * time.Second
+	}
+	return w
+}
@@ -0,0 +1,82 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	monitorsScheduled = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "scheduler_monitors_scheduled",
+			Help: "Number of monitors currently held in the dispatch queue",
+		},
+	)
+
+	checksDispatched = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "scheduler_checks_dispatched_total",
+			Help: "Total number of check requests published to the probe manager",
+		},
+	)
+
+	dispatchErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "scheduler_dispatch_errors_total",
+			Help: "Total number of check requests that failed to publish",
+		},
+	)
+
+	checksSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scheduler_checks_skipped_total",
+			Help: "Total number of due checks that were not dispatched by this replica",
+		},
+		[]string{"reason"},
+	)
+
+	confirmationChecks = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "scheduler_confirmation_checks_total",
+			Help: "Total number of accelerated re-checks dispatched after a failed result",
+		},
+	)
+
+	ownedMonitors = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "scheduler_owned_monitors",
+			Help: "Number of scheduled monitors owned by this replica",
+		},
+	)
+
+	clusterMembers = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "scheduler_cluster_members",
+			Help: "Number of live scheduler replicas",
+		},
+	)
+
+	monitorsInMaintenance = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "scheduler_monitors_in_maintenance",
+			Help: "Number of monitors owned by this replica that are inside a maintenance window",
+		},
+	)
+
+	dispatchLag = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "scheduler_dispatch_lag_seconds",
+			Help:    "Delay between a check's scheduled time and its dispatch",
+			Buckets: []float64{.001, .005, .01, .05, .1, .5, 1, 5, 15, 60},
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(monitorsScheduled)
+	prometheus.MustRegister(checksDispatched)
+	prometheus.MustRegister(dispatchErrors)
+	prometheus.MustRegister(checksSkipped)
+	prometheus.MustRegister(confirmationChecks)
+	prometheus.MustRegister(ownedMonitors)
+	prometheus.MustRegister(clusterMembers)
+	prometheus.MustRegister(monitorsInMaintenance)
+	prometheus.MustRegister(dispatchLag)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	sqlc "github.com/jjkirkpatrick/monitoring/internal/database/generated"
+	"github.com/jjkirkpatrick/monitoring/pkg/dnscheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/httpcheck"
+	"github.com/jjkirkpatrick/monitoring/pkg/pingcheck"
+	"go.uber.org/zap"
+)
+
+// runReconciliation periodically brings the in-memory schedules back in line
+// with the monitors table, covering any updates missed on monitors.updates.
+func (s *Scheduler) runReconciliation() {
+	ticker := time.NewTicker(s.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), s.reconcileInterval)
+			if err := s.reconcile(ctx); err != nil {
+				s.logger.Error("failed to reconcile schedules", zap.Error(err))
+			}
+			cancel()
+		case <-s.shutdownCh:
+			return
+		}
+	}
+}
+
+// reconcile loads all active monitors from the database and adds, updates or
+// drops schedules so that they match. Monitors that are newly scheduled and
+// overdue for a check are dispatched immediately rather than waiting a full
+// interval.
+func (s *Scheduler) reconcile(ctx context.Context) error {
+	// Keep scheduling even if maintenance windows cannot be loaded; the
+	// previously loaded windows stay in effect
+	if err := s.refreshMaintenance(ctx); err != nil {
+		s.logger.Error("failed to load maintenance windows", zap.Error(err))
+	}
+
+	active, err := s.db.Queries.ListActiveMonitors(ctx)
+	if err != nil {
+		return err
+	}
+
+	extensions, err := s.loadExtensions(ctx)
+	if err != nil {
+		return err
+	}
+
+	overdue, err := s.db.Queries.GetMonitorsNeedingCheck(ctx)
+	if err != nil {
+		return err
+	}
+	overdueIDs := make(map[string]bool, len(overdue))
+	for _, m := range overdue {
+		overdueIDs[m.ID.String()] = true
+	}
+
+	desired := make(map[string]*Monitor, len(active))
+	for _, m := range active {
+		desired[m.ID.String()] = monitorFromDB(m, extensions)
+	}
+
+	var added, updated, removed, caughtUp int
+
+	s.mu.Lock()
+	for id := range s.schedules {
+		if _, ok := desired[id]; !ok {
+			s.unscheduleMonitor(id)
+			removed++
+		}
+	}
+	for id, monitor := range desired {
+		entry, ok := s.schedules[id]
+		switch {
+		case !ok:
+			// Catch up on checks missed while the monitor was not scheduled
+			s.scheduleMonitor(monitor, overdueIDs[id])
+			added++
+			if overdueIDs[id] {
+				caughtUp++
+			}
+		case scheduleChanged(entry.monitor, monitor):
+			monitor.LastChecked = entry.monitor.LastChecked
+			s.scheduleMonitor(monitor, false)
+			updated++
+		}
+	}
+	owned := 0
+	for id := range s.schedules {
+		if s.cluster.Owns(id) {
+			owned++
+		}
+	}
+	s.mu.Unlock()
+	ownedMonitors.Set(float64(owned))
+
+	if s.confirm.MaxAttempts > 0 {
+		if err := s.refreshCheckBudgets(ctx); err != nil {
+			s.logger.Error("failed to load check budgets", zap.Error(err))
+		}
+	}
+
+	s.logger.Info("reconciled schedules",
+		zap.Int("active", len(desired)),
+		zap.Int("added", added),
+		zap.Int("updated", updated),
+		zap.Int("removed", removed),
+		zap.Int("caught_up", caughtUp),
+		zap.Int("owned", owned),
+		zap.Int("maintenance_windows", s.maintenanceWindowCount()))
+
+	return nil
+}
+
+// scheduleChanged reports whether a monitor needs to be rescheduled, or the
+// checks it dispatches have changed
+func scheduleChanged(current, desired *Monitor) bool {
+	return current.URL != desired.URL ||
+		current.Type != desired.Type ||
+		current.Interval != desired.Interval ||
+		current.Timeout != desired.Timeout ||
+		!slices.Equal(current.Locations, desired.Locations) ||
+		!maps.Equal(current.Selector, desired.Selector) ||
+		current.Schedule != desired.Schedule ||
+		current.Timezone != desired.Timezone ||
+		!reflect.DeepEqual(current.HTTP, desired.HTTP) ||
+		!reflect.DeepEqual(current.DNS, desired.DNS) ||
+		!reflect.DeepEqual(current.Ping, desired.Ping)
+}
+
+// extensions holds the rows of the per-type extension tables of active
+// monitors, which have a row only for monitors created with one
+type extensions struct {
+	http map[uuid.UUID]*sqlc.ListHttpExtensionsRow
+	dns  map[uuid.UUID]*sqlc.ListDnsExtensionsRow
+	ping map[uuid.UUID]*pingcheck.Config
+}
+
+// loadExtensions reads the extension tables of active monitors
+func (s *Scheduler) loadExtensions(ctx context.Context) (*extensions, error) {
+	httpRows, err := s.db.Queries.ListHttpExtensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dnsRows, err := s.db.Queries.ListDnsExtensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pingRows, err := s.db.Queries.ListPingExtensions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := &extensions{
+		http: make(map[uuid.UUID]*sqlc.ListHttpExtensionsRow, len(httpRows)),
+		dns:  make(map[uuid.UUID]*sqlc.ListDnsExtensionsRow, len(dnsRows)),
+		ping: make(map[uuid.UUID]*pingcheck.Config, len(pingRows)),
+	}
+	for i := range httpRows {
+		ext.http[httpRows[i].MonitorID] = &httpRows[i]
+	}
+	for i := range dnsRows {
+		ext.dns[dnsRows[i].MonitorID] = &dnsRows[i]
+	}
+	for _, row := range pingRows {
+		ext.ping[row.MonitorID] = pingConfigFromDB(row)
+	}
+	return ext, nil
+}
+
+// monitorFromDB converts a monitors row and its extension into the
+// scheduler's representation
+func monitorFromDB(m sqlc.Monitor, ext *extensions) *Monitor {
+	// TCP targets carry their port separately from the host, and may start
+	// with tls:// to have the worker complete a TLS handshake
+	target := m.Target
+	if m.Type == sqlc.MonitorTypeTcp && m.Port.Valid {
+		address, secure := strings.CutPrefix(target, "tls://")
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			target = net.JoinHostPort(address, strconv.Itoa(int(m.Port.Int32)))
+			if secure {
+				target = "tls://" + target
+			}
+		}
+	}
+
+	// A malformed selector is ignored rather than blocking the monitor
+	var selector map[string]string
+	if len(m.ProbeSelector) > 0 {
+		_ = json.Unmarshal(m.ProbeSelector, &selector)
+	}
+
+	var httpConfig *httpcheck.Config
+	var dnsConfig *dnscheck.Config
+	var pingConfig *pingcheck.Config
+	switch m.Type {
+	case sqlc.MonitorTypeHttp:
+		httpConfig = httpConfigFromDB(m, ext.http[m.ID])
+	case sqlc.MonitorTypeDns:
+		dnsConfig = dnsConfigFromDB(m, ext.dns[m.ID])
+	case sqlc.MonitorTypePing:
+		pingConfig = ext.ping[m.ID]
+	}
+
+	return &Monitor{
+		ID:        m.ID.String(),
+		UserID:    m.UserID.String(),
+		Name:      m.Name,
+		URL:       target,
+		Type:      strings.ToUpper(string(m.Type)),
+		Interval:  time.Duration(m.Interval) * time.Second,
+		Timeout:   time.Duration(m.Timeout) * time.Second,
+		Status:    string(m.Status),
+		Locations: m.Locations,
+		Selector:  selector,
+		Schedule:  m.Schedule.String,
+		Timezone:  m.ScheduleTimezone.String,
+		HTTP:      httpConfig,
+		DNS:       dnsConfig,
+		Ping:      pingConfig,
+	}
+}
+
+// httpConfigFromDB builds an HTTP monitor's request from its extension row,
+// which may be nil. Expected status codes, redirect following and certificate
+// verification are taken from the monitor's own columns when it sets them.
+func httpConfigFromDB(m sqlc.Monitor, row *sqlc.ListHttpExtensionsRow) *httpcheck.Config {
+	config := &httpcheck.Config{}
+	if row != nil {
+		// Like the selector, malformed JSON columns are left to the defaults
+		config.Method = row.Method.String
+		_ = json.Unmarshal(row.Headers, &config.Headers)
+		config.Body = row.Body.String
+		if row.MaxRedirects.Valid {
+			redirects := int(row.MaxRedirects.Int32)
+			config.MaxRedirects = &redirects
+		}
+		if row.BasicAuthUser.String != "" {
+			config.BasicAuth = &httpcheck.BasicAuth{
+				Username: row.BasicAuthUser.String,
+				Password: row.BasicAuthPassword.String,
+			}
+		}
+		config.UserAgent = row.UserAgent.String
+		_ = json.Unmarshal(row.Cookies, &config.Cookies)
+		_ = json.Unmarshal(row.Assertions, &config.Assertions)
+		config.MaxResponseTimeMs = int(row.MaxResponseTimeMs.Int32)
+		config.ContentMatch = row.ContentMatch.String
+		config.ContentMatchMode = row.ContentMatchMode.String
+
+		if row.ExpectedStatusCode.Valid {
+			config.ExpectedStatusCodes = []int{int(row.ExpectedStatusCode.Int32)}
+		}
+		if row.FollowRedirects.Valid {
+			config.FollowRedirects = &row.FollowRedirects.Bool
+		}
+		if row.VerifySsl.Valid {
+			config.VerifySSL = &row.VerifySsl.Bool
+		}
+	}
+
+	if len(m.ExpectedStatusCodes) > 0 {
+		config.ExpectedStatusCodes = nil
+		for _, code := range m.ExpectedStatusCodes {
+			config.ExpectedStatusCodes = append(config.ExpectedStatusCodes, int(code))
+		}
+	}
+	if m.FollowRedirects.Valid {
+		config.FollowRedirects = &m.FollowRedirects.Bool
+	}
+	if m.VerifySsl.Valid {
+		config.VerifySSL = &m.VerifySsl.Bool
+	}
+	// Monitors without an extension row match on their expected response
+	if row == nil {
+		config.ContentMatch = m.ExpectedResponse.String
+	}
+	return config
+}
+
+// dnsConfigFromDB builds a DNS monitor's query from its extension row. Without
+// one the monitor's record type is asked for and its expected response is the
+// expected value.
+func dnsConfigFromDB(m sqlc.Monitor, row *sqlc.ListDnsExtensionsRow) *dnscheck.Config {
+	if row == nil {
+		config := &dnscheck.Config{RecordType: m.DnsRecordType.String}
+		if m.ExpectedResponse.String != "" {
+			config.ExpectedValues = []string{m.ExpectedResponse.String}
+		}
+		return config
+	}
+
+	// Addresses are kept in expected_ip and any other answer in
+	// expected_value
+	config := &dnscheck.Config{
+		RecordType:       row.RecordType,
+		Nameserver:       row.Nameserver.String,
+		ExpectedValues:   row.ExpectedIp,
+		CheckPropagation: row.CheckPropagation.Bool,
+		Resolvers:        row.Resolvers,
+		DNSSEC:           row.Dnssec.Bool,
+		TrustAnchor:      row.TrustAnchor.String,
+	}
+	if row.ExpectedValue.String != "" {
+		config.ExpectedValues = append(config.ExpectedValues, row.ExpectedValue.String)
+	}
+	return config
+}
+
+// pingConfigFromDB reads a ping monitor's thresholds, leaving NULL columns to
+// the defaults
+func pingConfigFromDB(row sqlc.ListPingExtensionsRow) *pingcheck.Config {
+	config := &pingcheck.Config{
+		PacketCount:  int(row.PacketCount.Int32),
+		PacketSize:   int(row.PacketSize.Int32),
+		MaxLatencyMs: int(row.MaxLatencyMs.Int32),
+	}
+	if row.MaxPacketLossPercent.Valid {
+		loss := int(row.MaxPacketLossPercent.Int32)
+		config.MaxPacketLossPercent = &loss
+	}
+	return config
+}